@@ -27,6 +27,7 @@ func CreateRootCMD() *cobra.Command {
 		uninstallCmd(),
 		templateCmd(),
 		listCmd(),
+		outputCmd(),
 		createCmd(),
 		fmtCmd(),
 		versionCmd(),
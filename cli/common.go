@@ -2,6 +2,10 @@ package cli
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 	"kubehcl.sh/kubehcl/internal/configs"
@@ -48,3 +52,30 @@ func AddCmdSettings(cmd *cobra.Command) {
 
 	cmd.SetContext(ctx)
 }
+
+// Returns the context of a command which is cancelled on SIGINT or SIGTERM and once the deadline passes
+// After the first signal the default behavior is restored so a second signal exits immediately
+func commandContext(cmd *cobra.Command, conf *settings.EnvSettings) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(cmd.Context())
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-sigs:
+			signal.Stop(sigs)
+			fmt.Fprintf(os.Stderr, "Received %s, waiting for in-flight resources to finish, send it again to exit immediately\n", sig)
+			cancel(fmt.Errorf("received %s", sig))
+		case <-ctx.Done():
+			signal.Stop(sigs)
+		}
+	}()
+
+	if conf.Deadline <= 0 {
+		return ctx, func() { cancel(nil) }
+	}
+	deadlineCtx, deadlineCancel := context.WithTimeout(ctx, conf.Deadline)
+	return deadlineCtx, func() {
+		deadlineCancel()
+		cancel(nil)
+	}
+}
@@ -16,13 +16,9 @@ import (
 var installdesc string = `install will create or update existing resources managed by kubehcl
 automatically searches for files with ending of .hcl`
 
-type install struct {
-	CreateNamespace bool
-}
-
 // Apply command will validate then create the corresponding components written in the configuration files
 func installCmd() *cobra.Command {
-	var i install
+	i := settings.NewInstallSettings()
 
 	installCmd := &cobra.Command{
 		Use:   "install [name] [folder]",
@@ -34,11 +30,13 @@ func installCmd() *cobra.Command {
 			cmdSettings := cmd.Context().Value(cmdSettingsKey).(*settings.CmdSettings)
 			logging.SetLogger(conf.Debug)
 
-			client.Install(args, conf, viewSettings, cmdSettings, i.CreateNamespace)
+			ctx, cancel := commandContext(cmd, conf)
+			defer cancel()
+			client.Install(ctx, args, conf, viewSettings, cmdSettings, i)
 		},
 	}
 	// addCommonToCommand(installCmd)
-	installCmd.Flags().BoolVar(&i.CreateNamespace, "create-namespace", false, "automatically create namespace")
+	settings.AddInstallSettings(i, installCmd.Flags())
 	// addView(installCmd)
 	AddCmdSettings(installCmd)
 
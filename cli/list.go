@@ -22,7 +22,7 @@ func listCmd() *cobra.Command {
 			viewSettings := cmd.Parent().Context().Value(viewKey).(*view.ViewArgs)
 			logging.SetLogger(conf.Debug)
 
-			client.List(conf, viewSettings, "kube_secret")
+			client.List(cmd.Context(), conf, viewSettings, "kube_secret")
 		},
 	}
 	// addCommonToCommand(listCmd)
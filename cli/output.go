@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+	"kubehcl.sh/kubehcl/client"
+	"kubehcl.sh/kubehcl/internal/logging"
+	"kubehcl.sh/kubehcl/internal/view"
+	"kubehcl.sh/kubehcl/settings"
+)
+
+var outputDesc string = `output prints the root outputs saved in the state of a release
+with a name only the value of that output is printed`
+
+// Output prints the outputs of an installed release
+func outputCmd() *cobra.Command {
+	o := settings.NewOutputSettings()
+	outputCmd := &cobra.Command{
+		Use:   "output [name] [output name]",
+		Short: "Print the outputs of a release",
+		Long:  outputDesc,
+		Run: func(cmd *cobra.Command, args []string) {
+			conf := cmd.Parent().Context().Value(settingsKey).(*settings.EnvSettings)
+			viewSettings := cmd.Parent().Context().Value(viewKey).(*view.ViewArgs)
+			logging.SetLogger(conf.Debug)
+
+			client.Output(cmd.Context(), args, conf, viewSettings, o)
+		},
+	}
+	settings.AddOutputSettings(o, outputCmd.Flags())
+
+	return outputCmd
+}
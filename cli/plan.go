@@ -12,7 +12,7 @@ var plandesc string = `plan will show the difference between existing resources
 automatically searches for files with ending of .hcl`
 
 func planCmd() *cobra.Command {
-	// var a apply
+	a := settings.NewApplySettings()
 
 	planCmd := &cobra.Command{
 		Use:   "plan [name] [folder]",
@@ -23,10 +23,13 @@ func planCmd() *cobra.Command {
 			logging.SetLogger(conf.Debug)
 			viewSettings := cmd.Parent().Context().Value(viewKey).(*view.ViewArgs)
 			cmdSettings := cmd.Context().Value(cmdSettingsKey).(*settings.CmdSettings)
-			client.Plan(args, conf, viewSettings, cmdSettings)
+			ctx, cancel := commandContext(cmd, conf)
+			defer cancel()
+			client.Plan(ctx, args, conf, viewSettings, cmdSettings, a)
 		},
 	}
 
+	settings.AddApplySettings(a, planCmd.Flags())
 	AddCmdSettings(planCmd)
 
 	return planCmd
@@ -19,8 +19,9 @@ type template struct {
 // Template prints the template which will be applied in yaml form after being rendered
 func templateCmd() *cobra.Command {
 	var t template
+	templateSettings := settings.NewTemplateSettings()
 	templateCmd := &cobra.Command{
-		Use:   "template",
+		Use:   "template [name] [folder]",
 		Short: "Print the resources which will be created in yaml or json format",
 		Long:  "Template converts the hcl to yaml in order to view the kubernetes yamls which will be applied and created in your environment",
 		Run: func(cmd *cobra.Command, args []string) {
@@ -32,9 +33,9 @@ func templateCmd() *cobra.Command {
 
 			switch t.Kind {
 			case "yaml":
-				client.Template(args, "yaml", viewSettings, cmdSettings)
+				client.Template(args, "yaml", conf, viewSettings, cmdSettings, templateSettings)
 			case "json":
-				client.Template(args, "json", viewSettings, cmdSettings)
+				client.Template(args, "json", conf, viewSettings, cmdSettings, templateSettings)
 			default:
 				fmt.Println("Valid arguments for kind are [yaml, json]")
 				os.Exit(1)
@@ -43,6 +44,7 @@ func templateCmd() *cobra.Command {
 	}
 
 	templateCmd.Flags().StringVar(&t.Kind, "kind", "yaml", "prints the template in yaml or json format")
+	settings.AddTemplateSettings(templateSettings, templateCmd.Flags())
 	// templateCmd.Flags().StringVar(&t.Namespace, "namespace", "default", "prints the template in yaml or json format")
 
 	// addView(templateCmd)
@@ -11,6 +11,7 @@ import (
 // TODO: Use the same options as create and destroy in opposite option
 // Uninstall will destroy the corresponding components of the given apply name
 func uninstallCmd() *cobra.Command {
+	u := settings.NewUninstallSettings()
 	destroyCmd := &cobra.Command{
 		Use:   "uninstall [name] [folder]",
 		Short: "Uninstall all resources managed by kubehcl",
@@ -20,11 +21,14 @@ func uninstallCmd() *cobra.Command {
 			viewSettings := cmd.Parent().Context().Value(viewKey).(*view.ViewArgs)
 			cmdSettings := cmd.Context().Value(cmdSettingsKey).(*settings.CmdSettings)
 			logging.SetLogger(conf.Debug)
-			client.Uninstall(args, conf, viewSettings, cmdSettings)
+			ctx, cancel := commandContext(cmd, conf)
+			defer cancel()
+			client.Uninstall(ctx, args, conf, viewSettings, cmdSettings, u)
 		},
 	}
 	// addCommonToCommand(destroyCmd)
 	addView(destroyCmd)
+	settings.AddUninstallSettings(u, destroyCmd.Flags())
 	AddCmdSettings(destroyCmd)
 
 	return destroyCmd
@@ -0,0 +1,22 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+const (
+	// ExitInterrupted is the exit code when the operation was stopped by SIGINT or SIGTERM
+	ExitInterrupted = 130
+	// ExitDeadlineExceeded is the exit code when the operation did not finish before the deadline
+	ExitDeadlineExceeded = 124
+)
+
+// Exits with the exit code matching the reason the context was cancelled
+func exitCancelled(ctx context.Context) {
+	if errors.Is(context.Cause(ctx), context.DeadlineExceeded) {
+		os.Exit(ExitDeadlineExceeded)
+	}
+	os.Exit(ExitInterrupted)
+}
@@ -0,0 +1,248 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"kubehcl.sh/kubehcl/internal/view"
+	"kubehcl.sh/kubehcl/settings"
+)
+
+const (
+	ContextSucceeded = "succeeded"
+	ContextFailed    = "failed"
+	ContextCancelled = "cancelled"
+)
+
+// contextResult is the outcome of installing the release to a single kube context
+type contextResult struct {
+	context  string
+	diags    hcl.Diagnostics
+	duration time.Duration
+	status   string
+}
+
+// Resolves the kube contexts and context globs into the sorted names of the contexts in the kubeconfig
+func resolveContexts(conf *settings.EnvSettings, patterns []string) ([]string, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	raw, err := conf.RESTClientGetter().ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return nil, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Couldn't load kubeconfig",
+			Detail:   err.Error(),
+		})
+	}
+
+	var contexts []string
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid context glob",
+				Detail:   fmt.Sprintf("Context glob %s is invalid: %s", pattern, err.Error()),
+			})
+			continue
+		}
+		matched := false
+		for name := range raw.Contexts {
+			if ok, _ := path.Match(pattern, name); ok {
+				matched = true
+				if !slices.Contains(contexts, name) {
+					contexts = append(contexts, name)
+				}
+			}
+		}
+		if !matched {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Context not found",
+				Detail:   fmt.Sprintf("No context in the kubeconfig matches %s", pattern),
+			})
+		}
+	}
+	slices.Sort(contexts)
+	return contexts, diags
+}
+
+// Rejects contexts which connect to the same api server and namespace
+// Such contexts would share the state and the lock of the release
+func checkContextLocations(contexts []string, location func(kubeContext string) (string, error)) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	owners := make(map[string]string)
+	for _, kubeContext := range contexts {
+		loc, err := location(kubeContext)
+		if err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Couldn't resolve the api server",
+				Detail:   fmt.Sprintf("The api server of context %s couldn't be resolved: %s", kubeContext, err.Error()),
+			})
+			continue
+		}
+		if owner, exists := owners[loc]; exists {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate context",
+				Detail:   fmt.Sprintf("Context %s connects to the same api server and namespace as context %s (%s), both would share the state and the lock of the release", kubeContext, owner, loc),
+			})
+			continue
+		}
+		owners[loc] = kubeContext
+	}
+	return diags
+}
+
+// Reports the events of a single kube context
+type contextReporter struct {
+	ProgressReporter
+	context string
+}
+
+func (r *contextReporter) Report(event ProgressEvent) {
+	event.Context = r.context
+	r.ProgressReporter.Report(event)
+}
+
+// prefixWriter writes each line with a prefix
+// Writers sharing the mutex never interleave within a line
+type prefixWriter struct {
+	mutex  *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		p.mutex.Lock()
+		_, err := fmt.Fprintf(p.w, "%s%s", p.prefix, p.buf[:i+1])
+		p.mutex.Unlock()
+		p.buf = p.buf[i+1:]
+		if err != nil {
+			return len(b), err
+		}
+	}
+}
+
+// Creates the progress reporter of a kube context
+// Human output is prefixed with the context and never redrawn in place since several contexts write to the same output
+func newContextReporter(output string, w io.Writer, mutex *sync.Mutex, kubeContext string) ProgressReporter {
+	if output == OutputJSON {
+		// Each event is a single line so writing it through the shared mutex keeps the lines of the contexts apart
+		jw := &prefixWriter{mutex: mutex, w: w}
+		return &contextReporter{ProgressReporter: &jsonReporter{encoder: json.NewEncoder(jw)}, context: kubeContext}
+	}
+	pw := &prefixWriter{mutex: mutex, w: w, prefix: fmt.Sprintf("[%s] ", kubeContext)}
+	return &contextReporter{ProgressReporter: newHumanReporter(pw, progressInterval, false), context: kubeContext}
+}
+
+// Prints the status of each kube context
+func printContextSummary(w io.Writer, results []*contextResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "CONTEXT\tSTATUS\tDURATION\tERROR")
+	for _, result := range results {
+		errSummary := ""
+		if errs := result.diags.Errs(); len(errs) > 0 {
+			errSummary = errs[0].Error()
+			if len(errs) > 1 {
+				errSummary = fmt.Sprintf("%s (and %d more)", errSummary, len(errs)-1)
+			}
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", result.context, result.status, result.duration.Round(time.Second), errSummary)
+	}
+	_ = tw.Flush()
+}
+
+// Installs the release to each kube context concurrently, each context has its own state and lock
+// The folder is decoded for each context with the values of <context>.tfvars
+// Exits with a non-zero code if any context failed
+func installContexts(ctx context.Context, opts *installOptions, conf *settings.EnvSettings, viewArguments *view.ViewArgs) {
+	contexts, diags := resolveContexts(conf, opts.installSettings.Contexts)
+	if !diags.HasErrors() {
+		diags = append(diags, checkContextLocations(contexts, func(kubeContext string) (string, error) {
+			return conf.ForCluster(kubeContext, "", "").StateLocation()
+		})...)
+	}
+	if diags.HasErrors() {
+		v.DiagPrinter(diags, viewArguments)
+		os.Exit(1)
+	}
+
+	var outputMutex sync.Mutex
+	var wg sync.WaitGroup
+	results := make([]*contextResult, len(contexts))
+	for i, kubeContext := range contexts {
+		result := &contextResult{context: kubeContext}
+		results[i] = result
+
+		// Decoding shares the parser thus contexts are decoded one at a time
+		clusterConf := conf.ForCluster(kubeContext, "", "")
+		g, d, decodeDiags := decodeRelease(ctx, opts, clusterConf, kubeContext)
+		if decodeDiags.HasErrors() {
+			result.diags = decodeDiags
+			result.status = ContextFailed
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			reporter := newContextReporter(opts.installSettings.Output, os.Stdout, &outputMutex, kubeContext)
+			result.diags = append(decodeDiags, installRelease(ctx, opts, clusterConf, g, d, reporter, kubeContext)...)
+			result.duration = time.Since(start)
+			switch {
+			case result.diags.HasErrors():
+				result.status = ContextFailed
+			case ctx.Err() != nil:
+				result.status = ContextCancelled
+			default:
+				result.status = ContextSucceeded
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Stdout only contains the json events when the output is json
+	out := os.Stdout
+	if opts.installSettings.Output == OutputJSON {
+		out = os.Stderr
+	}
+	failed := false
+	for _, result := range results {
+		if len(result.diags) > 0 {
+			_, _ = fmt.Fprintf(out, "Context %s:\n", result.context)
+			v.DiagPrinter(result.diags, viewArguments)
+		}
+		failed = failed || result.status != ContextSucceeded
+	}
+	printContextSummary(out, results)
+
+	if ctx.Err() != nil {
+		exitCancelled(ctx)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+func Test_PrefixWriter(t *testing.T) {
+	var buf bytes.Buffer
+	var mutex sync.Mutex
+	w := &prefixWriter{mutex: &mutex, w: &buf, prefix: "[edge-1] "}
+	_, _ = fmt.Fprint(w, "Creating ")
+	_, _ = fmt.Fprint(w, "kube_resource.foo\nCreated kube_resource.foo\npartial")
+
+	expected := "[edge-1] Creating kube_resource.foo\n[edge-1] Created kube_resource.foo\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q got: %q", expected, buf.String())
+	}
+}
+
+func Test_PrintContextSummary(t *testing.T) {
+	var buf bytes.Buffer
+	printContextSummary(&buf, []*contextResult{
+		{context: "edge-1", status: ContextSucceeded, duration: 2 * time.Second},
+		{context: "edge-2", status: ContextFailed, diags: hcl.Diagnostics{
+			{Severity: hcl.DiagError, Summary: "State is locked"},
+			{Severity: hcl.DiagError, Summary: "Couldn't create resource"},
+		}},
+	})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines got: %d", len(lines))
+	}
+	if fields := strings.Fields(lines[1]); fields[0] != "edge-1" || fields[1] != ContextSucceeded || fields[2] != "2s" {
+		t.Errorf("Unexpected summary line: %s", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "edge-2") || !strings.Contains(lines[2], ContextFailed) || !strings.Contains(lines[2], "(and 1 more)") {
+		t.Errorf("Unexpected summary line: %s", lines[2])
+	}
+}
+
+func Test_CheckContextLocations(t *testing.T) {
+	locations := map[string]string{
+		"edge-1":       "https://edge-1/default",
+		"edge-1-alias": "https://edge-1/default",
+		"edge-1-other": "https://edge-1/other",
+		"edge-2":       "https://edge-2/default",
+	}
+	location := func(kubeContext string) (string, error) {
+		if loc, exists := locations[kubeContext]; exists {
+			return loc, nil
+		}
+		return "", fmt.Errorf("context %s not found", kubeContext)
+	}
+	tests := []struct {
+		contexts   []string
+		wantErrors int
+	}{
+		{contexts: []string{"edge-1", "edge-1-other", "edge-2"}},
+		{contexts: []string{"edge-1", "edge-1-alias", "edge-2"}, wantErrors: 1},
+		{contexts: []string{"edge-1", "missing"}, wantErrors: 1},
+	}
+
+	for _, test := range tests {
+		diags := checkContextLocations(test.contexts, location)
+		if len(diags.Errs()) != test.wantErrors {
+			t.Errorf("Expected %d errors for %v but received: %s", test.wantErrors, test.contexts, diags.Errs())
+		}
+	}
+}
@@ -1,38 +1,68 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"slices"
 	"sync"
 	"time"
 
 	"helm.sh/helm/v4/pkg/kube"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/hashicorp/hcl/v2"
 	"kubehcl.sh/kubehcl/internal/configs"
 	"kubehcl.sh/kubehcl/internal/dag"
 	"kubehcl.sh/kubehcl/internal/decode"
+	"kubehcl.sh/kubehcl/internal/logging"
 	"kubehcl.sh/kubehcl/internal/view"
 	"kubehcl.sh/kubehcl/kube/kubeclient"
 	"kubehcl.sh/kubehcl/settings"
+	"sigs.k8s.io/yaml"
 )
 
-type installResult struct {
-	run       bool
-	name      string
-	operation string
-}
+// Guards the objects printed by concurrent dry runs
+var dryRunMutex sync.Mutex
 
-func printUpdateFunc(res *installResult, wg *sync.WaitGroup) {
-	i := 0
-	for res.run {
-		fmt.Printf("Creating/Updating kube_resource: %s (%d seconds has passed) \n", res.name, i*10)
-		i++
-		time.Sleep(time.Second * 10)
+// Sends every resource to the api server with DryRun: All in the graph order
+// Prints the objects returned by the server, the state is never updated
+// The kube context is printed with each object when the release is installed to several contexts
+// With json output each object is reported as a dry run event instead
+func dryRunInstall(ctx context.Context, g *configs.Graph, clusters *kubeclient.Clusters, kubeContext string, output string, reporter ProgressReporter) hcl.Diagnostics {
+	dryRunFunc := func(v dag.Vertex) hcl.Diagnostics {
+		switch tt := v.(type) {
+		case *decode.DecodedResource:
+			objects, dryRunDiags := clusters.For(tt.Cluster).ServerDryRun(tt)
+			dryRunMutex.Lock()
+			defer dryRunMutex.Unlock()
+			for key, obj := range objects {
+				if output == OutputJSON {
+					reporter.Report(ProgressEvent{Type: EventDryRun, Resource: key, Object: obj, Time: time.Now()})
+					continue
+				}
+				out, err := yaml.Marshal(obj)
+				if err != nil {
+					dryRunDiags = append(dryRunDiags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Resource couldn't be marshalled",
+						Detail:   fmt.Sprintf("Resource: %s couldn't be marshalled", key),
+						Subject:  &tt.DeclRange,
+					})
+					continue
+				}
+				if kubeContext != "" {
+					fmt.Printf("# Context: %s\n", kubeContext)
+				}
+				fmt.Printf("# Resource: %s\n\n", key)
+				fmt.Printf("%s---\n", string(out))
+			}
+			return dryRunDiags
+		}
+		return nil
 	}
-	fmt.Printf("%s kube_resource: %s\n", res.operation, res.name)
-	wg.Done()
 
+	return g.WalkContext(ctx, dryRunFunc)
 }
 
 // Parses arguemtns for install command
@@ -50,48 +80,106 @@ func parseInstallArgs(args []string) (string, string, hcl.Diagnostics) {
 
 }
 
-// Install expects 2 arguments
-// 1. Release name, name of the release to be saved.
-// 2. Folder name which folder to decode
-// The rest is environment variables and flags of the settings for example namespace otherwise it will use the default settings
-// After parsing the variables install will decode the folder, validate the configuration and create the components.
-func Install(args []string, conf *settings.EnvSettings, viewArguments *view.ViewArgs, cmdSettings *settings.CmdSettings, createNamespace bool) {
-	name, folderName, diags := parseInstallArgs(args)
-	if diags.HasErrors() {
-		v.DiagPrinter(diags, viewArguments)
-		return
-	}
-
-	varsF, vals, diags := parseCmdSettings(cmdSettings)
+// installOptions contains the parsed arguments and flags of the install command
+type installOptions struct {
+	name            string
+	folderName      string
+	varsF           string
+	vals            []string
+	targets         configs.Targets
+	replace         configs.Targets
+	propagation     metav1.DeletionPropagation
+	cmdSettings     *settings.CmdSettings
+	installSettings *settings.InstallSettings
+}
 
+// Decodes the folder for the kube context and builds the graph of the resources to install
+// An empty kube context decodes the folder without context overrides
+// kube_data blocks and the release are read from the cluster of the settings, the cluster values from the cluster each module is deployed to
+func decodeRelease(ctx context.Context, opts *installOptions, conf *settings.EnvSettings, kubeContext string) (*configs.Graph, *decode.DecodedModule, hcl.Diagnostics) {
+	release, diags := kubeclient.ReadRelease(ctx, opts.name, conf)
 	if diags.HasErrors() {
-		v.DiagPrinter(diags, viewArguments)
-		return
+		return nil, &decode.DecodedModule{}, diags
 	}
-
-	d, decodeDiags := configs.DecodeFolderAndModules(name, folderName, "root", varsF, vals, 0)
+	d, decodeDiags := configs.DecodeFolder(&configs.DecodeOptions{
+		Release:       release,
+		FolderName:    opts.folderName,
+		VarsFile:      opts.varsF,
+		KubeContext:   kubeContext,
+		Vals:          opts.vals,
+		DataReader:    kubeclient.NewDataReader(ctx, conf),
+		ClusterReader: kubeclient.NewClusterReader(conf),
+	})
 	diags = append(diags, decodeDiags...)
 	if diags.HasErrors() {
-		v.DiagPrinter(diags, viewArguments)
-		os.Exit(1)
+		return nil, d, diags
 	}
 	g := &configs.Graph{
-		DecodedModule: d,
+		DecodedModule:       d,
+		DisableKindOrdering: opts.cmdSettings.DisableKindOrdering,
 	}
 	diags = append(diags, g.Init()...)
-	cfg, cfgDiags := kubeclient.New(name, conf, d.BackendStorage.Kind)
-	diags = append(diags, cfgDiags...)
+	if !diags.HasErrors() {
+		diags = append(diags, g.Target(opts.targets)...)
+		diags = append(diags, g.CheckReplace(opts.replace)...)
+	}
+	return g, d, diags
+}
 
+// Returns the namespaces of the resources deployed to the cluster alias
+// Namespaces declared as resources of the release are created by the walk and are not returned
+func resourceNamespaces(g *configs.Graph, alias string) []string {
+	var namespaces, declared []string
+	for _, v := range g.Vertices() {
+		if tt, ok := v.(*decode.DecodedResource); ok && tt.Cluster == alias {
+			namespaces = append(namespaces, kubeclient.ResourceNamespaces(tt)...)
+			declared = append(declared, kubeclient.DeclaredNamespaces(tt)...)
+		}
+	}
+	return slices.DeleteFunc(namespaces, func(namespace string) bool {
+		return slices.Contains(declared, namespace)
+	})
+}
+
+// Returns the sorted instance keys of the resource
+func instanceKeys(r *decode.DecodedResource) []string {
+	keys := make([]string, 0, len(r.Config))
+	for key := range r.Config {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Installs the decoded release to the clusters of the settings and reports the progress to the reporter
+// The state of every cluster is locked while it is changed
+// The reporter is closed before returning
+func installRelease(ctx context.Context, opts *installOptions, conf *settings.EnvSettings, g *configs.Graph, d *decode.DecodedModule, reporter ProgressReporter, kubeContext string) (diags hcl.Diagnostics) {
+	defer reporter.Close()
+	installSettings := opts.installSettings
+
+	clusters, diags := kubeclient.NewClusters(ctx, opts.name, conf, d.BackendStorage.Kind, d.Clusters)
 	if diags.HasErrors() {
-		v.DiagPrinter(diags, viewArguments)
-		os.Exit(1)
+		return diags
+	}
+	for _, cfg := range clusters.All() {
+		cfg.DeletionPropagation = opts.propagation
+		cfg.DryRun = installSettings.DryRun == kubeclient.DryRunServer
+		diags = append(diags, cfg.ConfigureApply(installSettings.ApplySettings)...)
+		if len(opts.replace) > 0 {
+			cfg.Replace = opts.replace.Match
+		}
+		diags = append(diags, cfg.ConfigureRetry(installSettings.RetrySettings)...)
+	}
+	if diags.HasErrors() {
+		return diags
 	}
-	v.DiagPrinter(diags, viewArguments)
 
-	diags = cfg.VerifyInstall(createNamespace)
+	for _, cfg := range clusters.All() {
+		diags = append(diags, cfg.VerifyInstall(installSettings.CreateNamespace, resourceNamespaces(g, cfg.Cluster))...)
+	}
 	if diags.HasErrors() {
-		v.DiagPrinter(diags, viewArguments)
-		os.Exit(1)
+		return diags
 	}
 
 	var results = kube.Result{}
@@ -99,64 +187,217 @@ func Install(args []string, conf *settings.EnvSettings, viewArguments *view.View
 	validateFunc := func(v dag.Vertex) hcl.Diagnostics {
 		switch tt := v.(type) {
 		case *decode.DecodedResource:
-			return cfg.Validate(tt)
+			return clusters.For(tt.Cluster).Validate(tt)
 		}
 		return nil
 	}
+	tracker := newProgressTracker()
+	replaced := make(map[string]bool)
+	onPhase := func(key string, phase kubeclient.Phase) {
+		switch phase {
+		case kubeclient.PhaseReplacing:
+			mutex.Lock()
+			replaced[key] = true
+			mutex.Unlock()
+			reporter.Report(tracker.event(EventReplacing, key))
+		case kubeclient.PhaseApplied:
+			reporter.Report(tracker.event(EventApplied, key))
+		case kubeclient.PhaseWaiting:
+			reporter.Report(tracker.event(EventWaiting, key))
+		}
+	}
+	onRollout := func(key string, progress kubeclient.RolloutProgress) {
+		event := tracker.event(EventProgress, key)
+		event.Rollout = &progress
+		event.Message = progress.String()
+		reporter.Report(event)
+	}
+	for _, cfg := range clusters.All() {
+		cfg.OnPhase = onPhase
+		cfg.OnRollout = onRollout
+	}
 	createFunc := func(v dag.Vertex) hcl.Diagnostics {
 		switch tt := v.(type) {
 		case *decode.DecodedResource:
-			// fmt.Printf("Creating/Updating resource: %s\n", tt.Name)
-			installRes := &installResult{}
-			installRes.name = tt.Name
-			installRes.run = true
-			installRes.operation = "Created"
-			var wg sync.WaitGroup
-			wg.Add(1)
-			go printUpdateFunc(installRes, &wg)
-			res, createDiags := cfg.Create(tt)
-			if !createDiags.HasErrors() {
-				if len(res.Updated) > 0 {
-					installRes.operation = "Updated"
+			// Events are reported for each instance so every event uses the address of the state
+			keys := instanceKeys(tt)
+			for _, key := range keys {
+				reporter.Report(tracker.start(key))
+			}
+			res, createDiags := clusters.For(tt.Cluster).Create(tt)
+			if createDiags.HasErrors() {
+				for _, key := range keys {
+					reporter.Report(tracker.event(EventFailed, key))
 				}
-				if len(res.Deleted) > 0 {
-					installRes.operation = "Deleted"
+				return createDiags
+			}
+
+			operation := "Created"
+			if len(res.Updated) > 0 {
+				operation = "Updated"
+			}
+			if len(res.Deleted) > 0 {
+				operation = "Deleted"
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			for _, key := range keys {
+				event := tracker.event(EventReady, key)
+				event.Operation = operation
+				if replaced[key] {
+					event.Operation = "Replaced"
 				}
-				mutex.Lock()
-				defer mutex.Unlock()
-				results.Created = append(results.Created, res.Created...)
-				results.Updated = append(results.Updated, res.Updated...)
-				results.Deleted = append(results.Deleted, res.Deleted...)
-				// fmt.Printf("%s resource: %s\n",operation, tt.Name)
-			} else {
-				installRes.operation = "Failed to perform any action on"
+				reporter.Report(event)
 			}
-			installRes.run = false
-			wg.Wait()
 
+			results.Created = append(results.Created, res.Created...)
+			results.Updated = append(results.Updated, res.Updated...)
+			results.Deleted = append(results.Deleted, res.Deleted...)
 			return createDiags
-			// fmt.Printf("%s",asdf.Created[0])
 		}
 		return nil
 	}
-	validateDiags := g.Walk(validateFunc)
-	if len(validateDiags) > 0 {
-		v.DiagPrinter(validateDiags[0:1], viewArguments)
-		os.Exit(1)
+	// Every resource is validated so all the errors are reported at once
+	diags = append(diags, g.Walk(validateFunc)...)
+	if diags.HasErrors() {
+		return diags
 	}
 
-	if !diags.HasErrors() {
-		diags = append(diags, g.Walk(createFunc)...)
+	if clusters.Default().DryRun {
+		diags = append(diags, clusters.Move(g.Moved)...)
+		return append(diags, dryRunInstall(ctx, g, clusters, kubeContext, installSettings.Output, reporter)...)
+	}
+
+	diags = append(diags, clusters.Lock()...)
+	if diags.HasErrors() {
+		return diags
+	}
+	defer func() {
+		diags = append(diags, clusters.Unlock()...)
+	}()
+	// The state is read again once locked so the saved resources are renamed after locking
+	diags = append(diags, clusters.Move(g.Moved)...)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	summary, walkDiags := g.WalkSummary(ctx, !installSettings.ContinueOnError, createFunc)
+	diags = append(diags, walkDiags...)
+
+	// Skipped resources were never applied, they keep their previous state and are not pruned
+	skipped := make(map[string]bool)
+	for _, v := range summary.Skipped {
+		if tt, ok := v.(*decode.DecodedResource); ok {
+			for _, key := range instanceKeys(tt) {
+				event := tracker.event(EventSkipped, key)
+				event.Message = "not applied because a resource failed"
+				reporter.Report(event)
+				skipped[key] = true
+			}
+		}
+	}
+	var targeted func(key string) bool
+	if len(opts.targets) > 0 || len(skipped) > 0 {
+		targeted = func(key string) bool {
+			return !skipped[key] && (len(opts.targets) == 0 || opts.targets.Match(key))
+		}
+	}
+	for _, cfg := range clusters.All() {
+		if ctx.Err() != nil {
+			// Resources which were not reached keep their previous state and nothing is pruned
+			diags = append(diags, cfg.KeepUnappliedState(nil)...)
+			continue
+		}
 		// if cfg.StorageKind != "stateless" {
-		saved, _, delDiags := cfg.DeleteResources()
+		saved, _, delDiags := cfg.DeleteResources(targeted)
 		diags = append(diags, delDiags...)
-		for key := range saved {
-			fmt.Printf("Deleted resource: %s\n", key)
+		for key, deleted := range saved {
+			event := tracker.event(EventDeleted, key)
+			if !deleted {
+				event.Message = "Removed resource from state without deleting it"
+			}
+			reporter.Report(event)
 		}
 		// }
+		if targeted != nil {
+			// Resources which were not targeted or were skipped keep their previous state
+			diags = append(diags, cfg.KeepUnappliedState(targeted)...)
+		}
 	}
-	diags = append(diags, cfg.Storage.UpdateState()...)
+	diags = append(diags, clusters.Default().SaveOutputs(d.Outputs)...)
+	diags = append(diags, clusters.UpdateState()...)
+	return diags
+}
 
-	v.DiagPrinter(diags, viewArguments)
+// Install expects 2 arguments
+// 1. Release name, name of the release to be saved.
+// 2. Folder name which folder to decode
+// The rest is environment variables and flags of the settings for example namespace otherwise it will use the default settings
+// After parsing the variables install will decode the folder, validate the configuration and create the components.
+// With --contexts the release is installed to each kube context concurrently
+func Install(ctx context.Context, args []string, conf *settings.EnvSettings, viewArguments *view.ViewArgs, cmdSettings *settings.CmdSettings, installSettings *settings.InstallSettings) {
+	if installSettings.Output == OutputJSON {
+		// Stdout only contains the json events, diagnostics and logs are written to stderr
+		viewArguments.Stderr = true
+		logging.SetLoggerWriter(conf.Debug, os.Stderr)
+	}
+	name, folderName, diags := parseInstallArgs(args)
+	if diags.HasErrors() {
+		v.DiagPrinter(diags, viewArguments)
+		return
+	}
+
+	varsF, vals, diags := parseCmdSettings(cmdSettings)
 
+	if diags.HasErrors() {
+		v.DiagPrinter(diags, viewArguments)
+		return
+	}
+
+	propagation, cascadeDiags := kubeclient.ParseCascade(installSettings.Cascade)
+	diags = append(diags, cascadeDiags...)
+	diags = append(diags, kubeclient.ParseDryRun(installSettings.DryRun)...)
+	diags = append(diags, parseOutput(installSettings.Output)...)
+	targets, targetDiags := configs.ParseTargets(cmdSettings.Targets)
+	diags = append(diags, targetDiags...)
+	replace, replaceDiags := configs.ParseTargets(installSettings.Replace)
+	diags = append(diags, replaceDiags...)
+	if diags.HasErrors() {
+		v.DiagPrinter(diags, viewArguments)
+		return
+	}
+
+	opts := &installOptions{
+		name:            name,
+		folderName:      folderName,
+		varsF:           varsF,
+		vals:            vals,
+		targets:         targets,
+		replace:         replace,
+		propagation:     propagation,
+		cmdSettings:     cmdSettings,
+		installSettings: installSettings,
+	}
+
+	if len(installSettings.Contexts) > 0 {
+		installContexts(ctx, opts, conf, viewArguments)
+		return
+	}
+
+	g, d, diags := decodeRelease(ctx, opts, conf, "")
+	if diags.HasErrors() {
+		v.DiagPrinter(diags, viewArguments)
+		os.Exit(1)
+	}
+
+	reporter := newProgressReporter(installSettings.Output, os.Stdout)
+	diags = append(diags, installRelease(ctx, opts, conf, g, d, reporter, "")...)
+	v.DiagPrinter(diags, viewArguments)
+	if ctx.Err() != nil {
+		exitCancelled(ctx)
+	}
+	if diags.HasErrors() {
+		os.Exit(1)
+	}
 }
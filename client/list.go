@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"kubehcl.sh/kubehcl/internal/view"
 	"kubehcl.sh/kubehcl/kube/kubeclient"
@@ -8,8 +9,8 @@ import (
 )
 
 // list the installations of kubehcl in the namespace
-func List(conf *settings.EnvSettings, viewArguments *view.ViewArgs, storageKind string) {
-	cfg, diags := kubeclient.New("", conf, storageKind)
+func List(ctx context.Context, conf *settings.EnvSettings, viewArguments *view.ViewArgs, storageKind string) {
+	cfg, diags := kubeclient.New(ctx, "", conf, storageKind)
 	if diags.HasErrors() {
 		v.DiagPrinter(diags, viewArguments)
 	} else {
@@ -0,0 +1,193 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"kubehcl.sh/kubehcl/internal/view"
+	"kubehcl.sh/kubehcl/kube/kubeclient"
+	"kubehcl.sh/kubehcl/kube/kubeclient/storage"
+	"kubehcl.sh/kubehcl/settings"
+)
+
+const OutputRaw = "raw"
+
+// Parses arguments for output command
+func parseOutputArgs(args []string) (string, string, hcl.Diagnostics) {
+	switch len(args) {
+	case 1:
+		return args[0], "", hcl.Diagnostics{}
+	case 2:
+		return args[0], args[1], hcl.Diagnostics{}
+	}
+	return "", "", hcl.Diagnostics{
+		&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Required arguments are :[name] and optionally [output name]",
+		},
+	}
+}
+
+// Decodes the json value of a saved output into a cty value
+func outputValue(name string, output storage.Output) (cty.Value, hcl.Diagnostics) {
+	ty, err := ctyjson.UnmarshalType(output.Type)
+	if err == nil {
+		var value cty.Value
+		if value, err = ctyjson.Unmarshal(output.Value, ty); err == nil {
+			return value, hcl.Diagnostics{}
+		}
+	}
+	return cty.NilVal, hcl.Diagnostics{
+		&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Couldn't read output",
+			Detail:   fmt.Sprintf("Output %s in the state is invalid: %s", name, err),
+		},
+	}
+}
+
+// Formats a primitive value without quotes
+func rawValue(name string, value cty.Value) (string, hcl.Diagnostics) {
+	switch {
+	case value.IsNull():
+		return "", hcl.Diagnostics{}
+	case value.Type() == cty.String:
+		return value.AsString(), hcl.Diagnostics{}
+	case value.Type() == cty.Number:
+		return value.AsBigFloat().Text('f', -1), hcl.Diagnostics{}
+	case value.Type() == cty.Bool:
+		return fmt.Sprintf("%t", value.True()), hcl.Diagnostics{}
+	}
+	return "", hcl.Diagnostics{
+		&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Unsupported value for raw output",
+			Detail:   fmt.Sprintf("Output %s is %s, raw output supports only strings, numbers and bools, use --output json instead", name, value.Type().FriendlyName()),
+		},
+	}
+}
+
+// Prints the outputs in the requested format
+// With a name only the value of that output is printed, sensitive values are hidden only in the human format listing all outputs
+// showSensitive prints the sensitive values in the human format as well
+func printOutputs(w io.Writer, outputs map[string]storage.Output, name string, format string, showSensitive bool) hcl.Diagnostics {
+	if name != "" {
+		output, exists := outputs[name]
+		if !exists {
+			return hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Output not found",
+					Detail:   fmt.Sprintf("The release has no output named %s", name),
+				},
+			}
+		}
+		switch format {
+		case OutputRaw:
+			value, diags := outputValue(name, output)
+			if diags.HasErrors() {
+				return diags
+			}
+			raw, diags := rawValue(name, value)
+			if diags.HasErrors() {
+				return diags
+			}
+			_, _ = fmt.Fprint(w, raw)
+		default:
+			_, _ = fmt.Fprintf(w, "%s\n", output.Value)
+		}
+		return hcl.Diagnostics{}
+	}
+
+	names := make([]string, 0, len(outputs))
+	for key := range outputs {
+		names = append(names, key)
+	}
+	slices.Sort(names)
+
+	switch format {
+	case OutputRaw:
+		return hcl.Diagnostics{
+			&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Raw output requires an output name",
+				Detail:   "Raw output prints the value of a single output, add the name of the output",
+			},
+		}
+	case OutputJSON:
+		out, err := json.MarshalIndent(outputs, "", "  ")
+		if err != nil {
+			panic("Should not get here: " + err.Error())
+		}
+		_, _ = fmt.Fprintf(w, "%s\n", out)
+	default:
+		for _, key := range names {
+			if outputs[key].Sensitive && !showSensitive {
+				_, _ = fmt.Fprintf(w, "%s = <sensitive>\n", key)
+				continue
+			}
+			var value bytes.Buffer
+			if err := json.Indent(&value, outputs[key].Value, "", "  "); err != nil {
+				value.Write(outputs[key].Value)
+			}
+			_, _ = fmt.Fprintf(w, "%s = %s\n", key, value.String())
+		}
+	}
+	return hcl.Diagnostics{}
+}
+
+// Output expects 1 or 2 arguments
+// 1. Release name, name of the release to read the outputs from.
+// 2. Optional output name, prints only the value of that output
+// Outputs are read from the state saved by the last install
+func Output(ctx context.Context, args []string, conf *settings.EnvSettings, viewArguments *view.ViewArgs, outputSettings *settings.OutputSettings) {
+	name, outputName, diags := parseOutputArgs(args)
+	switch outputSettings.Output {
+	case OutputHuman, OutputJSON, OutputRaw:
+	default:
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid output option",
+			Detail:   fmt.Sprintf("Output must be one of [human, json, raw] got: %s", outputSettings.Output),
+		})
+	}
+	if diags.HasErrors() {
+		v.DiagPrinter(diags, viewArguments)
+		os.Exit(1)
+	}
+
+	cfg, diags := kubeclient.New(ctx, name, conf, "kube_secret")
+	if diags.HasErrors() {
+		v.DiagPrinter(diags, viewArguments)
+		os.Exit(1)
+	}
+	secrets, diags := cfg.List()
+	if !diags.HasErrors() && !slices.Contains(secrets, "kubehcl."+name) {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Release does not exist",
+			Detail:   fmt.Sprintf("The release you provided \"%s\" does not exist in the given namespace \"%s\"", name, conf.Namespace()),
+		})
+	}
+	if diags.HasErrors() {
+		v.DiagPrinter(diags, viewArguments)
+		os.Exit(1)
+	}
+
+	outputs, diags := cfg.Outputs()
+	if !diags.HasErrors() {
+		diags = append(diags, printOutputs(os.Stdout, outputs, outputName, outputSettings.Output, viewArguments.ShowSensitive)...)
+	}
+	if diags.HasErrors() {
+		v.DiagPrinter(diags, viewArguments)
+		os.Exit(1)
+	}
+}
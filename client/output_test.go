@@ -0,0 +1,60 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"kubehcl.sh/kubehcl/kube/kubeclient/storage"
+)
+
+func Test_PrintOutputs(t *testing.T) {
+	outputs := map[string]storage.Output{
+		"host":     {Value: json.RawMessage(`"example.com"`), Type: json.RawMessage(`"string"`)},
+		"replicas": {Value: json.RawMessage(`3`), Type: json.RawMessage(`"number"`)},
+		"password": {Value: json.RawMessage(`"secret"`), Type: json.RawMessage(`"string"`), Sensitive: true},
+		"ports":    {Value: json.RawMessage(`[80,443]`), Type: json.RawMessage(`["list","number"]`)},
+	}
+
+	tests := []struct {
+		name          string
+		format        string
+		showSensitive bool
+		want          string
+		wantErrors    bool
+	}{
+		{format: OutputHuman, want: "host = \"example.com\"\npassword = <sensitive>\nports = [\n  80,\n  443\n]\nreplicas = 3\n"},
+		{format: OutputHuman, showSensitive: true, want: "host = \"example.com\"\npassword = \"secret\"\nports = [\n  80,\n  443\n]\nreplicas = 3\n"},
+		{name: "host", format: OutputRaw, want: "example.com"},
+		{name: "replicas", format: OutputRaw, want: "3"},
+		{name: "ports", format: OutputJSON, want: "[80,443]\n"},
+		{name: "password", format: OutputRaw, want: "secret"},
+		{name: "ports", format: OutputRaw, wantErrors: true},
+		{format: OutputRaw, wantErrors: true},
+		{name: "missing", format: OutputHuman, wantErrors: true},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		diags := printOutputs(&buf, outputs, test.name, test.format, test.showSensitive)
+		if diags.HasErrors() != test.wantErrors {
+			t.Errorf("Output %q format %s: expected errors %t got: %s", test.name, test.format, test.wantErrors, diags.Errs())
+			continue
+		}
+		if !test.wantErrors && buf.String() != test.want {
+			t.Errorf("Output %q format %s: expected %q got %q", test.name, test.format, test.want, buf.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if diags := printOutputs(&buf, outputs, "", OutputJSON, false); diags.HasErrors() {
+		t.Fatalf("Don't want errors but received: %s", diags.Errs())
+	}
+	var decoded map[string]map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Outputs are not valid json: %s", err)
+	}
+	if decoded["host"]["value"] != "example.com" || decoded["password"]["sensitive"] != true {
+		t.Errorf("Unexpected json outputs: %s", buf.String())
+	}
+}
@@ -1,11 +1,13 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"sync"
 
 	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
 	"helm.sh/helm/v4/pkg/kube"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -46,7 +48,7 @@ func adjustCmp(m map[string]*view.CompareResources) {
 	}
 }
 
-func Plan(args []string, conf *settings.EnvSettings, viewArguments *view.ViewArgs, cmdSettings *settings.CmdSettings) {
+func Plan(ctx context.Context, args []string, conf *settings.EnvSettings, viewArguments *view.ViewArgs, cmdSettings *settings.CmdSettings, applySettings *settings.ApplySettings) {
 	logging.KubeLogger.Info(fmt.Sprintf("Parsing install arguments %s", args))
 
 	name, folderName, diags := parseInstallArgs(args)
@@ -56,13 +58,31 @@ func Plan(args []string, conf *settings.EnvSettings, viewArguments *view.ViewArg
 	}
 
 	varsF, vals, diags := parseCmdSettings(cmdSettings)
+	targets, targetDiags := configs.ParseTargets(cmdSettings.Targets)
+	diags = append(diags, targetDiags...)
+	replace, replaceDiags := configs.ParseTargets(applySettings.Replace)
+	diags = append(diags, replaceDiags...)
 
 	if diags.HasErrors() {
 		v.DiagPrinter(diags, viewArguments)
 		return
 	}
 
-	d, decodeDiags := configs.DecodeFolderAndModules(name, folderName, "root", varsF, vals, 0)
+	release, releaseDiags := kubeclient.ReadRelease(ctx, name, conf)
+	diags = append(diags, releaseDiags...)
+	if diags.HasErrors() {
+		v.DiagPrinter(diags, viewArguments)
+		return
+	}
+
+	d, decodeDiags := configs.DecodeFolder(&configs.DecodeOptions{
+		Release:       release,
+		FolderName:    folderName,
+		VarsFile:      varsF,
+		Vals:          vals,
+		DataReader:    kubeclient.NewDataReader(ctx, conf),
+		ClusterReader: kubeclient.NewClusterReader(conf),
+	})
 	diags = append(diags, decodeDiags...)
 	if diags.HasErrors() {
 		v.DiagPrinter(diags, viewArguments)
@@ -81,10 +101,15 @@ func Plan(args []string, conf *settings.EnvSettings, viewArguments *view.ViewArg
 	}
 
 	g := &configs.Graph{
-		DecodedModule: d,
+		DecodedModule:       d,
+		DisableKindOrdering: cmdSettings.DisableKindOrdering,
 	}
 	diags = append(diags, g.Init()...)
-	cfg, cfgDiags := kubeclient.New(name, conf, d.BackendStorage.Kind)
+	if !diags.HasErrors() {
+		diags = append(diags, g.Target(targets)...)
+		diags = append(diags, g.CheckReplace(replace)...)
+	}
+	clusters, cfgDiags := kubeclient.NewClusters(ctx, name, conf, d.BackendStorage.Kind, d.Clusters)
 	diags = append(diags, cfgDiags...)
 
 	if diags.HasErrors() {
@@ -92,27 +117,47 @@ func Plan(args []string, conf *settings.EnvSettings, viewArguments *view.ViewArg
 		os.Exit(1)
 	}
 
+	for _, cfg := range clusters.All() {
+		diags = append(diags, cfg.ConfigureApply(applySettings)...)
+		if len(replace) > 0 {
+			cfg.Replace = replace.Match
+		}
+	}
+	if diags.HasErrors() {
+		v.DiagPrinter(diags, viewArguments)
+		os.Exit(1)
+	}
+
 	var mutex sync.Mutex
 	validateFunc := func(v dag.Vertex) hcl.Diagnostics {
 		switch tt := v.(type) {
 		case *decode.DecodedResource:
-			return cfg.Validate(tt)
+			return clusters.For(tt.Cluster).Validate(tt)
 		}
 		return nil
 	}
-	wantedMap := make(map[string]kube.ResourceList)
+	// Wanted resources of each cluster alias
+	wantedMaps := make(map[string]map[string]kube.ResourceList)
+	recreateMap := make(map[string]bool)
+	// Paths of the sensitive values of each wanted resource
+	sensitiveMap := make(map[string][]cty.Path)
 
 	planFunc := func(v dag.Vertex) hcl.Diagnostics {
 		switch tt := v.(type) {
 		case *decode.DecodedResource:
 			if len(tt.Config) > 0 {
 				// fmt.Printf("%s\n",tt.Name)
-				wanted, planDiags := cfg.BuildResource(tt)
+				wanted, planDiags := clusters.For(tt.Cluster).BuildResource(tt)
 				if !planDiags.HasErrors() {
 					mutex.Lock()
 					defer mutex.Unlock()
+					if wantedMaps[tt.Cluster] == nil {
+						wantedMaps[tt.Cluster] = make(map[string]kube.ResourceList)
+					}
 					for key, value := range wanted {
-						wantedMap[key] = value
+						wantedMaps[tt.Cluster][key] = value
+						recreateMap[key] = tt.Lifecycle.RecreateOnImmutable
+						sensitiveMap[key] = tt.Sensitive[key]
 					}
 				}
 				return planDiags
@@ -124,22 +169,54 @@ func Plan(args []string, conf *settings.EnvSettings, viewArguments *view.ViewArg
 
 	diags = append(diags, g.Walk(validateFunc)...)
 	if !diags.HasErrors() {
-		diags = append(diags, g.Walk(planFunc)...)
+		diags = append(diags, g.WalkContext(ctx, planFunc)...)
 	}
 	if diags.HasErrors() {
 		v.DiagPrinter(diags, viewArguments)
+		if ctx.Err() != nil {
+			exitCancelled(ctx)
+		}
 		return
 	}
 
-	currentMap, diags := cfg.GetStateResourcesCurrentState()
-	if diags.HasErrors() {
-		v.DiagPrinter(diags, viewArguments)
-		return
-	}
-	cmps, diags := cfg.CompareResources(wantedMap, currentMap)
-	if diags.HasErrors() {
-		v.DiagPrinter(diags, viewArguments)
-		return
+	cmps := make(map[string]*view.CompareResources)
+	for _, cfg := range clusters.All() {
+		wantedMap := wantedMaps[cfg.Cluster]
+		// Saved resources are renamed by the moved blocks before they are compared
+		movedFrom, diags := cfg.Move(g.Moved)
+		if diags.HasErrors() {
+			v.DiagPrinter(diags, viewArguments)
+			return
+		}
+		currentMap, diags := cfg.GetStateResourcesCurrentState()
+		if diags.HasErrors() {
+			v.DiagPrinter(diags, viewArguments)
+			return
+		}
+		if len(targets) > 0 {
+			// Resources in the state which are not targeted are left untouched
+			for key := range currentMap {
+				if _, wanted := wantedMap[key]; !wanted && !targets.Match(key) {
+					delete(currentMap, key)
+				}
+			}
+		}
+		clusterCmps, diags := cfg.CompareResources(wantedMap, currentMap, func(key string) bool {
+			return recreateMap[key]
+		})
+		if diags.HasErrors() {
+			v.DiagPrinter(diags, viewArguments)
+			return
+		}
+		// Resources of other clusters are named after their cluster since an address may move between clusters
+		for key, cmp := range clusterCmps {
+			cmp.Sensitive = sensitiveMap[key]
+			cmp.MovedFrom = movedFrom[key]
+			if cfg.Cluster != kubeclient.DefaultCluster {
+				key = fmt.Sprintf("%s (cluster.%s)", key, cfg.Cluster)
+			}
+			cmps[key] = cmp
+		}
 	}
 	adjustCmp(cmps)
 	v.PlanPrinter(cmps, viewArguments)
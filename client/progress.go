@@ -0,0 +1,352 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"golang.org/x/term"
+	"k8s.io/apimachinery/pkg/runtime"
+	"kubehcl.sh/kubehcl/kube/kubeclient"
+)
+
+// Type of a progress event
+type EventType string
+
+const (
+	EventStarted EventType = "started"
+	EventApplied EventType = "applied"
+	EventWaiting EventType = "waiting"
+	EventReady   EventType = "ready"
+	EventFailed  EventType = "failed"
+	EventDeleted EventType = "deleted"
+	// EventReplacing is reported before a resource is deleted in order to be created again
+	EventReplacing EventType = "replacing"
+	// EventProgress is reported when the rollout progress of a workload changes while waiting for it
+	EventProgress EventType = "progress"
+	// EventSkipped is reported after the walk for each resource which was never applied because a resource failed
+	EventSkipped EventType = "skipped"
+	// EventDryRun is reported with the object returned by the api server for each resource of a server-side dry run
+	EventDryRun EventType = "dry_run"
+)
+
+// Status of a resource in the summary printed by the human renderer
+const (
+	ResourceSucceeded = "succeeded"
+	ResourceFailed    = "failed"
+	ResourceSkipped   = "skipped"
+)
+
+const (
+	OutputHuman = "human"
+	OutputJSON  = "json"
+)
+
+// How often the human renderer prints the resources which are still in progress
+const progressInterval = 10 * time.Second
+
+// How often the human renderer redraws the resources which are still in progress on a terminal
+const terminalProgressInterval = time.Second
+
+// ProgressEvent describes a change in the progress of a single resource instance
+// Resource is the address of the instance as saved in the state such as module.foo.kube_resource.bar["x"]
+// Duration is the time passed since the resource was started
+type ProgressEvent struct {
+	Type     EventType `json:"type"`
+	Resource string    `json:"resource"`
+	// Context is the kube context of the resource when the release is installed to several contexts
+	Context   string `json:"context,omitempty"`
+	Operation string `json:"operation,omitempty"`
+	Message   string `json:"message,omitempty"`
+	// Rollout is set on progress events of workloads
+	Rollout *kubeclient.RolloutProgress `json:"rollout,omitempty"`
+	// Object is the object returned by the api server on dry run events
+	Object   runtime.Object `json:"object,omitempty"`
+	Duration time.Duration  `json:"-"`
+	Time     time.Time      `json:"time"`
+}
+
+// Encodes the duration of the event in milliseconds
+func (e ProgressEvent) MarshalJSON() ([]byte, error) {
+	type event ProgressEvent
+	return json.Marshal(struct {
+		event
+		DurationMs int64 `json:"duration_ms"`
+	}{
+		event:      event(e),
+		DurationMs: e.Duration.Milliseconds(),
+	})
+}
+
+// ProgressReporter receives the progress events of an install
+// Implementations must be safe for concurrent use since vertices are walked in parallel
+type ProgressReporter interface {
+	Report(event ProgressEvent)
+	Close()
+}
+
+// Validates the output option
+func parseOutput(output string) hcl.Diagnostics {
+	switch output {
+	case OutputHuman, OutputJSON:
+		return hcl.Diagnostics{}
+	}
+	return hcl.Diagnostics{
+		&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid output option",
+			Detail:   fmt.Sprintf("Output must be one of [human, json] got: %s", output),
+		},
+	}
+}
+
+// Creates the progress reporter matching the output option
+// The human reporter updates the resources in progress in place when writing to a terminal
+func newProgressReporter(output string, w io.Writer) ProgressReporter {
+	if output == OutputJSON {
+		return &jsonReporter{encoder: json.NewEncoder(w)}
+	}
+	if f, ok := w.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		return newHumanReporter(w, terminalProgressInterval, true)
+	}
+	return newHumanReporter(w, progressInterval, false)
+}
+
+// Tracks the start time of each resource to calculate the duration of the events
+type progressTracker struct {
+	mutex   sync.Mutex
+	started map[string]time.Time
+}
+
+func newProgressTracker() *progressTracker {
+	return &progressTracker{started: make(map[string]time.Time)}
+}
+
+func (t *progressTracker) start(resource string) ProgressEvent {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	now := time.Now()
+	t.started[resource] = now
+	return ProgressEvent{Type: EventStarted, Resource: resource, Time: now}
+}
+
+// Creates an event for the resource with the duration since it was started
+func (t *progressTracker) event(eventType EventType, resource string) ProgressEvent {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	now := time.Now()
+	event := ProgressEvent{Type: eventType, Resource: resource, Time: now}
+	if start, exists := t.started[resource]; exists {
+		event.Duration = now.Sub(start)
+	}
+	return event
+}
+
+type jsonReporter struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+// Writes each event as a single json line
+func (r *jsonReporter) Report(event ProgressEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	_ = r.encoder.Encode(event)
+}
+
+func (r *jsonReporter) Close() {}
+
+type humanReporter struct {
+	mutex    sync.Mutex
+	w        io.Writer
+	inFlight map[string]time.Time
+	// rollouts contains the latest rollout progress of each instance key
+	rollouts map[string]*kubeclient.RolloutProgress
+	// tty redraws the resources in progress in place instead of printing them periodically
+	tty bool
+	// live is the number of lines drawn in place which are cleared before anything else is printed
+	live int
+	// statuses contains the status of each finished resource for the summary
+	statuses map[string]string
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+func newHumanReporter(w io.Writer, interval time.Duration, tty bool) *humanReporter {
+	r := &humanReporter{
+		w:        w,
+		inFlight: make(map[string]time.Time),
+		rollouts: make(map[string]*kubeclient.RolloutProgress),
+		statuses: make(map[string]string),
+		tty:      tty,
+		done:     make(chan struct{}),
+	}
+	r.wg.Add(1)
+	go r.printInFlight(interval)
+	return r
+}
+
+// Returns a line for each resource which did not finish yet
+// Resources which are rolling out contain the rollout progress of their instances
+func (r *humanReporter) inFlightLines(now time.Time) []string {
+	names := make([]string, 0, len(r.inFlight))
+	for name := range r.inFlight {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	keys := make([]string, 0, len(r.rollouts))
+	for key := range r.rollouts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, name := range names {
+		seconds := int(now.Sub(r.inFlight[name]).Seconds())
+		rolling := false
+		for _, key := range keys {
+			if isInstanceOf(key, name) {
+				rolling = true
+				lines = append(lines, fmt.Sprintf("Rolling out %s %s %s, %s (%d seconds has passed)", key, r.rollouts[key].Kind, r.rollouts[key].Name, r.rollouts[key], seconds))
+			}
+		}
+		if !rolling {
+			lines = append(lines, fmt.Sprintf("Still creating/updating %s (%d seconds has passed)", name, seconds))
+		}
+	}
+	return lines
+}
+
+// Checks if the instance key belongs to the resource
+func isInstanceOf(key, resource string) bool {
+	return key == resource || strings.HasPrefix(key, resource+"[")
+}
+
+// Clears the lines drawn in place
+func (r *humanReporter) clearLive() {
+	for ; r.live > 0; r.live-- {
+		_, _ = fmt.Fprint(r.w, "\x1b[1A\x1b[2K")
+	}
+}
+
+// Redraws the resources in progress in place
+func (r *humanReporter) drawLive(now time.Time) {
+	r.clearLive()
+	for _, line := range r.inFlightLines(now) {
+		_, _ = fmt.Fprintln(r.w, line)
+		r.live++
+	}
+}
+
+// Periodically prints the resources which did not finish yet
+// On a terminal the lines are redrawn in place
+func (r *humanReporter) printInFlight(interval time.Duration) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case now := <-ticker.C:
+			r.mutex.Lock()
+			if r.tty {
+				r.drawLive(now)
+			} else {
+				for _, line := range r.inFlightLines(now) {
+					_, _ = fmt.Fprintln(r.w, line)
+				}
+			}
+			r.mutex.Unlock()
+		}
+	}
+}
+
+func (r *humanReporter) Report(event ProgressEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.clearLive()
+	switch event.Type {
+	case EventStarted:
+		r.inFlight[event.Resource] = event.Time
+		_, _ = fmt.Fprintf(r.w, "Creating/Updating %s\n", event.Resource)
+	case EventApplied:
+		_, _ = fmt.Fprintf(r.w, "Applied %s [%s]\n", event.Resource, event.Duration.Round(time.Second))
+	case EventWaiting:
+		_, _ = fmt.Fprintf(r.w, "Waiting for %s to be ready\n", event.Resource)
+	case EventProgress:
+		// Rollout progress is shown with the resources in progress
+		if event.Rollout != nil {
+			r.rollouts[event.Resource] = event.Rollout
+		}
+	case EventReplacing:
+		_, _ = fmt.Fprintf(r.w, "Deleting %s to create it again\n", event.Resource)
+	case EventReady:
+		r.finish(event.Resource)
+		r.statuses[event.Resource] = ResourceSucceeded
+		_, _ = fmt.Fprintf(r.w, "%s %s [%s]\n", event.Operation, event.Resource, event.Duration.Round(time.Second))
+	case EventFailed:
+		r.finish(event.Resource)
+		r.statuses[event.Resource] = ResourceFailed
+		_, _ = fmt.Fprintf(r.w, "Failed to perform any action on %s [%s]\n", event.Resource, event.Duration.Round(time.Second))
+	case EventSkipped:
+		r.statuses[event.Resource] = ResourceSkipped
+		_, _ = fmt.Fprintf(r.w, "Skipped %s: %s\n", event.Resource, event.Message)
+	case EventDeleted:
+		if event.Message != "" {
+			_, _ = fmt.Fprintf(r.w, "%s: %s\n", event.Message, event.Resource)
+		} else {
+			_, _ = fmt.Fprintf(r.w, "Deleted resource: %s\n", event.Resource)
+		}
+	}
+	if r.tty {
+		r.drawLive(time.Now())
+	}
+}
+
+// Removes the resource and the rollout progress of its instances
+func (r *humanReporter) finish(resource string) {
+	delete(r.inFlight, resource)
+	for key := range r.rollouts {
+		if isInstanceOf(key, resource) {
+			delete(r.rollouts, key)
+		}
+	}
+}
+
+// Prints the status of each resource when any resource failed or was skipped
+func (r *humanReporter) printSummary() {
+	succeeded := true
+	names := make([]string, 0, len(r.statuses))
+	for name, status := range r.statuses {
+		names = append(names, name)
+		succeeded = succeeded && status == ResourceSucceeded
+	}
+	if succeeded {
+		return
+	}
+	sort.Strings(names)
+	tw := tabwriter.NewWriter(r.w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "\nRESOURCE\tSTATUS")
+	for _, name := range names {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\n", name, r.statuses[name])
+	}
+	_ = tw.Flush()
+}
+
+func (r *humanReporter) Close() {
+	close(r.done)
+	r.wg.Wait()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.clearLive()
+	r.printSummary()
+}
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"kubehcl.sh/kubehcl/kube/kubeclient"
+)
+
+func Test_JsonReporter(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := newProgressReporter(OutputJSON, &buf)
+	tracker := newProgressTracker()
+	reporter.Report(tracker.start("kube_resource.foo"))
+	event := tracker.event(EventReady, "kube_resource.foo")
+	event.Operation = "Created"
+	reporter.Report(event)
+	reporter.Close()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 events got: %d", len(lines))
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &decoded); err != nil {
+		t.Fatalf("Event is not valid json: %s", err)
+	}
+	for key, expected := range map[string]string{"type": "ready", "resource": "kube_resource.foo", "operation": "Created"} {
+		if decoded[key] != expected {
+			t.Errorf("Expected %s to be %s got: %v", key, expected, decoded[key])
+		}
+	}
+	if _, exists := decoded["duration_ms"]; !exists {
+		t.Errorf("Expected duration_ms in event: %s", lines[1])
+	}
+}
+
+func Test_HumanReporter(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := newHumanReporter(&buf, time.Hour, false)
+	reporter.Report(ProgressEvent{Type: EventStarted, Resource: "kube_resource.foo"})
+	reporter.Report(ProgressEvent{Type: EventDeleted, Resource: "kube_resource.bar", Message: "Removed resource from state without deleting it"})
+	reporter.Close()
+
+	expected := "Creating/Updating kube_resource.foo\nRemoved resource from state without deleting it: kube_resource.bar\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+	if _, exists := reporter.inFlight["kube_resource.foo"]; !exists {
+		t.Errorf("Expected kube_resource.foo to be in flight")
+	}
+}
+
+func Test_HumanReporterSummary(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := newHumanReporter(&buf, time.Hour, false)
+	reporter.Report(ProgressEvent{Type: EventReady, Resource: "kube_resource.foo", Operation: "Created"})
+	reporter.Report(ProgressEvent{Type: EventFailed, Resource: "kube_resource.bar"})
+	reporter.Report(ProgressEvent{Type: EventSkipped, Resource: "kube_resource.baz", Message: "not applied because a resource failed"})
+	reporter.Close()
+
+	expected := "Created kube_resource.foo [0s]\n" +
+		"Failed to perform any action on kube_resource.bar [0s]\n" +
+		"Skipped kube_resource.baz: not applied because a resource failed\n" +
+		"\n" +
+		"RESOURCE           STATUS\n" +
+		"kube_resource.bar  failed\n" +
+		"kube_resource.baz  skipped\n" +
+		"kube_resource.foo  succeeded\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func Test_HumanReporterRollout(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := newHumanReporter(&buf, time.Hour, false)
+	start := time.Now()
+	reporter.Report(ProgressEvent{Type: EventStarted, Resource: "kube_resource.web", Time: start})
+	reporter.Report(ProgressEvent{Type: EventStarted, Resource: "kube_resource.config", Time: start})
+	reporter.Report(ProgressEvent{
+		Type:     EventProgress,
+		Resource: "kube_resource.web[a]",
+		Rollout:  &kubeclient.RolloutProgress{Kind: "Deployment", Name: "web", Revision: "2", Desired: 3, Updated: 1, Ready: 2, Available: 2},
+	})
+
+	lines := reporter.inFlightLines(start.Add(5 * time.Second))
+	expected := []string{
+		"Still creating/updating kube_resource.config (5 seconds has passed)",
+		"Rolling out kube_resource.web[a] Deployment web, revision 2: 1/3 updated, 2/3 ready, 2/3 available (5 seconds has passed)",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(lines, "\n"))
+	}
+
+	reporter.Report(ProgressEvent{Type: EventReady, Resource: "kube_resource.web", Operation: "Updated"})
+	if len(reporter.rollouts) != 0 {
+		t.Errorf("Expected the rollout progress to be removed once the resource is ready")
+	}
+	reporter.Close()
+}
+
+func Test_HumanReporterTerminal(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := newHumanReporter(&buf, time.Hour, true)
+	reporter.Report(ProgressEvent{Type: EventStarted, Resource: "kube_resource.foo", Time: time.Now()})
+	reporter.Report(ProgressEvent{Type: EventReady, Resource: "kube_resource.foo", Operation: "Created"})
+	reporter.Close()
+
+	// The line in progress is drawn after the start event and cleared before the ready event
+	expected := "Creating/Updating kube_resource.foo\nStill creating/updating kube_resource.foo (0 seconds has passed)\n\x1b[1A\x1b[2KCreated kube_resource.foo [0s]\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%q\ngot:\n%q", expected, buf.String())
+	}
+}
+
+func Test_ParseOutput(t *testing.T) {
+	if diags := parseOutput("yaml"); !diags.HasErrors() {
+		t.Errorf("Expected invalid output to fail")
+	}
+	if diags := parseOutput(OutputJSON); diags.HasErrors() {
+		t.Errorf("Expected json output to be valid")
+	}
+}
+
+func Test_JsonReporterDryRun(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := newProgressReporter(OutputJSON, &buf)
+	obj := &unstructured.Unstructured{Object: map[string]any{"apiVersion": "v1", "kind": "ConfigMap", "metadata": map[string]any{"name": "foo"}}}
+	reporter.Report(ProgressEvent{Type: EventDryRun, Resource: `kube_resource.foo["a"]`, Object: obj, Time: time.Now()})
+	reporter.Close()
+
+	var decoded struct {
+		Type     string         `json:"type"`
+		Resource string         `json:"resource"`
+		Object   map[string]any `json:"object"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Event is not valid json: %s", err)
+	}
+	if decoded.Type != string(EventDryRun) || decoded.Resource != `kube_resource.foo["a"]` {
+		t.Errorf("Unexpected event: %s", buf.String())
+	}
+	if decoded.Object["kind"] != "ConfigMap" {
+		t.Errorf("Expected the object in the event: %s", buf.String())
+	}
+}
@@ -12,30 +12,65 @@ import (
 	"kubehcl.sh/kubehcl/internal/dag"
 	"kubehcl.sh/kubehcl/internal/decode"
 	"kubehcl.sh/kubehcl/internal/view"
+	"kubehcl.sh/kubehcl/kube/kubeclient"
 	"kubehcl.sh/kubehcl/settings"
 )
 
-// Template expects 1 argument
-// 1. Folder name which folder to decode
-// Template will render the configuration and print it as json/yaml format after inserting the values
-func Template(args []string, kind string, viewArguments *view.ViewArgs, cmdSettings *settings.CmdSettings) {
+// Parses arguments for template command, the release name before the folder is optional
+func parseTemplateArgs(args []string) (string, string, hcl.Diagnostics) {
+	if len(args) == 2 {
+		return args[0], args[1], hcl.Diagnostics{}
+	}
 	folderName, diags := parseFolderArgs(args)
+	return "", folderName, diags
+}
+
+// Template expects 1 or 2 arguments
+// 1. Release name which is exposed as release.name and annotated on the resources, optional
+// 2. Folder name which folder to decode
+// Template will render the configuration and print it as json/yaml format after inserting the values
+// kube_data blocks are read from the data file of the settings instead of the cluster
+// cluster.version and cluster.api_versions are taken from --kube-version and --api-versions instead of the cluster
+// Sensitive values are redacted unless --show-sensitive is set
+func Template(args []string, kind string, conf *settings.EnvSettings, viewArguments *view.ViewArgs, cmdSettings *settings.CmdSettings, templateSettings *settings.TemplateSettings) {
+	name, folderName, diags := parseTemplateArgs(args)
 	if diags.HasErrors() {
 		v.DiagPrinter(diags, viewArguments)
 		return
 	}
 
 	varF, vars, diags := parseCmdSettings(cmdSettings)
+	targets, targetDiags := configs.ParseTargets(cmdSettings.Targets)
+	diags = append(diags, targetDiags...)
+	var reader configs.DataReader
+	if templateSettings.DataFile != "" {
+		var readerDiags hcl.Diagnostics
+		reader, readerDiags = configs.NewStubDataReader(templateSettings.DataFile)
+		diags = append(diags, readerDiags...)
+	}
+	release, releaseDiags := kubeclient.TemplateRelease(name, conf.Namespace(), templateSettings.KubeVersion, templateSettings.APIVersions)
+	diags = append(diags, releaseDiags...)
 	if diags.HasErrors() {
 		v.DiagPrinter(diags, viewArguments)
 		return
 	}
 
-	d, diags := configs.DecodeFolderAndModules("", folderName, "root", varF, vars, 0)
+	d, decodeDiags := configs.DecodeFolder(&configs.DecodeOptions{
+		Release:    release,
+		FolderName: folderName,
+		VarsFile:   varF,
+		Vals:       vars,
+		DataReader: reader,
+	})
+	diags = append(diags, decodeDiags...)
 	g := &configs.Graph{
-		DecodedModule: d,
+		DecodedModule:       d,
+		DisableKindOrdering: cmdSettings.DisableKindOrdering,
 	}
 	diags = append(diags, g.Init()...)
+	if !diags.HasErrors() {
+		diags = append(diags, g.Target(targets)...)
+	}
 
 	if diags.HasErrors() {
 		v.DiagPrinter(diags, viewArguments)
@@ -47,6 +82,9 @@ func Template(args []string, kind string, viewArguments *view.ViewArgs, cmdSetti
 		switch resource := v.(type) {
 		case *decode.DecodedResource:
 			for key, value := range resource.Config {
+				if !viewArguments.ShowSensitive {
+					value = decode.Redact(value, resource.Sensitive[key])
+				}
 				var resourceOutput []byte
 				var err error
 
@@ -68,8 +106,11 @@ func Template(args []string, kind string, viewArguments *view.ViewArgs, cmdSetti
 						Subject:  &resource.DeclRange,
 					})
 				} else {
-					fmt.Printf("# Resource: %s\n\n", key)
-					fmt.Printf("%s---\n", string(resourceOutput))
+					fmt.Printf("# Resource: %s\n", key)
+					if resource.Cluster != "" {
+						fmt.Printf("# Cluster: %s\n", resource.Cluster)
+					}
+					fmt.Printf("\n%s---\n", string(resourceOutput))
 				}
 			}
 		}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"slices"
@@ -41,7 +42,7 @@ func parseUninstallArgs(args []string) (string, string, hcl.Diagnostics) {
 // 1. Release name, name of the release to be saved.
 // The rest is environment variables and flags of the settings for example namespace otherwise it will use the default settings
 // Uninstall will uninstall all resources registered to the given namespace and release name
-func Uninstall(args []string, conf *settings.EnvSettings, viewArguments *view.ViewArgs, cmdSettings *settings.CmdSettings) {
+func Uninstall(ctx context.Context, args []string, conf *settings.EnvSettings, viewArguments *view.ViewArgs, cmdSettings *settings.CmdSettings, uninstallSettings *settings.UninstallSettings) {
 	name, folderName, diags := parseUninstallArgs(args)
 	if diags.HasErrors() {
 		v.DiagPrinter(diags, viewArguments)
@@ -49,16 +50,48 @@ func Uninstall(args []string, conf *settings.EnvSettings, viewArguments *view.Vi
 	}
 
 	varsF, vals, diags := parseCmdSettings(cmdSettings)
-	d, decodeDiags := configs.DecodeFolderAndModules(name, folderName, "root", varsF, vals, 0)
+	targets, targetDiags := configs.ParseTargets(cmdSettings.Targets)
+	diags = append(diags, targetDiags...)
+	propagation, cascadeDiags := kubeclient.ParseCascade(uninstallSettings.Cascade)
+	diags = append(diags, cascadeDiags...)
+	if diags.HasErrors() {
+		v.DiagPrinter(diags, viewArguments)
+		return
+	}
+
+	release, releaseDiags := kubeclient.ReadRelease(ctx, name, conf)
+	diags = append(diags, releaseDiags...)
+	if diags.HasErrors() {
+		v.DiagPrinter(diags, viewArguments)
+		return
+	}
+
+	d, decodeDiags := configs.DecodeFolder(&configs.DecodeOptions{
+		Release:       release,
+		FolderName:    folderName,
+		VarsFile:      varsF,
+		Vals:          vals,
+		DataReader:    kubeclient.NewDataReader(ctx, conf),
+		ClusterReader: kubeclient.NewClusterReader(conf),
+	})
 	diags = append(diags, decodeDiags...)
 	if diags.HasErrors() {
 		v.DiagPrinter(diags, viewArguments)
 		os.Exit(1)
 	}
 
-	cfg, cfgDiags := kubeclient.New(name, conf, d.BackendStorage.Kind)
+	clusters, cfgDiags := kubeclient.NewClusters(ctx, name, conf, d.BackendStorage.Kind, d.Clusters)
 	diags = append(diags, cfgDiags...)
 
+	if diags.HasErrors() {
+		v.DiagPrinter(diags, viewArguments)
+		return
+	}
+	for _, cfg := range clusters.All() {
+		cfg.DeletionPropagation = propagation
+		diags = append(diags, cfg.ConfigureRetry(uninstallSettings.RetrySettings)...)
+	}
+	cfg := clusters.Default()
 	if diags.HasErrors() {
 		v.DiagPrinter(diags, viewArguments)
 		return
@@ -91,7 +124,9 @@ func Uninstall(args []string, conf *settings.EnvSettings, viewArguments *view.Vi
 			Summary:  "Storage kind is stateless no resource will be deleted",
 			Subject:  &d.BackendStorage.DeclRange,
 		})
-		diags = append(diags, cfg.Storage.DeleteState()...)
+		for _, cfg := range clusters.All() {
+			diags = append(diags, cfg.Storage.DeleteState()...)
+		}
 		if diags.HasErrors() {
 			v.DiagPrinter(diags, viewArguments)
 			os.Exit(1)
@@ -100,8 +135,32 @@ func Uninstall(args []string, conf *settings.EnvSettings, viewArguments *view.Vi
 		os.Exit(0)
 	}
 
-	_, deleteDiags := cfg.DeleteAllResources()
-	diags = append(diags, deleteDiags...)
+	var targeted func(key string) bool
+	if len(targets) > 0 {
+		targeted = targets.Match
+	}
+	diags = append(diags, clusters.Lock()...)
+	if !diags.HasErrors() {
+		// Namespaces are read before the state is deleted with the resources
+		namespaces := make(map[*kubeclient.Config][]string)
+		if targeted == nil && !uninstallSettings.KeepNamespace {
+			for _, cfg := range clusters.All() {
+				owned, nsDiags := cfg.Storage.GetNamespaces()
+				diags = append(diags, nsDiags...)
+				namespaces[cfg] = owned
+			}
+		}
+		diags = append(diags, clusters.DeleteAllResources(targeted)...)
+		diags = append(diags, clusters.Unlock()...)
+		if !diags.HasErrors() {
+			for cfg, owned := range namespaces {
+				diags = append(diags, cfg.DeleteNamespaces(owned)...)
+			}
+		}
+	}
 	v.DiagPrinter(diags, viewArguments)
+	if ctx.Err() != nil {
+		exitCancelled(ctx)
+	}
 
 }
@@ -0,0 +1,214 @@
+package configs
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"kubehcl.sh/kubehcl/internal/decode"
+)
+
+// Cluster is an alias of a kubernetes cluster resources and modules can be deployed to
+type Cluster struct {
+	Name       string
+	Context    hcl.Expression
+	Kubeconfig hcl.Expression
+	Namespace  hcl.Expression
+	DeclRange  hcl.Range
+}
+
+type Clusters []*Cluster
+
+// ClusterReader reads the capabilities of the cluster aliases modules are deployed to
+type ClusterReader interface {
+	Capabilities(cluster *decode.DecodedCluster) (*decode.Capabilities, hcl.Diagnostics)
+}
+
+// clusterCapabilities reads the capabilities of each cluster alias once for the modules deployed to it
+type clusterCapabilities struct {
+	reader   ClusterReader
+	clusters decode.DecodedClusterMap
+	read     map[string]*decode.Capabilities
+}
+
+// Returns the capabilities of the cluster alias, nil for the default cluster
+// Without a reader every alias has the capabilities of the default cluster
+// Aliases which are not declared are reported by verifyClusterReferences
+func (c *clusterCapabilities) get(alias string) (*decode.Capabilities, hcl.Diagnostics) {
+	if c == nil || c.reader == nil || alias == "" {
+		return nil, nil
+	}
+	if capabilities, exists := c.read[alias]; exists {
+		return capabilities, nil
+	}
+	cluster, exists := c.clusters[alias]
+	if !exists {
+		return nil, nil
+	}
+	capabilities, diags := c.reader.Capabilities(cluster)
+	c.read[alias] = capabilities
+	return capabilities, diags
+}
+
+var inputClusterBlockSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{
+			Name: "context",
+		},
+		{
+			Name: "kubeconfig",
+		},
+		{
+			Name: "namespace",
+		},
+	},
+}
+
+// Decodes an optional string attribute of the cluster, an empty string is returned when it is not set
+func decodeClusterString(expr hcl.Expression, ctx *hcl.EvalContext, name string) (string, hcl.Diagnostics) {
+	if expr == nil {
+		return "", nil
+	}
+	value, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return "", diags
+	}
+	value, err := convert.Convert(value, cty.String)
+	value, _ = value.Unmark()
+	if err != nil || !value.IsKnown() {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  fmt.Sprintf("Invalid cluster %s", name),
+			Detail:   fmt.Sprintf("The %s argument of a cluster must be a string", name),
+			Subject:  expr.Range().Ptr(),
+		})
+		return "", diags
+	}
+	if value.IsNull() {
+		return "", diags
+	}
+	return value.AsString(), diags
+}
+
+// Decode the cluster into a decoded cluster
+func (c *Cluster) decode(ctx *hcl.EvalContext) (*decode.DecodedCluster, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	dC := &decode.DecodedCluster{
+		Name:      c.Name,
+		DeclRange: c.DeclRange,
+	}
+	var valDiags hcl.Diagnostics
+	dC.Context, valDiags = decodeClusterString(c.Context, ctx, "context")
+	diags = append(diags, valDiags...)
+	dC.Kubeconfig, valDiags = decodeClusterString(c.Kubeconfig, ctx, "kubeconfig")
+	diags = append(diags, valDiags...)
+	dC.Namespace, valDiags = decodeClusterString(c.Namespace, ctx, "namespace")
+	diags = append(diags, valDiags...)
+	return dC, diags
+}
+
+// Decode multiple clusters
+func (c Clusters) Decode(ctx *hcl.EvalContext) (decode.DecodedClusterMap, hcl.Diagnostics) {
+	dClusters := make(decode.DecodedClusterMap)
+	var diags hcl.Diagnostics
+	for _, cluster := range c {
+		dC, clusterDiags := cluster.decode(ctx)
+		diags = append(diags, clusterDiags...)
+		if _, exists := dClusters[dC.Name]; exists {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Clusters must have different names",
+				Detail:   fmt.Sprintf("Two clusters have the same name: %s", dC.Name),
+				Subject:  &dC.DeclRange,
+			})
+		}
+		dClusters[dC.Name] = dC
+	}
+	return dClusters, diags
+}
+
+// Decode cluster block
+// Each block may contain the context, kubeconfig and namespace used to connect to the cluster
+func decodeClusterBlock(block *hcl.Block) (*Cluster, hcl.Diagnostics) {
+	cluster := &Cluster{
+		Name:      block.Labels[0],
+		DeclRange: block.DefRange,
+	}
+	content, diags := block.Body.Content(inputClusterBlockSchema)
+	if attr, exists := content.Attributes["context"]; exists {
+		cluster.Context = attr.Expr
+	}
+	if attr, exists := content.Attributes["kubeconfig"]; exists {
+		cluster.Kubeconfig = attr.Expr
+	}
+	if attr, exists := content.Attributes["namespace"]; exists {
+		cluster.Namespace = attr.Expr
+	}
+	return cluster, diags
+}
+
+// Decode multiple cluster blocks
+func DecodeClusterBlocks(blocks hcl.Blocks) (Clusters, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	var clusters Clusters
+	for _, block := range blocks {
+		cluster, clusterDiags := decodeClusterBlock(block)
+		diags = append(diags, clusterDiags...)
+		clusters = append(clusters, cluster)
+	}
+	return clusters, diags
+}
+
+// Decode the cluster meta-argument of a resource or a module
+// The argument must reference a cluster alias such as cluster.prod
+func decodeClusterAttr(attr *hcl.Attribute) (string, hcl.Diagnostics) {
+	traversal, diags := hcl.AbsTraversalForExpr(attr.Expr)
+	if diags.HasErrors() {
+		return "", diags
+	}
+	if len(traversal) == 2 && traversal.RootName() == "cluster" {
+		if alias, ok := traversal[1].(hcl.TraverseAttr); ok {
+			return alias.Name, diags
+		}
+	}
+	diags = append(diags, &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Invalid cluster reference",
+		Detail:   "The cluster argument must reference a cluster alias such as cluster.<name>",
+		Subject:  attr.Expr.Range().Ptr(),
+	})
+	return "", diags
+}
+
+// Verifies that each resource and module call of the module references a declared cluster
+func verifyClusterReferences(dm *decode.DecodedModule, clusters decode.DecodedClusterMap) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, resource := range dm.Resources {
+		diags = append(diags, verifyClusterReference(&resource.DecodedDeployable, clusters)...)
+	}
+	for _, call := range dm.ModuleCalls {
+		diags = append(diags, verifyClusterReference(&call.DecodedDeployable, clusters)...)
+	}
+	for _, module := range dm.Modules {
+		diags = append(diags, verifyClusterReferences(module, clusters)...)
+	}
+	return diags
+}
+
+func verifyClusterReference(d *decode.DecodedDeployable, clusters decode.DecodedClusterMap) hcl.Diagnostics {
+	if d.Cluster == "" {
+		return nil
+	}
+	if _, exists := clusters[d.Cluster]; exists {
+		return nil
+	}
+	return hcl.Diagnostics{
+		&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Reference to undeclared cluster",
+			Detail:   fmt.Sprintf("%s references cluster %s which is not declared, add a cluster \"%s\" block to the root module", d.Name, d.Cluster, d.Cluster),
+			Subject:  &d.DeclRange,
+		},
+	}
+}
@@ -0,0 +1,122 @@
+package configs
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/spf13/afero"
+	"kubehcl.sh/kubehcl/internal/logging"
+)
+
+func Test_Cluster(t *testing.T) {
+	tests := []struct {
+		folder     string
+		files      map[string]string
+		want       map[string]string
+		wantErrors bool
+	}{
+		{
+			folder: "clusters",
+			files: map[string]string{
+				"clusters/main.hcl": `cluster "prod" {
+  context   = "prod-context"
+  namespace = "apps"
+}
+kube_resource "foo" {
+  cluster = cluster.prod
+  kind    = "ConfigMap"
+}
+kube_resource "bar" {
+  kind = "ConfigMap"
+}
+module "child" {
+  source  = "./child"
+  cluster = cluster.prod
+}`,
+				"clusters/child/main.hcl": `kube_resource "baz" {
+  kind = "ConfigMap"
+}`,
+			},
+			want: map[string]string{"foo": "prod", "bar": "", "child.baz": "prod"},
+		},
+		{
+			folder: "undeclared",
+			files: map[string]string{
+				"undeclared/main.hcl": `kube_resource "foo" {
+  cluster = cluster.missing
+  kind    = "ConfigMap"
+}`,
+			},
+			wantErrors: true,
+		},
+		{
+			folder: "string_reference",
+			files: map[string]string{
+				"string_reference/main.hcl": `cluster "prod" {}
+kube_resource "foo" {
+  cluster = "prod"
+  kind    = "ConfigMap"
+}`,
+			},
+			wantErrors: true,
+		},
+		{
+			folder: "duplicate",
+			files: map[string]string{
+				"duplicate/main.hcl": `cluster "prod" {}
+cluster "prod" {}`,
+			},
+			wantErrors: true,
+		},
+		{
+			folder: "module_cluster",
+			files: map[string]string{
+				"module_cluster/main.hcl": `module "nested" {
+  source = "./nested"
+}`,
+				"module_cluster/nested/main.hcl": `cluster "prod" {}`,
+			},
+			wantErrors: true,
+		},
+	}
+
+	logging.SetLogger(false)
+	for _, test := range tests {
+		appFs := afero.NewMemMapFs()
+		for name, src := range test.files {
+			if err := afero.WriteFile(appFs, name, []byte(src), 0644); err != nil {
+				t.Fatalf("Couldn't write test file: %s", err)
+			}
+		}
+		mod, diags := decodeFolder(test.folder, appFs)
+		dm, decodeDiags := mod.decode(&DecodeOptions{}, 0, test.folder, "", "", nil, &hcl.EvalContext{}, appFs)
+		diags = append(diags, decodeDiags...)
+		if diags.HasErrors() && !test.wantErrors {
+			t.Errorf("Don't want errors but received: %s", diags.Errs())
+		} else if !diags.HasErrors() && test.wantErrors {
+			t.Errorf("Want errors but did not receive any")
+		} else if !test.wantErrors {
+			got := make(map[string]string)
+			for name, resource := range dm.Resources {
+				got[name] = resource.Cluster
+			}
+			for moduleName, module := range dm.Modules {
+				for name, resource := range module.Resources {
+					got[moduleName+"."+name] = resource.Cluster
+				}
+			}
+			if len(got) != len(test.want) {
+				t.Errorf("Expected %d resources got %d", len(test.want), len(got))
+			}
+			for name, cluster := range test.want {
+				if got[name] != cluster {
+					t.Errorf("Expected resource %s in cluster %q got %q", name, cluster, got[name])
+				}
+			}
+			prod, exists := dm.Clusters["prod"]
+			if !exists || prod.Context != "prod-context" || prod.Namespace != "apps" || prod.Kubeconfig != "" {
+				t.Errorf("Cluster prod was not decoded correctly: %+v", prod)
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package configs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"kubehcl.sh/kubehcl/internal/decode"
+)
+
+const CheckType = "check"
+
+var conditionBlockSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "condition", Required: true},
+		{Name: "error_message", Required: true},
+	},
+}
+
+// Decode a precondition, postcondition or assert block
+func decodeConditionBlock(block *hcl.Block) (*decode.Condition, hcl.Diagnostics) {
+	content, diags := block.Body.Content(conditionBlockSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return &decode.Condition{
+		Condition:    content.Attributes["condition"].Expr,
+		ErrorMessage: content.Attributes["error_message"].Expr,
+		DeclRange:    block.DefRange,
+	}, diags
+}
+
+// Returns an error for each reference to self, self is only known once the resource was applied
+func selfReferences(condition *decode.Condition, blockType string) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, expr := range []hcl.Expression{condition.Condition, condition.ErrorMessage} {
+		for _, traversal := range expr.Variables() {
+			if traversal.RootName() == "self" {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid reference to self",
+					Detail:   fmt.Sprintf("self can't be referenced by a %s, only postconditions can reference self", blockType),
+					Subject:  traversal.SourceRange().Ptr(),
+				})
+			}
+		}
+	}
+	return diags
+}
+
+// Decode the precondition and postcondition blocks of a lifecycle block into the deployable
+func decodeLifecycleConditions(content *hcl.BodyContent, deployable *decode.Deployable) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, block := range content.Blocks {
+		condition, conditionDiags := decodeConditionBlock(block)
+		diags = append(diags, conditionDiags...)
+		if condition == nil {
+			continue
+		}
+		switch block.Type {
+		case "precondition":
+			diags = append(diags, selfReferences(condition, block.Type)...)
+			deployable.Preconditions = append(deployable.Preconditions, condition)
+		case "postcondition":
+			deployable.Postconditions = append(deployable.Postconditions, condition)
+		}
+	}
+	return diags
+}
+
+// Checks the postconditions of the module call once the module was decoded, self refers to the outputs of the module
+// Modules created with count or for_each refer to a list or a map of the outputs of each instance
+func (m *Module) checkPostconditions(dm *decode.DecodedModule, ctx *hcl.EvalContext) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	if len(m.Postconditions) == 0 {
+		return diags
+	}
+	selfCtx := ctx.NewChild()
+	selfCtx.Variables = map[string]cty.Value{"self": dm.Value}
+	for _, postcondition := range m.Postconditions {
+		diags = append(diags, postcondition.Check(selfCtx, hcl.DiagError, "Module postcondition failed", "postcondition of "+strings.TrimSuffix(m.path, "."))...)
+	}
+	return diags
+}
+
+// Check contains assertions about the configuration which are reported as warnings when they fail
+type Check struct {
+	Name      string
+	Asserts   []*decode.Condition
+	DeclRange hcl.Range
+}
+
+type CheckList []*Check
+
+var inputCheckBlockSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "assert"},
+	},
+}
+
+func decodeCheckBlock(block *hcl.Block) (*Check, hcl.Diagnostics) {
+	check := &Check{
+		Name:      block.Labels[0],
+		DeclRange: block.DefRange,
+	}
+	content, diags := block.Body.Content(inputCheckBlockSchema)
+	for _, assertBlock := range content.Blocks.OfType("assert") {
+		assert, assertDiags := decodeConditionBlock(assertBlock)
+		diags = append(diags, assertDiags...)
+		if assert != nil {
+			diags = append(diags, selfReferences(assert, assertBlock.Type)...)
+			check.Asserts = append(check.Asserts, assert)
+		}
+	}
+	if len(check.Asserts) == 0 && !diags.HasErrors() {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Check block without assertions",
+			Detail:   fmt.Sprintf("Check %s must contain at least one assert block", check.Name),
+			Subject:  &check.DeclRange,
+		})
+	}
+	return check, diags
+}
+
+// Decode multiple check blocks
+func DecodeCheckBlocks(blocks hcl.Blocks) (CheckList, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	var checks CheckList
+	for _, block := range blocks {
+		check, checkDiags := decodeCheckBlock(block)
+		diags = append(diags, checkDiags...)
+		checks = append(checks, check)
+	}
+	return checks, diags
+}
+
+// Evaluates the assertions of the check blocks, failed assertions are warnings and don't stop the release
+func (checks CheckList) Check(ctx *hcl.EvalContext, path string) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	names := make(map[string]*Check)
+	for _, check := range checks {
+		if existing, exists := names[check.Name]; exists {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate check block",
+				Detail:   fmt.Sprintf("Check %s is declared more than once", check.Name),
+				Subject:  &check.DeclRange,
+				Context:  &existing.DeclRange,
+			})
+			continue
+		}
+		names[check.Name] = check
+		for _, assert := range check.Asserts {
+			diags = append(diags, assert.Check(ctx, hcl.DiagWarning, "Check block assertion failed", fmt.Sprintf("assertion of %s%s.%s", path, CheckType, check.Name))...)
+		}
+	}
+	return diags
+}
@@ -0,0 +1,231 @@
+package configs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/spf13/afero"
+	"github.com/zclconf/go-cty/cty"
+	"kubehcl.sh/kubehcl/internal/logging"
+)
+
+func Test_Conditions(t *testing.T) {
+	service := `kube_resource "service" {
+  count      = var.replicas
+  apiVersion = "v1"
+  kind       = "Service"
+  metadata = {
+    name = "web-${count.index}"
+  }
+  lifecycle {
+    precondition {
+      condition     = count.index <= 2
+      error_message = "Only 2 services are allowed got ${count.index}"
+    }
+    postcondition {
+      condition     = length(self.status.loadBalancer.ingress) > 0 && self.metadata.name == "web-${count.index}"
+      error_message = "Service web-${count.index} has no ingress"
+    }
+  }
+}
+`
+	tests := []struct {
+		folder       string
+		files        map[string]string
+		wantErrors   []string
+		wantWarnings []string
+	}{
+		{
+			folder: "conditions",
+			files: map[string]string{
+				"conditions/main.hcl": `variable "replicas" {
+  default = 2
+}
+` + service + `module "child" {
+  source = "./child"
+  stage  = "dev"
+  lifecycle {
+    precondition {
+      condition     = var.replicas > 0
+      error_message = "At least one replica is required"
+    }
+    postcondition {
+      condition     = self.stage == "dev"
+      error_message = "The child module must be deployed to dev"
+    }
+  }
+}
+check "replicas" {
+  assert {
+    condition     = var.replicas > 1
+    error_message = "A single replica is not highly available"
+  }
+}`,
+				"conditions/child/main.hcl": `variable "stage" {}
+output "stage" {
+  value = var.stage
+}`,
+			},
+		},
+		{
+			folder: "failed_precondition",
+			files: map[string]string{
+				"failed_precondition/main.hcl": `variable "replicas" {
+  default = 3
+}
+` + service,
+			},
+			wantErrors: []string{"Only 2 services are allowed got 3"},
+		},
+		{
+			folder: "failed_module_conditions",
+			files: map[string]string{
+				"failed_module_conditions/main.hcl": `module "child" {
+  source = "./child"
+  stage  = "prod"
+  lifecycle {
+    precondition {
+      condition     = false
+      error_message = "The precondition of the module failed"
+    }
+    postcondition {
+      condition     = self.stage == "dev"
+      error_message = "The child module must be deployed to dev"
+    }
+  }
+}`,
+				"failed_module_conditions/child/main.hcl": `variable "stage" {}
+output "stage" {
+  value = var.stage
+}`,
+			},
+			wantErrors: []string{"The precondition of the module failed", "The child module must be deployed to dev"},
+		},
+		{
+			folder: "self_precondition",
+			files: map[string]string{
+				"self_precondition/main.hcl": `kube_resource "config" {
+  apiVersion = "v1"
+  kind       = "ConfigMap"
+  metadata = {
+    name = "config"
+  }
+  lifecycle {
+    precondition {
+      condition     = self.metadata.name == "config"
+      error_message = "Invalid name"
+    }
+  }
+}`,
+			},
+			wantErrors: []string{"only postconditions can reference self"},
+		},
+		{
+			folder: "failed_check",
+			files: map[string]string{
+				"failed_check/main.hcl": `variable "replicas" {
+  default = 1
+}
+check "replicas" {
+  assert {
+    condition     = var.replicas > 1
+    error_message = "A single replica is not highly available"
+  }
+}`,
+			},
+			wantWarnings: []string{"A single replica is not highly available"},
+		},
+		{
+			// Checks can reference the config of the resources of the module
+			folder: "resource_check",
+			files: map[string]string{
+				"resource_check/main.hcl": `variable "replicas" {
+  default = 2
+}
+` + service + `check "service" {
+  assert {
+    condition     = kube_resource.service[2].metadata.name == "web-1"
+    error_message = "Service ${kube_resource.service[2].metadata.name} isn't the first service"
+  }
+}`,
+			},
+			wantWarnings: []string{"Service web-2 isn't the first service"},
+		},
+		{
+			folder: "check_without_assert",
+			files: map[string]string{
+				"check_without_assert/main.hcl": `check "empty" {}`,
+			},
+			wantErrors: []string{"must contain at least one assert block"},
+		},
+	}
+
+	contains := func(diags hcl.Diagnostics, severity hcl.DiagnosticSeverity, want string) bool {
+		for _, diag := range diags {
+			if diag.Severity == severity && strings.Contains(diag.Detail, want) {
+				return true
+			}
+		}
+		return false
+	}
+
+	logging.SetLogger(false)
+	for _, test := range tests {
+		appFs := afero.NewMemMapFs()
+		for name, src := range test.files {
+			if err := afero.WriteFile(appFs, name, []byte(src), 0644); err != nil {
+				t.Fatalf("Couldn't write test file: %s", err)
+			}
+		}
+		mod, diags := decodeFolder(test.folder, appFs)
+		dm, decodeDiags := mod.decode(&DecodeOptions{}, 0, test.folder, "", "", nil, &hcl.EvalContext{}, appFs)
+		diags = append(diags, decodeDiags...)
+		if len(test.wantErrors) == 0 && diags.HasErrors() {
+			t.Errorf("Don't want errors but received: %s", diags.Errs())
+			continue
+		}
+		for _, want := range test.wantErrors {
+			if !contains(diags, hcl.DiagError, want) {
+				t.Errorf("Expected an error containing %q got: %s", want, diags.Errs())
+			}
+		}
+		for _, want := range test.wantWarnings {
+			if !contains(diags, hcl.DiagWarning, want) {
+				t.Errorf("Expected a warning containing %q got: %#v", want, diags)
+			}
+		}
+		if len(test.wantErrors) > 0 || len(test.wantWarnings) > 0 {
+			continue
+		}
+		if len(diags) > 0 {
+			t.Errorf("Don't want diagnostics but received: %#v", diags)
+		}
+
+		// Postconditions are checked with the live object of each instance
+		service := dm.Resources["service"]
+		live := func(name string, ingress ...cty.Value) cty.Value {
+			ingressVal := cty.EmptyTupleVal
+			if len(ingress) > 0 {
+				ingressVal = cty.TupleVal(ingress)
+			}
+			return cty.ObjectVal(map[string]cty.Value{
+				"metadata": cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal(name)}),
+				"status": cty.ObjectVal(map[string]cty.Value{
+					"loadBalancer": cty.ObjectVal(map[string]cty.Value{"ingress": ingressVal}),
+				}),
+			})
+		}
+		ingress := cty.ObjectVal(map[string]cty.Value{"ip": cty.StringVal("10.0.0.1")})
+		if postDiags := service.CheckPostconditions("kube_resource.service[1]", live("web-1", ingress)); postDiags.HasErrors() {
+			t.Errorf("Don't want errors but received: %s", postDiags.Errs())
+		}
+		postDiags := service.CheckPostconditions("kube_resource.service[2]", live("web-2"))
+		if !contains(postDiags, hcl.DiagError, "Service web-2 has no ingress") {
+			t.Errorf("Expected the postcondition of kube_resource.service[2] to fail got: %s", postDiags.Errs())
+		}
+		if postDiags := service.CheckPostconditions("kube_resource.service[2]", live("web-1", ingress)); !postDiags.HasErrors() {
+			t.Errorf("Expected the postcondition to use count.index of kube_resource.service[2]")
+		}
+	}
+}
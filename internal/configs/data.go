@@ -0,0 +1,292 @@
+package configs
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"kubehcl.sh/kubehcl/internal/decode"
+)
+
+const DataType = "kube_data"
+
+// DataReader reads the objects of kube_data blocks
+// A single object is returned as an object and the objects matching a label selector as a list
+type DataReader interface {
+	Read(data *decode.DecodedData) (cty.Value, hcl.Diagnostics)
+}
+
+// Data reads existing objects from the cluster
+type Data struct {
+	Name          string
+	APIVersion    hcl.Expression
+	Kind          hcl.Expression
+	Metadata      hcl.Expression
+	LabelSelector hcl.Expression
+	DeclRange     hcl.Range
+}
+
+type DataList []*Data
+
+var inputDataBlockSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "apiVersion", Required: true},
+		{Name: "kind", Required: true},
+		{Name: "metadata", Required: false},
+		{Name: "label_selector", Required: false},
+	},
+}
+
+// Decode an expression which must be a string
+func decodeStringExpr(ctx *hcl.EvalContext, expr hcl.Expression, name string) (string, hcl.Diagnostics) {
+	val, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return "", diags
+	}
+	val, err := convert.Convert(val, cty.String)
+	val, _ = val.Unmark()
+	if err != nil || val.IsNull() || !val.IsKnown() {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  fmt.Sprintf("Invalid %s value", name),
+			Detail:   fmt.Sprintf("The %s argument must be a known string", name),
+			Subject:  expr.Range().Ptr(),
+		})
+		return "", diags
+	}
+	return val.AsString(), diags
+}
+
+// Decode the metadata of the block which can contain name and namespace
+func (d *Data) decodeMetadata(ctx *hcl.EvalContext, dD *decode.DecodedData) hcl.Diagnostics {
+	if d.Metadata == nil {
+		return hcl.Diagnostics{}
+	}
+	metadata, diags := d.Metadata.Value(ctx)
+	if diags.HasErrors() {
+		return diags
+	}
+	metadata, err := convert.Convert(metadata, cty.Map(cty.String))
+	if err == nil {
+		metadata, _ = metadata.UnmarkDeep()
+	}
+	if err != nil || metadata.IsNull() || !metadata.IsWhollyKnown() {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid metadata value",
+			Detail:   "The metadata of kube_data can only contain the name and namespace strings",
+			Subject:  d.Metadata.Range().Ptr(),
+		})
+	}
+	for key, val := range metadata.AsValueMap() {
+		switch key {
+		case "name":
+			dD.ObjectName = val.AsString()
+		case "namespace":
+			dD.Namespace = val.AsString()
+		default:
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unsupported metadata attribute",
+				Detail:   fmt.Sprintf("The metadata of kube_data can only contain name and namespace got: %s", key),
+				Subject:  d.Metadata.Range().Ptr(),
+			})
+		}
+	}
+	return diags
+}
+
+// Decode the label selector of the block which lists the matching objects
+func (d *Data) decodeLabelSelector(ctx *hcl.EvalContext, dD *decode.DecodedData) hcl.Diagnostics {
+	selector, diags := d.LabelSelector.Value(ctx)
+	if diags.HasErrors() {
+		return diags
+	}
+	selector, err := convert.Convert(selector, cty.Map(cty.String))
+	if err == nil {
+		selector, _ = selector.UnmarkDeep()
+	}
+	if err != nil || selector.IsNull() || !selector.IsWhollyKnown() {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid label_selector value",
+			Detail:   "The label_selector of kube_data must be a map of strings",
+			Subject:  d.LabelSelector.Range().Ptr(),
+		})
+	}
+	dD.List = true
+	dD.LabelSelector = make(map[string]string)
+	for key, val := range selector.AsValueMap() {
+		dD.LabelSelector[key] = val.AsString()
+	}
+	return diags
+}
+
+// Decode the block and read the objects with the reader, the reader is nil when there is no cluster
+// The value is exposed as kube_data.<name>.object for a single object and kube_data.<name>.objects for a label selector
+func (d *Data) decode(ctx *hcl.EvalContext, path string, reader DataReader) (*decode.DecodedData, hcl.Diagnostics) {
+	dD := &decode.DecodedData{
+		Name:      d.Name,
+		Address:   path + DataType + "." + d.Name,
+		Value:     cty.DynamicVal,
+		DeclRange: d.DeclRange,
+	}
+	var diags hcl.Diagnostics
+	var strDiags hcl.Diagnostics
+	dD.APIVersion, strDiags = decodeStringExpr(ctx, d.APIVersion, "apiVersion")
+	diags = append(diags, strDiags...)
+	dD.Kind, strDiags = decodeStringExpr(ctx, d.Kind, "kind")
+	diags = append(diags, strDiags...)
+	diags = append(diags, d.decodeMetadata(ctx, dD)...)
+	if d.LabelSelector != nil {
+		diags = append(diags, d.decodeLabelSelector(ctx, dD)...)
+	} else if dD.ObjectName == "" && !diags.HasErrors() {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "kube_data requires a name or a label selector",
+			Detail:   fmt.Sprintf("Set metadata.name to read a single object or label_selector to list objects in %s", dD.Address),
+			Subject:  &dD.DeclRange,
+		})
+	}
+	if diags.HasErrors() {
+		return dD, diags
+	}
+
+	if reader == nil {
+		return dD, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "kube_data can't be read without a cluster",
+			Detail:   fmt.Sprintf("Objects of %s are read from the cluster, use --data-file to supply them in template", dD.Address),
+			Subject:  &dD.DeclRange,
+		})
+	}
+	value, readDiags := reader.Read(dD)
+	diags = append(diags, readDiags...)
+	if readDiags.HasErrors() {
+		return dD, diags
+	}
+	if dD.List {
+		dD.Value = cty.ObjectVal(map[string]cty.Value{"objects": value})
+	} else {
+		dD.Value = cty.ObjectVal(map[string]cty.Value{"object": value})
+	}
+	return dD, diags
+}
+
+// Decode multiple kube_data blocks, path is the address of the module such as module.foo.
+func (d DataList) Decode(ctx *hcl.EvalContext, path string, reader DataReader) (decode.DecodedDataMap, hcl.Diagnostics) {
+	dData := make(decode.DecodedDataMap)
+	var diags hcl.Diagnostics
+	for _, data := range d {
+		dD, dataDiags := data.decode(ctx, path, reader)
+		diags = append(diags, dataDiags...)
+		if _, exists := dData[dD.Name]; exists {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "kube_data blocks must have different names",
+				Detail:   fmt.Sprintf("Two kube_data blocks have the same name: %s", dD.Name),
+				Subject:  &dD.DeclRange,
+			})
+		}
+		dData[dD.Name] = dD
+	}
+	return dData, diags
+}
+
+// Returns the values of the kube_data blocks as an object
+func dataValue(data decode.DecodedDataMap) cty.Value {
+	values := make(map[string]cty.Value)
+	for name, dD := range data {
+		values[name] = dD.Value
+	}
+	return cty.ObjectVal(values)
+}
+
+// Decode kube_data block
+// Each block must contain apiVersion and kind and either metadata with a name or a label selector
+func decodeDataBlock(block *hcl.Block) (*Data, hcl.Diagnostics) {
+	data := &Data{
+		Name:      block.Labels[0],
+		DeclRange: block.DefRange,
+	}
+	content, diags := block.Body.Content(inputDataBlockSchema)
+	if attr, exists := content.Attributes["apiVersion"]; exists {
+		data.APIVersion = attr.Expr
+	}
+	if attr, exists := content.Attributes["kind"]; exists {
+		data.Kind = attr.Expr
+	}
+	if attr, exists := content.Attributes["metadata"]; exists {
+		data.Metadata = attr.Expr
+	}
+	if attr, exists := content.Attributes["label_selector"]; exists {
+		data.LabelSelector = attr.Expr
+	}
+	return data, diags
+}
+
+// Decode multiple kube_data blocks
+func DecodeDataBlocks(blocks hcl.Blocks) (DataList, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	var dataList DataList
+	for _, block := range blocks {
+		data, dataDiags := decodeDataBlock(block)
+		diags = append(diags, dataDiags...)
+		if data.APIVersion != nil && data.Kind != nil {
+			dataList = append(dataList, data)
+		}
+	}
+	return dataList, diags
+}
+
+// stubDataReader returns the values of a file instead of reading them from the cluster
+type stubDataReader map[string]cty.Value
+
+// Creates a data reader from a json file which maps the address of each kube_data block to its object or list of objects
+// For example {"kube_data.env": {"data": {"stage": "dev"}}, "module.foo.kube_data.pods": [...]}
+func NewStubDataReader(fileName string) (DataReader, hcl.Diagnostics) {
+	src, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, hcl.Diagnostics{
+			&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Couldn't read the data file",
+				Detail:   fmt.Sprintf("Data file %s couldn't be read: %s", fileName, err),
+			},
+		}
+	}
+	ty, err := ctyjson.ImpliedType(src)
+	var value cty.Value
+	if err == nil {
+		value, err = ctyjson.Unmarshal(src, ty)
+	}
+	if err != nil || !ty.IsObjectType() {
+		return nil, hcl.Diagnostics{
+			&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid data file",
+				Detail:   fmt.Sprintf("Data file %s must be a json object which maps the address of each kube_data block to its value", fileName),
+			},
+		}
+	}
+	return stubDataReader(value.AsValueMap()), hcl.Diagnostics{}
+}
+
+// Returns the stub value of the kube_data block
+func (s stubDataReader) Read(data *decode.DecodedData) (cty.Value, hcl.Diagnostics) {
+	value, exists := s[data.Address]
+	if !exists {
+		return cty.DynamicVal, hcl.Diagnostics{
+			&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Missing kube_data value",
+				Detail:   fmt.Sprintf("The data file doesn't contain a value for %s", data.Address),
+				Subject:  &data.DeclRange,
+			},
+		}
+	}
+	return value, hcl.Diagnostics{}
+}
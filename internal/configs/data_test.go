@@ -0,0 +1,226 @@
+package configs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/spf13/afero"
+	"github.com/zclconf/go-cty/cty"
+	"kubehcl.sh/kubehcl/internal/decode"
+	"kubehcl.sh/kubehcl/internal/logging"
+)
+
+// fakeDataReader returns an object with the name and namespace of the block or a list with one object per label
+type fakeDataReader struct {
+	reads []*decode.DecodedData
+}
+
+func (f *fakeDataReader) Read(data *decode.DecodedData) (cty.Value, hcl.Diagnostics) {
+	f.reads = append(f.reads, data)
+	if data.List {
+		var objects []cty.Value
+		for key, value := range data.LabelSelector {
+			objects = append(objects, cty.ObjectVal(map[string]cty.Value{"label": cty.StringVal(key + "=" + value)}))
+		}
+		return cty.TupleVal(objects), hcl.Diagnostics{}
+	}
+	return cty.ObjectVal(map[string]cty.Value{
+		"data": cty.ObjectVal(map[string]cty.Value{
+			"stage":     cty.StringVal("dev"),
+			"namespace": cty.StringVal(data.Namespace),
+		}),
+	}), hcl.Diagnostics{}
+}
+
+func Test_Data(t *testing.T) {
+	tests := []struct {
+		folder     string
+		files      map[string]string
+		reader     bool
+		want       map[string]cty.Value
+		addresses  []string
+		wantErrors bool
+	}{
+		{
+			folder: "data",
+			files: map[string]string{
+				"data/main.hcl": `variable "namespace" {
+  default = "settings"
+}
+kube_data "env" {
+  apiVersion = "v1"
+  kind       = "ConfigMap"
+  metadata = {
+    name      = "env"
+    namespace = var.namespace
+  }
+}
+kube_data "pods" {
+  apiVersion     = "v1"
+  kind           = "Pod"
+  label_selector = { app = "web" }
+}
+locals {
+  stage = kube_data.env.object.data.stage
+}
+module "child" {
+  source = "./child"
+  stage  = local.stage
+}
+kube_resource "config" {
+  apiVersion = "v1"
+  kind       = "ConfigMap"
+  metadata = {
+    name = "config"
+  }
+  data = {
+    stage     = local.stage
+    namespace = kube_data.env.object.data.namespace
+    pod       = kube_data.pods.objects[0].label
+    child     = module.child.stage
+  }
+}`,
+				"data/child/main.hcl": `variable "stage" {}
+kube_data "env" {
+  apiVersion = "v1"
+  kind       = "ConfigMap"
+  metadata = {
+    name = "env"
+  }
+}
+output "stage" {
+  value = "${var.stage}-${kube_data.env.object.data.stage}"
+}`,
+			},
+			reader: true,
+			want: map[string]cty.Value{
+				"stage":     cty.StringVal("dev"),
+				"namespace": cty.StringVal("settings"),
+				"pod":       cty.StringVal("app=web"),
+				"child":     cty.StringVal("dev-dev"),
+			},
+			addresses: []string{"kube_data.env", "kube_data.pods", "module.child.kube_data.env"},
+		},
+		{
+			folder: "data_without_reader",
+			files: map[string]string{
+				"data_without_reader/main.hcl": `kube_data "env" {
+  apiVersion = "v1"
+  kind       = "ConfigMap"
+  metadata = {
+    name = "env"
+  }
+}`,
+			},
+			wantErrors: true,
+		},
+		{
+			folder: "data_without_name",
+			files: map[string]string{
+				"data_without_name/main.hcl": `kube_data "env" {
+  apiVersion = "v1"
+  kind       = "ConfigMap"
+}`,
+			},
+			reader:     true,
+			wantErrors: true,
+		},
+		{
+			folder: "data_invalid_metadata",
+			files: map[string]string{
+				"data_invalid_metadata/main.hcl": `kube_data "env" {
+  apiVersion = "v1"
+  kind       = "ConfigMap"
+  metadata = {
+    name   = "env"
+    labels = "app"
+  }
+}`,
+			},
+			reader:     true,
+			wantErrors: true,
+		},
+	}
+
+	logging.SetLogger(false)
+	for _, test := range tests {
+		appFs := afero.NewMemMapFs()
+		for name, src := range test.files {
+			if err := afero.WriteFile(appFs, name, []byte(src), 0644); err != nil {
+				t.Fatalf("Couldn't write test file: %s", err)
+			}
+		}
+		reader := &fakeDataReader{}
+		opts := &DecodeOptions{}
+		if test.reader {
+			opts.DataReader = reader
+		}
+		mod, diags := decodeFolder(test.folder, appFs)
+		dm, decodeDiags := mod.decode(opts, 0, test.folder, "", "", nil, &hcl.EvalContext{}, appFs)
+		diags = append(diags, decodeDiags...)
+		if diags.HasErrors() && !test.wantErrors {
+			t.Errorf("Don't want errors but received: %s", diags.Errs())
+			continue
+		} else if !diags.HasErrors() && test.wantErrors {
+			t.Errorf("Want errors but did not receive any: %s", test.folder)
+			continue
+		} else if test.wantErrors {
+			continue
+		}
+
+		data := dm.Resources["config"].Config["kube_resource.config"].GetAttr("data")
+		for name, value := range test.want {
+			if !data.GetAttr(name).RawEquals(value) {
+				t.Errorf("Expected %s to be %#v got %#v", name, value, data.GetAttr(name))
+			}
+		}
+		var addresses []string
+		for _, read := range reader.reads {
+			addresses = append(addresses, read.Address)
+		}
+		if len(addresses) != len(test.addresses) {
+			t.Errorf("Expected reads of %v got %v", test.addresses, addresses)
+		}
+		for i := range addresses {
+			if i < len(test.addresses) && addresses[i] != test.addresses[i] {
+				t.Errorf("Expected reads of %v got %v", test.addresses, addresses)
+			}
+		}
+	}
+}
+
+func Test_StubDataReader(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "data.json")
+	src := `{"kube_data.env": {"data": {"stage": "dev"}}, "module.foo.kube_data.pods": [{"name": "a"}]}`
+	if err := os.WriteFile(fileName, []byte(src), 0644); err != nil {
+		t.Fatalf("Couldn't write test file: %s", err)
+	}
+	reader, diags := NewStubDataReader(fileName)
+	if diags.HasErrors() {
+		t.Fatalf("Don't want errors but received: %s", diags.Errs())
+	}
+
+	value, diags := reader.Read(&decode.DecodedData{Address: "kube_data.env"})
+	if diags.HasErrors() {
+		t.Errorf("Don't want errors but received: %s", diags.Errs())
+	} else if !value.GetAttr("data").GetAttr("stage").RawEquals(cty.StringVal("dev")) {
+		t.Errorf("Expected the stub of kube_data.env got %#v", value)
+	}
+
+	value, diags = reader.Read(&decode.DecodedData{Address: "module.foo.kube_data.pods", List: true})
+	if diags.HasErrors() {
+		t.Errorf("Don't want errors but received: %s", diags.Errs())
+	} else if value.LengthInt() != 1 {
+		t.Errorf("Expected a list with one object got %#v", value)
+	}
+
+	if _, diags = reader.Read(&decode.DecodedData{Address: "kube_data.missing"}); !diags.HasErrors() {
+		t.Errorf("Want errors for a missing stub but did not receive any")
+	}
+
+	if _, diags = NewStubDataReader(filepath.Join(t.TempDir(), "missing.json")); !diags.HasErrors() {
+		t.Errorf("Want errors for a missing file but did not receive any")
+	}
+}
@@ -21,6 +21,10 @@ import (
 type Graph struct {
 	dag.AcyclicGraph
 	DecodedModule *decode.DecodedModule
+	// DisableKindOrdering disables the implicit edges based on the kind of the resources
+	DisableKindOrdering bool
+	// Moved contains the moved blocks of every module, set by Init
+	Moved decode.DecodedMovedList
 }
 
 const rootNodeName = "root"
@@ -63,6 +67,21 @@ func getResourceNames(m *decode.DecodedModule) (decode.DecodedResourceMap, hcl.D
 // For example a resource named foo inside module called bar will be called module.bar.resource.foo
 func getResourceName(m *decode.DecodedModule, rMap decode.DecodedResourceMap, currentName string) (decode.DecodedResourceMap, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
+	// A module which references the outputs of another module depends on its resources
+	if len(m.References) > 0 {
+		m.Dependencies = append(m.Dependencies, decode.DependsOn{
+			Depth:    m.Depth - 1,
+			Trav:     m.References,
+			Implicit: true,
+		})
+	}
+	for _, module := range m.Modules {
+		for _, dependency := range m.Dependencies {
+			if dependency.Implicit {
+				module.Dependencies = append(module.Dependencies, dependency)
+			}
+		}
+	}
 	for _, r := range m.Resources {
 		r.Name = currentName + r.Addr().String()
 		r.Depth = m.Depth
@@ -86,6 +105,13 @@ func getResourceName(m *decode.DecodedModule, rMap decode.DecodedResourceMap, cu
 				Trav:  r.DependsOn,
 			})
 		}
+		if r.References != nil {
+			dependencies = append(dependencies, decode.DependsOn{
+				Depth:    r.Depth,
+				Trav:     r.References,
+				Implicit: true,
+			})
+		}
 		dependencies = append(dependencies, m.Dependencies...)
 		dependencies = append(dependencies, moduleDependencies...)
 		r.Dependencies = dependencies
@@ -94,6 +120,20 @@ func getResourceName(m *decode.DecodedModule, rMap decode.DecodedResourceMap, cu
 			newConfig[currentName+key] = value
 		}
 		r.Config = newConfig
+		if r.Sensitive != nil {
+			sensitive := make(map[string][]cty.Path)
+			for key, paths := range r.Sensitive {
+				sensitive[currentName+key] = paths
+			}
+			r.Sensitive = sensitive
+		}
+		if r.PostconditionContexts != nil {
+			contexts := make(map[string]*hcl.EvalContext)
+			for key, ctx := range r.PostconditionContexts {
+				contexts[currentName+key] = ctx
+			}
+			r.PostconditionContexts = contexts
+		}
 	}
 
 	// for _, module := range m.Modules {
@@ -150,7 +190,8 @@ func addEdges(g *Graph, r *decode.DecodedResource, resourceMap map[string]*decod
 			}
 		}
 
-		if !added {
+		// Referenced modules without resources don't add edges
+		if !added && !edge.Implicit {
 			diags = append(diags, &hcl.Diagnostic{
 				Severity: hcl.DiagError,
 				Summary:  "Dependency does not exist",
@@ -190,6 +231,9 @@ func (g *Graph) Init() hcl.Diagnostics {
 	for _, r := range rMap {
 		if len(r.Dependencies) > 0 {
 			rDiags := addEdges(g, r, resourceMap)
+			if len(rDiags) == 0 {
+				continue
+			}
 			added := true
 			for _, diag := range diags {
 				if rDiags[0].Subject.Start == diag.Subject.Start && rDiags[0].Subject.End == diag.Subject.End {
@@ -204,6 +248,10 @@ func (g *Graph) Init() hcl.Diagnostics {
 
 	}
 
+	if !g.DisableKindOrdering {
+		addKindEdges(g, resourceMap)
+	}
+
 	for _, cycle := range g.Cycles() {
 		diags = append(diags, &hcl.Diagnostic{
 			Severity: hcl.DiagError,
@@ -213,6 +261,8 @@ func (g *Graph) Init() hcl.Diagnostics {
 		})
 	}
 
+	diags = append(diags, g.initMoved(resourceMap)...)
+
 	addRootNodeToGraph(g)
 	if diags.HasErrors() {
 		return diags
@@ -227,10 +277,11 @@ func (g *Graph) Init() hcl.Diagnostics {
 }
 
 type rangeName struct {
-	Name  string
-	Type  string
-	Depth int
-	Range hcl.Range
+	Name     string
+	Type     string
+	Depth    int
+	Implicit bool
+	Range    hcl.Range
 }
 
 // Get the name of the resource based on the depends on attribute in resource and module blocks
@@ -287,10 +338,11 @@ func getName(resource *decode.DecodedResource) ([]rangeName, hcl.Diagnostics) {
 			}
 
 			rNames = append(rNames, rangeName{
-				Name:  resourceName,
-				Type:  resourceType,
-				Range: hcl.RangeBetween(traversal[0].SourceRange(), traversal[len(traversal)-1].SourceRange()),
-				Depth: dependsOn.Depth,
+				Name:     resourceName,
+				Type:     resourceType,
+				Range:    hcl.RangeBetween(traversal[0].SourceRange(), traversal[len(traversal)-1].SourceRange()),
+				Depth:    dependsOn.Depth,
+				Implicit: dependsOn.Implicit,
 			})
 		}
 	}
@@ -0,0 +1,213 @@
+package configs
+
+import (
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+	"kubehcl.sh/kubehcl/internal/dag"
+	"kubehcl.sh/kubehcl/internal/decode"
+)
+
+// Install order of kinds which other resources usually depend on
+// Namespaces come first so objects are created after the namespaces they live in
+// Custom resource definitions are ready only after they are established, so custom resources can be created after them
+var kindOrder = map[string]int{
+	"Namespace":                0,
+	"CustomResourceDefinition": 1,
+	"ServiceAccount":           2,
+	"Role":                     2,
+	"ClusterRole":              2,
+	"RoleBinding":              2,
+	"ClusterRoleBinding":       2,
+	"ConfigMap":                3,
+	"Secret":                   3,
+}
+
+// Order of every kind which is not in kindOrder such as workloads and custom resources
+const workloadOrder = 4
+
+// Returns the kind of the resource instance or an empty string if it is not known yet
+func instanceKind(value cty.Value) string {
+	if value.IsNull() || !value.IsKnown() || !value.Type().IsObjectType() || !value.Type().HasAttribute("kind") {
+		return ""
+	}
+	kind := value.GetAttr("kind")
+	if kind.IsNull() || !kind.IsKnown() || kind.Type() != cty.String {
+		return ""
+	}
+	return kind.AsString()
+}
+
+// Returns the value at the path of the object
+// Returns false if the value doesn't exist, the returned value is unknown when an object on the path is not known yet
+func lookupAttr(value cty.Value, path ...string) (cty.Value, bool) {
+	for _, name := range path {
+		if !value.IsKnown() {
+			return cty.DynamicVal, true
+		}
+		if value.IsNull() {
+			return cty.NilVal, false
+		}
+		ty := value.Type()
+		switch {
+		case ty.IsObjectType() && ty.HasAttribute(name):
+			value = value.GetAttr(name)
+		case ty.IsMapType():
+			has := value.HasIndex(cty.StringVal(name))
+			if !has.IsKnown() {
+				return cty.DynamicVal, true
+			}
+			if has.False() {
+				return cty.NilVal, false
+			}
+			value = value.Index(cty.StringVal(name))
+		default:
+			return cty.NilVal, false
+		}
+	}
+	return value, !value.IsNull()
+}
+
+// Returns the string at the path of the object, false if it doesn't exist or is not known yet
+func stringAttr(value cty.Value, path ...string) (string, bool) {
+	value, exists := lookupAttr(value, path...)
+	if !exists || !value.IsKnown() || value.Type() != cty.String {
+		return "", false
+	}
+	return value.AsString(), true
+}
+
+// Returns the group of the api version such as apps for apps/v1 and an empty string for v1
+func apiGroup(apiVersion string) string {
+	if i := strings.Index(apiVersion, "/"); i >= 0 {
+		return apiVersion[:i]
+	}
+	return ""
+}
+
+// orderedResource contains what the kind ordering needs to know about the instances of a resource
+type orderedResource struct {
+	resource        *decode.DecodedResource
+	lowest, highest int
+	// namespaces of the instances, an empty namespace is the namespace of the release
+	namespaces map[string]bool
+	// anyNamespace is set when the namespace of an instance is not known yet
+	anyNamespace bool
+	// kinds of the instances as group/kind
+	kinds map[string]bool
+	// declaredNamespaces are the names of the Namespace instances
+	declaredNamespaces []string
+	// definedKinds are the group/kind of the custom resources defined by the CustomResourceDefinition instances
+	definedKinds []string
+}
+
+// Collects the install order, namespaces and kinds of the resource instances
+// A resource with for_each or count may contain instances of different kinds
+// Returns false if the kind of no instance is known
+func newOrderedResource(r *decode.DecodedResource) (*orderedResource, bool) {
+	o := &orderedResource{
+		resource:   r,
+		lowest:     workloadOrder,
+		namespaces: make(map[string]bool),
+		kinds:      make(map[string]bool),
+	}
+	found := false
+	for _, value := range r.Config {
+		kind := instanceKind(value)
+		if kind == "" {
+			continue
+		}
+		order, exists := kindOrder[kind]
+		if !exists {
+			order = workloadOrder
+		}
+		o.lowest = min(o.lowest, order)
+		o.highest = max(o.highest, order)
+		found = true
+
+		apiVersion, _ := stringAttr(value, "apiVersion")
+		o.kinds[apiGroup(apiVersion)+"/"+kind] = true
+		switch namespace, exists := lookupAttr(value, "metadata", "namespace"); {
+		case !exists:
+			o.namespaces[""] = true
+		case !namespace.IsKnown() || namespace.Type() != cty.String:
+			o.anyNamespace = true
+		default:
+			o.namespaces[namespace.AsString()] = true
+		}
+
+		switch kind {
+		case "Namespace":
+			if name, ok := stringAttr(value, "metadata", "name"); ok {
+				o.declaredNamespaces = append(o.declaredNamespaces, name)
+			}
+		case "CustomResourceDefinition":
+			group, groupOk := stringAttr(value, "spec", "group")
+			definedKind, kindOk := stringAttr(value, "spec", "names", "kind")
+			if groupOk && kindOk {
+				o.definedKinds = append(o.definedKinds, group+"/"+definedKind)
+			}
+		}
+	}
+	return o, found
+}
+
+// Checks if an instance of the resource may live in the namespace
+func (o *orderedResource) inNamespace(namespace string) bool {
+	return o.anyNamespace || o.namespaces[namespace]
+}
+
+// Checks if the instances of both resources may share a namespace
+func (o *orderedResource) sharesNamespace(other *orderedResource) bool {
+	if o.anyNamespace || other.anyNamespace {
+		return true
+	}
+	for namespace := range o.namespaces {
+		if other.namespaces[namespace] {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks if the resource must be installed after dep according to their kinds
+// A resource depends on the namespaces its instances live in and on the definitions of its custom resources
+// Other kinds are installed according to kindOrder before the resources of the same namespace
+func (o *orderedResource) dependsOn(dep *orderedResource) bool {
+	for _, namespace := range dep.declaredNamespaces {
+		if o.lowest > 0 && o.inNamespace(namespace) {
+			return true
+		}
+	}
+	for _, kind := range dep.definedKinds {
+		if o.kinds[kind] {
+			return true
+		}
+	}
+	return dep.highest > kindOrder["CustomResourceDefinition"] && o.lowest > dep.highest && o.sharesNamespace(dep)
+}
+
+// Adds implicit edges between resources based on their kind
+// Resources which are not related by their namespace or custom resource definition stay parallel
+// Edges which would create a cycle with explicit depends_on are skipped, explicit dependencies take precedence
+func addKindEdges(g *Graph, resourceMap map[string]*decode.DecodedResource) {
+	var resources []*orderedResource
+	for _, r := range resourceMap {
+		if o, found := newOrderedResource(r); found {
+			resources = append(resources, o)
+		}
+	}
+
+	for _, r := range resources {
+		dependents, err := g.Descendents(r.resource)
+		if err != nil {
+			continue
+		}
+		for _, dep := range resources {
+			if r == dep || !r.dependsOn(dep) || dependents.Include(dep.resource) {
+				continue
+			}
+			g.Connect(dag.BasicEdge(r.resource, dep.resource))
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package configs
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"kubehcl.sh/kubehcl/internal/dag"
+	"kubehcl.sh/kubehcl/internal/decode"
+)
+
+func kindResource(name, kind string) *decode.DecodedResource {
+	return &decode.DecodedResource{
+		DecodedDeployable: decode.DecodedDeployable{
+			Name: name,
+			Type: "r",
+			Config: map[string]cty.Value{
+				"kube_resource." + name: cty.ObjectVal(map[string]cty.Value{
+					"kind": cty.StringVal(kind),
+				}),
+			},
+		},
+	}
+}
+
+// Returns a resource of the kind in the namespace, an empty namespace is not set in the metadata
+func namespacedResource(name, apiVersion, kind, namespace string) *decode.DecodedResource {
+	metadata := map[string]cty.Value{"name": cty.StringVal(name)}
+	if namespace != "" {
+		metadata["namespace"] = cty.StringVal(namespace)
+	}
+	return &decode.DecodedResource{
+		DecodedDeployable: decode.DecodedDeployable{
+			Name: name,
+			Type: "r",
+			Config: map[string]cty.Value{
+				"kube_resource." + name: cty.ObjectVal(map[string]cty.Value{
+					"apiVersion": cty.StringVal(apiVersion),
+					"kind":       cty.StringVal(kind),
+					"metadata":   cty.ObjectVal(metadata),
+				}),
+			},
+		},
+	}
+}
+
+// Returns a custom resource definition of the group and kind
+func crdResource(name, group, kind string) *decode.DecodedResource {
+	return &decode.DecodedResource{
+		DecodedDeployable: decode.DecodedDeployable{
+			Name: name,
+			Type: "r",
+			Config: map[string]cty.Value{
+				"kube_resource." + name: cty.ObjectVal(map[string]cty.Value{
+					"apiVersion": cty.StringVal("apiextensions.k8s.io/v1"),
+					"kind":       cty.StringVal("CustomResourceDefinition"),
+					"spec": cty.ObjectVal(map[string]cty.Value{
+						"group": cty.StringVal(group),
+						"names": cty.ObjectVal(map[string]cty.Value{"kind": cty.StringVal(kind)}),
+					}),
+				}),
+			},
+		},
+	}
+}
+
+func Test_KindOrdering(t *testing.T) {
+	mod := &decode.DecodedModule{
+		Name: rootNodeName,
+		Resources: decode.DecodedResourceMap{
+			"ns":         namespacedResource("apps", "v1", "Namespace", ""),
+			"crd":        crdResource("crd", "example.com", "Foo"),
+			"sa":         namespacedResource("sa", "v1", "ServiceAccount", "apps"),
+			"config":     namespacedResource("config", "v1", "ConfigMap", "apps"),
+			"deployment": namespacedResource("deployment", "apps/v1", "Deployment", "apps"),
+			"cr":         namespacedResource("cr", "example.com/v1", "Foo", "apps"),
+		},
+	}
+
+	g := &Graph{DecodedModule: mod}
+	diags := g.Init()
+	if diags.HasErrors() {
+		t.Fatalf("Failed becuase %s", diags.Error())
+	}
+
+	resources := mod.Resources
+	expected := map[string][]string{
+		"sa":         {"ns"},
+		"config":     {"ns", "sa"},
+		"deployment": {"ns", "sa", "config"},
+		"cr":         {"ns", "crd", "sa", "config"},
+	}
+	for name, deps := range expected {
+		ancestors, err := g.Ancestors(resources[name])
+		if err != nil {
+			t.Fatalf("Failed becuase %s", err)
+		}
+		for _, dep := range deps {
+			if !ancestors.Include(resources[dep]) {
+				t.Errorf("Expected %s to depend on %s", name, dep)
+			}
+		}
+	}
+
+	ancestors, _ := g.Ancestors(resources["deployment"])
+	if ancestors.Include(resources["cr"]) {
+		t.Errorf("Resources of the same order must not depend on each other")
+	}
+	if ancestors.Include(resources["crd"]) {
+		t.Errorf("Resources must depend only on the definitions of their kind")
+	}
+}
+
+func Test_KindOrderingParallel(t *testing.T) {
+	mod := &decode.DecodedModule{
+		Name: rootNodeName,
+		Resources: decode.DecodedResourceMap{
+			"ns_a":       namespacedResource("a", "v1", "Namespace", ""),
+			"ns_b":       namespacedResource("b", "v1", "Namespace", ""),
+			"crd_foo":    crdResource("crd_foo", "example.com", "Foo"),
+			"crd_bar":    crdResource("crd_bar", "example.com", "Bar"),
+			"config_a":   namespacedResource("config_a", "v1", "ConfigMap", "a"),
+			"config_b":   namespacedResource("config_b", "v1", "ConfigMap", "b"),
+			"deployment": namespacedResource("deployment", "apps/v1", "Deployment", "a"),
+			"foo":        namespacedResource("foo", "example.com/v1", "Foo", "a"),
+			"other_foo":  namespacedResource("other_foo", "other.com/v1", "Foo", "a"),
+			"released":   namespacedResource("released", "apps/v1", "Deployment", ""),
+		},
+	}
+
+	g := &Graph{DecodedModule: mod}
+	diags := g.Init()
+	if diags.HasErrors() {
+		t.Fatalf("Failed becuase %s", diags.Error())
+	}
+
+	resources := mod.Resources
+	unrelated := map[string][]string{
+		"config_a":   {"ns_b", "crd_foo", "crd_bar", "config_b"},
+		"deployment": {"ns_b", "crd_foo", "crd_bar", "config_b"},
+		"foo":        {"ns_b", "crd_bar", "config_b"},
+		"other_foo":  {"crd_foo", "crd_bar"},
+		"released":   {"ns_a", "ns_b", "crd_foo", "config_a", "config_b"},
+	}
+	for name, deps := range unrelated {
+		ancestors, err := g.Ancestors(resources[name])
+		if err != nil {
+			t.Fatalf("Failed becuase %s", err)
+		}
+		for _, dep := range deps {
+			if ancestors.Include(resources[dep]) {
+				t.Errorf("Expected %s not to depend on %s", name, dep)
+			}
+		}
+	}
+
+	related := map[string][]string{
+		"config_a":   {"ns_a"},
+		"deployment": {"ns_a", "config_a"},
+		"foo":        {"ns_a", "crd_foo", "config_a"},
+	}
+	for name, deps := range related {
+		ancestors, _ := g.Ancestors(resources[name])
+		for _, dep := range deps {
+			if !ancestors.Include(resources[dep]) {
+				t.Errorf("Expected %s to depend on %s", name, dep)
+			}
+		}
+	}
+}
+
+func Test_KindOrderingExplicitDependency(t *testing.T) {
+	ns := namespacedResource("apps", "v1", "Namespace", "")
+	config := namespacedResource("config", "v1", "ConfigMap", "apps")
+	ns.DependsOn = []hcl.Traversal{
+		{
+			hcl.TraverseRoot{Name: "kube_resource"},
+			hcl.TraverseAttr{Name: "config"},
+		},
+	}
+	mod := &decode.DecodedModule{
+		Name: rootNodeName,
+		Resources: decode.DecodedResourceMap{
+			"ns":     ns,
+			"config": config,
+		},
+	}
+
+	g := &Graph{DecodedModule: mod}
+	diags := g.Init()
+	if diags.HasErrors() {
+		t.Fatalf("Explicit dependencies must take precedence over the kind ordering %s", diags.Error())
+	}
+	if g.HasEdge(dag.BasicEdge(config, ns)) {
+		t.Errorf("Expected the implicit edge to be skipped")
+	}
+}
+
+func Test_DisableKindOrdering(t *testing.T) {
+	ns := namespacedResource("apps", "v1", "Namespace", "")
+	config := namespacedResource("config", "v1", "ConfigMap", "apps")
+	mod := &decode.DecodedModule{
+		Name: rootNodeName,
+		Resources: decode.DecodedResourceMap{
+			"ns":     ns,
+			"config": config,
+		},
+	}
+
+	g := &Graph{DecodedModule: mod, DisableKindOrdering: true}
+	diags := g.Init()
+	if diags.HasErrors() {
+		t.Fatalf("Failed becuase %s", diags.Error())
+	}
+	if g.HasEdge(dag.BasicEdge(config, ns)) {
+		t.Errorf("Expected no implicit edges when kind ordering is disabled")
+	}
+}
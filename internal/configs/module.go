@@ -68,6 +68,25 @@ var inputConfig = &hcl.BodySchema{
 			Type: "backend_storage",
 			// LabelNames: []string{"Kind","Name"},
 		},
+		{
+			Type:       "cluster",
+			LabelNames: []string{"Name"},
+		},
+		{
+			Type:       "output",
+			LabelNames: []string{"Name"},
+		},
+		{
+			Type:       DataType,
+			LabelNames: []string{"Name"},
+		},
+		{
+			Type: MovedType,
+		},
+		{
+			Type:       CheckType,
+			LabelNames: []string{"Name"},
+		},
 	},
 }
 
@@ -83,9 +102,14 @@ func (m *Module) merge(o *Module) {
 		maps.Copy(m.Inputs, o.Inputs)
 	}
 	m.Locals = append(m.Locals, o.Locals...)
+	m.Outputs = append(m.Outputs, o.Outputs...)
+	m.Data = append(m.Data, o.Data...)
+	m.Moved = append(m.Moved, o.Moved...)
+	m.Checks = append(m.Checks, o.Checks...)
 	m.Annotations = append(m.Annotations, o.Annotations...)
 	m.Resources = append(m.Resources, o.Resources...)
 	m.ModuleCalls = append(m.ModuleCalls, o.ModuleCalls...)
+	m.Clusters = append(m.Clusters, o.Clusters...)
 	if m.BackendStorage != nil {
 		if !m.BackendStorage.Used {
 			m.BackendStorage = o.BackendStorage
@@ -252,6 +276,8 @@ func (call *ModuleCall) decodeCallWithFolder(source, folderName string, appFs af
 			if existingVar.Type != cty.NilType {
 				variable.Type = existingVar.Type
 			}
+			variable.Sensitive = existingVar.Sensitive
+			variable.Validations = existingVar.Validations
 			module.Inputs[variable.Name] = variable
 		}
 	}
@@ -267,6 +293,10 @@ func (call *ModuleCall) decodeCallWithFolder(source, folderName string, appFs af
 	}
 	module.Name = call.Name
 	module.DependsOn = call.DependsOn
+	module.Cluster = call.Cluster
+	module.Count = call.Count
+	module.ForEach = call.ForEach
+	module.Postconditions = call.Postconditions
 	return module, diags
 }
 
@@ -348,6 +378,8 @@ func (call *ModuleCall) decodeCallWithRepo(source, prev string) (*Module, hcl.Di
 			if existingVar.Type != cty.NilType {
 				variable.Type = existingVar.Type
 			}
+			variable.Sensitive = existingVar.Sensitive
+			variable.Validations = existingVar.Validations
 			module.Inputs[variable.Name] = variable
 		}
 	}
@@ -363,6 +395,10 @@ func (call *ModuleCall) decodeCallWithRepo(source, prev string) (*Module, hcl.Di
 	}
 	module.Name = call.Name
 	module.DependsOn = call.DependsOn
+	module.Cluster = call.Cluster
+	module.Count = call.Count
+	module.ForEach = call.ForEach
+	module.Postconditions = call.Postconditions
 	module.Scope = appFs
 	return module, diags
 }
@@ -370,21 +406,38 @@ func (call *ModuleCall) decodeCallWithRepo(source, prev string) (*Module, hcl.Di
 // Decode module into decoded module
 // This decodes module and also modules inside that module
 // There are few parameters
+// Options of the decode which are shared by every module
 // Depth of the module
 // Folder to decode
 // Namespace to add to each resource if not exists
 // previous module context all vars and locals to apply to the variables of the new module
-func (m *Module) decode(releaseName string, depth int, folderName string, varsF string, vals []string, prevCtx *hcl.EvalContext, appFs afero.Fs) (*decode.DecodedModule, hcl.Diagnostics) {
+// Overrides file to override the values of the vars file
+func (m *Module) decode(opts *DecodeOptions, depth int, folderName string, varsF string, overridesF string, vals []string, prevCtx *hcl.EvalContext, appFs afero.Fs) (*decode.DecodedModule, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
 	if m.Scope != nil {
 		appFs = m.Scope
 	} else {
 		m.Scope = appFs
 	}
+	if depth == 0 {
+		m.root = folderName
+	}
+	// File functions and path values of the module are resolved from its own folder
+	m.scope = &decode.Scope{
+		Fs:      appFs,
+		Module:  folderName,
+		Root:    m.root,
+		Release: opts.Release,
+	}
+	// cluster exposes the capabilities of the cluster the module is deployed to
+	var clusterDiags hcl.Diagnostics
+	m.scope.Cluster, clusterDiags = m.clusters.get(m.Cluster)
+	diags = append(diags, clusterDiags...)
 	decodedModule := &decode.DecodedModule{
 		Depth:     depth,
 		Name:      m.Name,
 		DependsOn: m.DependsOn,
+		Cluster:   m.Cluster,
 		Modules:   make(decode.DecodedModuleMap),
 	}
 
@@ -396,6 +449,14 @@ func (m *Module) decode(releaseName string, depth int, folderName string, varsF
 				Subject:  &m.BackendStorage.DeclRange,
 			})
 		}
+		for _, cluster := range m.Clusters {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "cluster block is not allowed in a module",
+				Detail:   fmt.Sprintf("Declare cluster %s in the root module and reference it with the cluster argument of the module", cluster.Name),
+				Subject:  &cluster.DeclRange,
+			})
+		}
 	}
 
 	if diags.HasErrors() {
@@ -405,6 +466,11 @@ func (m *Module) decode(releaseName string, depth int, folderName string, varsF
 	if depth == 0 {
 		varsFromFile, varFileDiags := decodeVarsFile(folderName, varsF)
 		diags = append(diags, varFileDiags...)
+		if overridesF != "" {
+			overrides, overridesDiags := decodeVarsFile(folderName, overridesF)
+			diags = append(diags, overridesDiags...)
+			maps.Copy(varsFromFile, overrides)
+		}
 		vars, varsDiags := decodeVars(vals)
 		diags = append(diags, varsDiags...)
 		for key, variable := range vars {
@@ -467,23 +533,124 @@ func (m *Module) decode(releaseName string, depth int, folderName string, varsF
 		return &decode.DecodedModule{}, diags
 	}
 
-	decodedVariables, decodeVarDiags := m.Inputs.Decode(prevCtx)
+	decodedVariables, decodeVarDiags := m.Inputs.Decode(prevCtx, m.scope)
 	diags = append(diags, decodeVarDiags...)
 	decodedModule.Inputs = decodedVariables
 
-	ctx, ctxDiags := decode.CreateContext(decodedVariables, decode.DecodedLocalsMap{})
+	// Locals which reference module outputs are decoded once the referenced modules were decoded
+	// Locals which reference kube_data are decoded once the objects were read
+	localModules := make(map[string][]hcl.Traversal)
+	var earlyLocals, pendingLocals Locals
+	for _, local := range m.Locals {
+		traversals := local.Value.Variables()
+		if references := moduleReferences(traversals); len(references) > 0 {
+			localModules[local.Name] = references
+			pendingLocals = append(pendingLocals, local)
+		} else if len(shortReferences(traversals, DataType)) > 0 {
+			pendingLocals = append(pendingLocals, local)
+		} else {
+			earlyLocals = append(earlyLocals, local)
+		}
+	}
+
+	for i, module := range modules {
+		call := m.ModuleCalls[i]
+		traversals := append(bodyTraversals(call.Config), exprTraversals(call.Count, call.ForEach)...)
+		module.References = expandReferences(traversals, localModules)
+	}
+
+	ctx, ctxDiags := decode.CreateContext(decodedVariables, decode.DecodedLocalsMap{}, m.scope)
 	diags = append(diags, ctxDiags...)
 
-	DecodedLocals, decodeLocalsDiags := m.Locals.Decode(ctx)
+	DecodedLocals, decodeLocalsDiags := earlyLocals.Decode(ctx)
 	diags = append(diags, decodeLocalsDiags...)
 	decodedModule.Locals = DecodedLocals
 
-	ctx, ctxDiags = decode.CreateContext(decodedVariables, DecodedLocals)
-	diags = append(diags, ctxDiags...)
+	// kube_data is read before the resources and modules which may reference it
+	var DecodedData decode.DecodedDataMap
+	if len(m.Data) > 0 {
+		var dataDiags hcl.Diagnostics
+		DecodedData, dataDiags = m.Data.Decode(ctx, m.path, opts.DataReader)
+		diags = append(diags, dataDiags...)
+		decodedModule.Data = DecodedData
+	}
+
+	if len(m.Moved) > 0 {
+		var movedDiags hcl.Diagnostics
+		decodedModule.Moved, movedDiags = m.Moved.Decode(m.path)
+		diags = append(diags, movedDiags...)
+	}
+
+	moduleValues := make(map[string]cty.Value)
+	newContext := func() *hcl.EvalContext {
+		ctx, ctxDiags := decode.CreateContext(decodedVariables, DecodedLocals, m.scope)
+		diags = append(diags, ctxDiags...)
+		ctx.Variables[ModuleType] = cty.ObjectVal(moduleValues)
+		ctx.Variables[DataType] = dataValue(DecodedData)
+		return ctx
+	}
+
+	// Clusters are decoded before the modules which read the capabilities of the cluster they are deployed to
+	var clusters decode.DecodedClusterMap
+	if depth == 0 {
+		var localDiags hcl.Diagnostics
+		pendingLocals, localDiags = decodeReadyLocals(pendingLocals, localModules, moduleValues, DecodedLocals, newContext())
+		diags = append(diags, localDiags...)
+		if len(m.Clusters) > 0 {
+			clusters, clusterDiags = m.Clusters.Decode(newContext())
+			diags = append(diags, clusterDiags...)
+			decodedModule.Clusters = clusters
+		}
+		m.clusters = &clusterCapabilities{
+			reader:   opts.ClusterReader,
+			clusters: clusters,
+			read:     make(map[string]*decode.Capabilities),
+		}
+	}
+
+	orderedModules, orderDiags := orderModules(modules)
+	diags = append(diags, orderDiags...)
+	for _, module := range orderedModules {
+		var localDiags hcl.Diagnostics
+		pendingLocals, localDiags = decodeReadyLocals(pendingLocals, localModules, moduleValues, DecodedLocals, newContext())
+		diags = append(diags, localDiags...)
+
+		if module.Cluster == "" {
+			module.Cluster = m.Cluster
+		}
+		module.path = m.path + ModuleType + "." + module.Name + "."
+		module.root = m.root
+		module.clusters = m.clusters
+		moduleCtx := newContext()
+		// Instances are evaluated when the value of the module is computed
+		if module.Count != nil {
+			moduleCtx.Variables["count"] = cty.ObjectVal(map[string]cty.Value{"index": cty.UnknownVal(cty.Number)})
+		} else if module.ForEach != nil {
+			moduleCtx.Variables["each"] = cty.ObjectVal(map[string]cty.Value{"key": cty.UnknownVal(cty.String), "value": cty.DynamicVal})
+		}
+		dm, dmDiags := module.decode(opts, depth+1, module.Source, "", "", make([]string, 0), moduleCtx, appFs)
+		diags = append(diags, dmDiags...)
+		dm.References = module.References
+		dm.Value = cty.DynamicVal
+		if !dmDiags.HasErrors() {
+			value, valueDiags := module.value(dm, moduleCtx)
+			diags = append(diags, valueDiags...)
+			dm.Value = value
+			diags = append(diags, module.checkPostconditions(dm, moduleCtx)...)
+		}
+		moduleValues[dm.Name] = dm.Value
+		decodedModule.Modules[dm.Name] = dm
+	}
+
+	// The remaining locals reference modules which don't exist and are reported when decoded
+	_, decodeLocalsDiags = decodeReadyLocals(pendingLocals, map[string][]hcl.Traversal{}, moduleValues, DecodedLocals, newContext())
+	diags = append(diags, decodeLocalsDiags...)
+
+	ctx = newContext()
 
 	DecodedAnnotations, decodeAnnotationsDiags := m.Annotations.Decode(ctx)
 	diags = append(diags, decodeAnnotationsDiags...)
-	if releaseName != "" {
+	if releaseName := opts.releaseName(); releaseName != "" {
 		DecodedAnnotations["kubehcl.sh/managed"] = &decode.DecodedAnnotation{
 			Name:  "kubehcl.sh/managed",
 			Value: cty.StringVal("This resource is managed by kubehcl"),
@@ -495,19 +662,27 @@ func (m *Module) decode(releaseName string, depth int, folderName string, varsF
 	}
 	decodedModule.Annotations = DecodedAnnotations
 
+	// References are collected before decoding since decoding removes the meta attributes from the body
+	references := make(map[string][]hcl.Traversal)
+	for _, resource := range m.Resources {
+		traversals := resource.traversals()
+		references[resource.Name] = append(expandReferences(traversals, localModules), resourceReferences(traversals)...)
+	}
+
 	DecodedResources, decodeResourcesDiags := m.Resources.Decode(ctx)
 	diags = append(diags, decodeResourcesDiags...)
 	decodedModule.Resources = DecodedResources
+	// Resources without a cluster are deployed to the cluster of the module
+	for _, resource := range DecodedResources {
+		if resource.Cluster == "" {
+			resource.Cluster = m.Cluster
+		}
+		resource.References = references[resource.Name]
+	}
 
 	DecodedModuleCalls, decodeModuleCallDiags := m.ModuleCalls.Decode(ctx)
 	diags = append(diags, decodeModuleCallDiags...)
 	decodedModule.ModuleCalls = DecodedModuleCalls
-
-	for _, module := range modules {
-		dm, dmDiags := module.decode(releaseName, depth+1, module.Source, "", make([]string, 0), ctx, appFs)
-		diags = append(diags, dmDiags...)
-		decodedModule.Modules[dm.Name] = dm
-	}
 	/*
 		Adding annotations to each decoded resource only if it has metadata defined beforehand
 	*/
@@ -551,7 +726,16 @@ func (m *Module) decode(releaseName string, depth int, folderName string, varsF
 		storage, storageDiags := m.BackendStorage.decode(ctx)
 		diags = append(diags, storageDiags...)
 		decodedModule.BackendStorage = storage
+		diags = append(diags, verifyClusterReferences(decodedModule, clusters)...)
+	}
+
+	// Outputs are evaluated once everything else in the module was decoded
+	if len(m.Outputs) > 0 {
+		DecodedOutputs, decodeOutputsDiags := m.Outputs.Decode(ctx)
+		diags = append(diags, decodeOutputsDiags...)
+		decodedModule.Outputs = DecodedOutputs
 	}
+	diags = append(diags, m.Checks.Check(ctx, m.path)...)
 
 	return decodedModule, diags
 }
@@ -593,6 +777,21 @@ func decodeHclBytes(src []byte, fileName string, addrMap addrs.AddressMap) (Modu
 	storageBlock, storageDiags := DecodeBackendStorageBlocks(b.Blocks.OfType("backend_storage"))
 	diags = append(diags, storageDiags...)
 
+	clusters, clusterDiags := DecodeClusterBlocks(b.Blocks.OfType("cluster"))
+	diags = append(diags, clusterDiags...)
+
+	outputs, outputDiags := DecodeOutputBlocks(b.Blocks.OfType("output"))
+	diags = append(diags, outputDiags...)
+
+	data, dataDiags := DecodeDataBlocks(b.Blocks.OfType(DataType))
+	diags = append(diags, dataDiags...)
+
+	moved, movedDiags := DecodeMovedBlocks(b.Blocks.OfType(MovedType))
+	diags = append(diags, movedDiags...)
+
+	checks, checkDiags := DecodeCheckBlocks(b.Blocks.OfType(CheckType))
+	diags = append(diags, checkDiags...)
+
 	var modules ModuleCallList
 
 	moduleList, moduleDiags := DecodeModuleBlocks(b.Blocks.OfType("module"), addrMap)
@@ -602,6 +801,11 @@ func decodeHclBytes(src []byte, fileName string, addrMap addrs.AddressMap) (Modu
 
 	return Module{
 		BackendStorage: storageBlock,
+		Clusters:       clusters,
+		Outputs:        outputs,
+		Data:           data,
+		Moved:          moved,
+		Checks:         checks,
 		Inputs:         vars,
 		Locals:         locals,
 		Annotations:    defaultAnnotaions,
@@ -719,7 +923,7 @@ func DecodeIndexFile(fileName string) (map[string]string, hcl.Diagnostics) {
 			DeclRange:  *attr.Expr.Range().Ptr(),
 		}
 	}
-	decodedVariables, decodeDiags := variables.Decode(&hcl.EvalContext{})
+	decodedVariables, decodeDiags := variables.Decode(&hcl.EvalContext{}, nil)
 	diags = append(diags, decodeDiags...)
 	annotations := make(map[string]string)
 	for _, variable := range decodedVariables {
@@ -740,17 +944,45 @@ func DecodeIndexFile(fileName string) (map[string]string, hcl.Diagnostics) {
 	return annotations, diags
 }
 
+// DecodeOptions are the options of decoding a folder and its modules
+type DecodeOptions struct {
+	// Release is exposed to every module as release, resources are annotated with its name when it is set
+	Release    *decode.Release
+	FolderName string
+	VarsFile   string
+	// KubeContext selects the <context>.tfvars file of the folder which overrides the values of the vars file
+	KubeContext string
+	Vals        []string
+	// DataReader reads the kube_data blocks, they can't be decoded when it is nil
+	DataReader DataReader
+	// ClusterReader reads the capabilities of the cluster aliases, modules deployed to an alias have the capabilities of the default cluster when it is nil
+	ClusterReader ClusterReader
+}
+
+// Returns the name of the release being decoded, empty when decoded without a release
+func (opts *DecodeOptions) releaseName() string {
+	if opts.Release == nil {
+		return ""
+	}
+	return opts.Release.Name
+}
+
 // Decode both folder and module into a decoded module
-func DecodeFolderAndModules(releaseName string, folderName string, name string, varF string, vals []string, depth int) (*decode.DecodedModule, hcl.Diagnostics) {
-	if depth == 0 {
-		_, diags := DecodeIndexFile(folderName + "/" + INDEXVARSFILE)
-		if diags.HasErrors() {
-			return &decode.DecodedModule{}, diags
-		}
+// The folder is parsed again since decoding modifies the parsed bodies
+func DecodeFolder(opts *DecodeOptions) (*decode.DecodedModule, hcl.Diagnostics) {
+	parser = hclparse.NewParser()
+	storageCounter = 0
+	_, diags := DecodeIndexFile(opts.FolderName + "/" + INDEXVARSFILE)
+	if diags.HasErrors() {
+		return &decode.DecodedModule{}, diags
 	}
 	appFs := afero.NewOsFs()
-	mod, diags := decodeFolder(folderName, appFs)
-	dm, decodeDiags := mod.decode(releaseName, 0, folderName, varF, vals, &hcl.EvalContext{}, appFs)
+	mod, diags := decodeFolder(opts.FolderName, appFs)
+	overridesF := ""
+	if opts.KubeContext != "" {
+		overridesF = opts.KubeContext + ".tfvars"
+	}
+	dm, decodeDiags := mod.decode(opts, 0, opts.FolderName, opts.VarsFile, overridesF, opts.Vals, &hcl.EvalContext{}, appFs)
 	diags = append(diags, decodeDiags...)
 	return dm, diags
 }
@@ -12,6 +12,7 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/zclconf/go-cty/cty"
 	"kubehcl.sh/kubehcl/internal/addrs"
 	"kubehcl.sh/kubehcl/internal/decode"
@@ -105,6 +106,9 @@ var inputModuleBlockSchema = &hcl.BodySchema{
 		{
 			Name: "depends_on",
 		},
+		{
+			Name: "cluster",
+		},
 		{
 			Name:     "source",
 			Required: true,
@@ -113,7 +117,23 @@ var inputModuleBlockSchema = &hcl.BodySchema{
 			Name: "version",
 		},
 	},
-	Blocks: []hcl.BlockHeaderSchema{},
+	Blocks: []hcl.BlockHeaderSchema{
+		{
+			Type: "lifecycle",
+		},
+	},
+}
+
+// Module calls don't create resources themselves so their lifecycle contains only conditions
+var moduleLifecycleBlockSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{
+			Type: "precondition",
+		},
+		{
+			Type: "postcondition",
+		},
+	},
 }
 
 // Decode module block
@@ -130,6 +150,16 @@ func decodeModuleBlock(block *hcl.Block) (*ModuleCall, hcl.Diagnostics) {
 	Module.Type = addrs.MType
 
 	content, remain, diags := block.Body.PartialContent(inputModuleBlockSchema)
+	// The remaining body contains the arguments of the module, the lifecycle block is removed so it isn't read as one
+	if body, ok := remain.(*hclsyntax.Body); ok {
+		var blocks hclsyntax.Blocks
+		for _, b := range body.Blocks {
+			if b.Type != "lifecycle" {
+				blocks = append(blocks, b)
+			}
+		}
+		body.Blocks = blocks
+	}
 	Module.Config = remain
 	if attr, exists := content.Attributes["count"]; exists {
 
@@ -145,9 +175,8 @@ func decodeModuleBlock(block *hcl.Block) (*ModuleCall, hcl.Diagnostics) {
 				Subject:  &attr.NameRange,
 				Context:  &content.Attributes["count"].NameRange,
 			})
-			Module.ForEach = attr.Expr
-
 		}
+		Module.ForEach = attr.Expr
 	}
 
 	if attr, exists := content.Attributes["depends_on"]; exists {
@@ -156,6 +185,12 @@ func decodeModuleBlock(block *hcl.Block) (*ModuleCall, hcl.Diagnostics) {
 		Module.DependsOn = append(Module.DependsOn, traversal...)
 	}
 
+	if attr, exists := content.Attributes["cluster"]; exists {
+		cluster, clusterDiags := decodeClusterAttr(attr)
+		diags = append(diags, clusterDiags...)
+		Module.Cluster = cluster
+	}
+
 	if attr, exists := content.Attributes["source"]; exists {
 		Module.Source = attr.Expr
 	}
@@ -163,6 +198,21 @@ func decodeModuleBlock(block *hcl.Block) (*ModuleCall, hcl.Diagnostics) {
 		Module.Version = attr.Expr
 	}
 
+	for i, lifecycleBlock := range content.Blocks.OfType("lifecycle") {
+		if i > 0 {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate lifecycle block",
+				Detail:   fmt.Sprintf("Module %s can contain only one lifecycle block", Module.Name),
+				Subject:  &lifecycleBlock.DefRange,
+			})
+			continue
+		}
+		lifecycle, lifecycleDiags := lifecycleBlock.Body.Content(moduleLifecycleBlockSchema)
+		diags = append(diags, lifecycleDiags...)
+		diags = append(diags, decodeLifecycleConditions(lifecycle, &Module.Deployable)...)
+	}
+
 	return Module, diags
 
 }
@@ -137,3 +137,47 @@ func Test_ModuleCall(t *testing.T) {
 		}
 	}
 }
+
+func Test_ModuleCallForEach(t *testing.T) {
+	tests := []struct {
+		src        string
+		wantErrors bool
+	}{
+		{
+			src: `module "foo" {
+  source   = "./foo"
+  for_each = { a = "first", b = "second" }
+}`,
+		},
+		{
+			src: `module "foo" {
+  source   = "./foo"
+  count    = 2
+  for_each = { a = "first", b = "second" }
+}`,
+			wantErrors: true,
+		},
+	}
+
+	schema := &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: "module", LabelNames: []string{"name"}}},
+	}
+	for _, test := range tests {
+		file, diags := hclsyntax.ParseConfig([]byte(test.src), "main.hcl", hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			t.Fatalf("Test is not written correctly: %s", diags.Errs())
+		}
+		content, diags := file.Body.Content(schema)
+		if diags.HasErrors() {
+			t.Fatalf("Test is not written correctly: %s", diags.Errs())
+		}
+		calls, diags := DecodeModuleBlocks(content.Blocks, addrs.AddressMap{})
+		if diags.HasErrors() && !test.wantErrors {
+			t.Errorf("Don't want errors but received: %s", diags.Errs())
+		} else if !diags.HasErrors() && test.wantErrors {
+			t.Errorf("Want errors but did not receive any")
+		} else if !test.wantErrors && (len(calls) != 1 || calls[0].ForEach == nil) {
+			t.Errorf("Expected for_each of module foo to be decoded")
+		}
+	}
+}
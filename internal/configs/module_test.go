@@ -9,6 +9,8 @@
 package configs
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/go-test/deep"
@@ -685,7 +687,7 @@ func Test_Module(t *testing.T) {
 												End: hcl.Pos{
 													Line:   68,
 													Column: 30,
-													Byte:  1316,
+													Byte:   1316,
 												},
 											},
 										},
@@ -802,7 +804,7 @@ func Test_Module(t *testing.T) {
 
 	for _, test := range tests {
 		logging.SetLogger(false)
-		want, diags := DecodeFolderAndModules("test", test.d, "", "", []string{}, 0)
+		want, diags := DecodeFolder(&DecodeOptions{Release: &decode.Release{Name: "test"}, FolderName: test.d})
 
 		if diags.HasErrors() && !test.wantErrors {
 			t.Errorf("Don't want errors but received: %s", diags.Errs())
@@ -816,3 +818,42 @@ func Test_Module(t *testing.T) {
 		}
 	}
 }
+
+func Test_DecodeFolderKubeContext(t *testing.T) {
+	folder := t.TempDir()
+	files := map[string]string{
+		INDEXVARSFILE:    "name = \"test\"\nversion = \"1\"\n",
+		"main.hcl":       "variable \"replicas\" {}\nvariable \"region\" {}\n",
+		"kubehcl.tfvars": "replicas = 1\nregion = \"eu\"\n",
+		"edge-1.tfvars":  "replicas = 3\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(folder, name), []byte(src), 0644); err != nil {
+			t.Fatalf("Couldn't write test file: %s", err)
+		}
+	}
+
+	tests := []struct {
+		context  string
+		replicas int64
+	}{
+		{context: "", replicas: 1},
+		{context: "edge-1", replicas: 3},
+		{context: "edge-2", replicas: 1},
+	}
+
+	logging.SetLogger(false)
+	for _, test := range tests {
+		dm, diags := DecodeFolder(&DecodeOptions{Release: &decode.Release{Name: "test"}, FolderName: folder, KubeContext: test.context})
+		if diags.HasErrors() {
+			t.Fatalf("Don't want errors but received: %s", diags.Errs())
+		}
+		replicas, _ := dm.Inputs["replicas"].Default.AsBigFloat().Int64()
+		if replicas != test.replicas {
+			t.Errorf("Context %q: expected replicas %d got %d", test.context, test.replicas, replicas)
+		}
+		if region := dm.Inputs["region"].Default.AsString(); region != "eu" {
+			t.Errorf("Context %q: expected region eu got %s", test.context, region)
+		}
+	}
+}
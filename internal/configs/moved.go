@@ -0,0 +1,235 @@
+package configs
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"kubehcl.sh/kubehcl/internal/decode"
+)
+
+const MovedType = "moved"
+
+// Moved renames an address saved in the state so the resource is not deleted and created again
+type Moved struct {
+	From      hcl.Expression
+	To        hcl.Expression
+	DeclRange hcl.Range
+}
+
+type MovedList []*Moved
+
+var inputMovedBlockSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "from", Required: true},
+		{Name: "to", Required: true},
+	},
+}
+
+func decodeMovedBlock(block *hcl.Block) (*Moved, hcl.Diagnostics) {
+	moved := &Moved{
+		DeclRange: block.DefRange,
+	}
+	content, diags := block.Body.Content(inputMovedBlockSchema)
+	if attr, exists := content.Attributes["from"]; exists {
+		moved.From = attr.Expr
+	}
+	if attr, exists := content.Attributes["to"]; exists {
+		moved.To = attr.Expr
+	}
+	return moved, diags
+}
+
+// Decode multiple moved blocks
+func DecodeMovedBlocks(blocks hcl.Blocks) (MovedList, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	var movedList MovedList
+	for _, block := range blocks {
+		moved, movedDiags := decodeMovedBlock(block)
+		diags = append(diags, movedDiags...)
+		if moved.From != nil && moved.To != nil {
+			movedList = append(movedList, moved)
+		}
+	}
+	return movedList, diags
+}
+
+// Decode the from or to address of a moved block
+func decodeMovedAddress(expr hcl.Expression, name string) (Target, hcl.Diagnostics) {
+	traversal, diags := hcl.AbsTraversalForExpr(expr)
+	if diags.HasErrors() {
+		return Target{}, diags
+	}
+	target, detail := traversalTarget(traversal)
+	if detail != "" {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid moved address",
+			Detail:   fmt.Sprintf("The %s address is invalid: %s", name, detail),
+			Subject:  expr.Range().Ptr(),
+		})
+	}
+	return target, diags
+}
+
+// Decode the moved block, the addresses are prefixed with the path of the module
+func (m *Moved) decode(path string) (*decode.DecodedMoved, hcl.Diagnostics) {
+	from, diags := decodeMovedAddress(m.From, "from")
+	to, toDiags := decodeMovedAddress(m.To, "to")
+	diags = append(diags, toDiags...)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	if (from.kind == targetModule) != (to.kind == targetModule) {
+		return nil, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid moved block",
+			Detail:   fmt.Sprintf("Can't move %s to %s, a module can only be moved to a module and a resource to a resource", from.Address, to.Address),
+			Subject:  &m.DeclRange,
+		})
+	}
+	if from.Address == to.Address {
+		return nil, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid moved block",
+			Detail:   fmt.Sprintf("The from and to addresses are both %s", from.Address),
+			Subject:  &m.DeclRange,
+		})
+	}
+
+	return &decode.DecodedMoved{
+		From:      path + from.Address,
+		To:        path + to.Address,
+		Module:    from.kind == targetModule,
+		DeclRange: m.DeclRange,
+	}, diags
+}
+
+// Decode the moved blocks of a module
+func (mList MovedList) Decode(path string) (decode.DecodedMovedList, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	var movedList decode.DecodedMovedList
+	for _, m := range mList {
+		moved, movedDiags := m.decode(path)
+		diags = append(diags, movedDiags...)
+		if moved != nil {
+			movedList = append(movedList, moved)
+		}
+	}
+	return movedList, diags
+}
+
+// Collects the moves of the module and its child modules
+func collectMoved(dm *decode.DecodedModule) decode.DecodedMovedList {
+	moved := slices.Clone(dm.Moved)
+	for _, child := range dm.Modules {
+		moved = append(moved, collectMoved(child)...)
+	}
+	return moved
+}
+
+// Checks if the module address such as module.foo.module.bar is declared in the configuration
+func moduleDeclared(dm *decode.DecodedModule, address string) bool {
+	parts := strings.Split(address, ".")
+	for i := 1; i < len(parts); i += 2 {
+		child, exists := dm.Modules[parts[i]]
+		if !exists {
+			return false
+		}
+		dm = child
+	}
+	return true
+}
+
+// Checks if the address moved from is still declared in the configuration
+func (g *Graph) movedDeclared(m *decode.DecodedMoved, resourceMap map[string]*decode.DecodedResource) bool {
+	if m.Module {
+		return moduleDeclared(g.DecodedModule, m.From)
+	}
+	if r, exists := resourceMap[m.From]; exists {
+		return true
+	} else if name, _, ok := strings.Cut(m.From, "["); ok {
+		r, exists = resourceMap[name]
+		if exists {
+			_, exists = r.Config[m.From]
+		}
+		return exists
+	}
+	return false
+}
+
+// Checks if following the moves from the address applies the same move twice
+func movedCycle(moved decode.DecodedMovedList, address string) bool {
+	used := make([]bool, len(moved))
+	for {
+		next := false
+		for i, m := range moved {
+			if to, ok := m.Rewrite(address); ok {
+				if used[i] {
+					return true
+				}
+				used[i] = true
+				address = to
+				next = true
+				break
+			}
+		}
+		if !next {
+			return false
+		}
+	}
+}
+
+// Collects the moved blocks of every module into the graph and validates them against the configuration
+// Moves are ordered from the most specific address so moving a resource out of a moved module takes precedence
+func (g *Graph) initMoved(resourceMap map[string]*decode.DecodedResource) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	moved := collectMoved(g.DecodedModule)
+	if len(moved) == 0 {
+		return diags
+	}
+	slices.SortStableFunc(moved, func(a, b *decode.DecodedMoved) int {
+		return len(b.From) - len(a.From)
+	})
+
+	from := make(map[string]bool)
+	for _, m := range moved {
+		if from[m.From] {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Ambiguous move statements",
+				Detail:   fmt.Sprintf("%s is moved by more than one moved block", m.From),
+				Subject:  &m.DeclRange,
+			})
+			continue
+		}
+		from[m.From] = true
+
+		if g.movedDeclared(m, resourceMap) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Moved object still declared",
+				Detail:   fmt.Sprintf("%s is still declared in the configuration, remove it or change the from address of the moved block", m.From),
+				Subject:  &m.DeclRange,
+			})
+		}
+	}
+	if diags.HasErrors() {
+		return diags
+	}
+
+	for _, m := range moved {
+		if movedCycle(moved, m.From) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Cyclic move statements",
+				Detail:   fmt.Sprintf("Following the moved blocks from %s leads back to an address which was already moved", m.From),
+				Subject:  &m.DeclRange,
+			})
+		}
+	}
+	g.Moved = moved
+	return diags
+}
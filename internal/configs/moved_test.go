@@ -0,0 +1,174 @@
+package configs
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/spf13/afero"
+	"kubehcl.sh/kubehcl/internal/logging"
+)
+
+func Test_Moved(t *testing.T) {
+	resource := func(name string) string {
+		return `kube_resource "` + name + `" {
+  apiVersion = "v1"
+  kind       = "ConfigMap"
+  metadata = {
+    name = "` + name + `"
+  }
+}
+`
+	}
+	tests := []struct {
+		folder     string
+		files      map[string]string
+		want       map[string]string
+		wantErrors bool
+	}{
+		{
+			folder: "moved",
+			files: map[string]string{
+				"moved/main.hcl": resource("baz") + `moved {
+  from = kube_resource.foo
+  to   = kube_resource.bar
+}
+moved {
+  from = kube_resource.bar
+  to   = kube_resource.baz
+}
+moved {
+  from = kube_resource.web
+  to   = module.child.kube_resource.web
+}
+moved {
+  from = module.old
+  to   = module.child
+}
+module "child" {
+  source = "./child"
+}`,
+				"moved/child/main.hcl": resource("web") + resource("config") + `moved {
+  from = kube_resource.settings["a"]
+  to   = kube_resource.config
+}`,
+			},
+			want: map[string]string{
+				"kube_resource.foo":                          "kube_resource.baz",
+				"kube_resource.foo[1]":                       "kube_resource.baz[1]",
+				"kube_resource.bar":                          "kube_resource.baz",
+				"kube_resource.web":                          "module.child.kube_resource.web",
+				"module.old.kube_resource.web":               "module.child.kube_resource.web",
+				"module.old.module.x.kube_resource.a":        "module.child.module.x.kube_resource.a",
+				"module.child.kube_resource.settings[a]":     "module.child.kube_resource.config",
+				"module.child.kube_resource.settings[b]":     "",
+				"kube_resource.baz":                          "",
+				"module.older.kube_resource.web":             "",
+				"module.child.kube_resource.settings_2[a]":   "",
+				"module.child.kube_resource.settings[a].foo": "",
+			},
+		},
+		{
+			folder: "moved_invalid_address",
+			files: map[string]string{
+				"moved_invalid_address/main.hcl": `moved {
+  from = variable.foo
+  to   = kube_resource.bar
+}`,
+			},
+			wantErrors: true,
+		},
+		{
+			folder: "moved_module_to_resource",
+			files: map[string]string{
+				"moved_module_to_resource/main.hcl": `moved {
+  from = module.foo
+  to   = kube_resource.bar
+}`,
+			},
+			wantErrors: true,
+		},
+		{
+			folder: "moved_same_address",
+			files: map[string]string{
+				"moved_same_address/main.hcl": `moved {
+  from = kube_resource.foo
+  to   = kube_resource.foo
+}`,
+			},
+			wantErrors: true,
+		},
+		{
+			folder: "moved_still_declared",
+			files: map[string]string{
+				"moved_still_declared/main.hcl": resource("foo") + `moved {
+  from = kube_resource.foo
+  to   = kube_resource.bar
+}`,
+			},
+			wantErrors: true,
+		},
+		{
+			folder: "moved_ambiguous",
+			files: map[string]string{
+				"moved_ambiguous/main.hcl": `moved {
+  from = kube_resource.foo
+  to   = kube_resource.bar
+}
+moved {
+  from = kube_resource.foo
+  to   = kube_resource.baz
+}`,
+			},
+			wantErrors: true,
+		},
+		{
+			folder: "moved_cycle",
+			files: map[string]string{
+				"moved_cycle/main.hcl": `moved {
+  from = kube_resource.foo
+  to   = kube_resource.bar
+}
+moved {
+  from = kube_resource.bar
+  to   = kube_resource.foo
+}`,
+			},
+			wantErrors: true,
+		},
+	}
+
+	logging.SetLogger(false)
+	for _, test := range tests {
+		appFs := afero.NewMemMapFs()
+		for name, src := range test.files {
+			if err := afero.WriteFile(appFs, name, []byte(src), 0644); err != nil {
+				t.Fatalf("Couldn't write test file: %s", err)
+			}
+		}
+		mod, diags := decodeFolder(test.folder, appFs)
+		dm, decodeDiags := mod.decode(&DecodeOptions{}, 0, test.folder, "", "", nil, &hcl.EvalContext{}, appFs)
+		diags = append(diags, decodeDiags...)
+		g := &Graph{DecodedModule: dm}
+		if !diags.HasErrors() {
+			diags = append(diags, g.Init()...)
+		}
+		if diags.HasErrors() && !test.wantErrors {
+			t.Errorf("Don't want errors but received: %s", diags.Errs())
+			continue
+		} else if !diags.HasErrors() && test.wantErrors {
+			t.Errorf("Want errors but did not receive any: %s", test.folder)
+			continue
+		} else if test.wantErrors {
+			continue
+		}
+
+		for key, want := range test.want {
+			got, moved := g.Moved.Resolve(key)
+			if want == "" && moved {
+				t.Errorf("Expected %s not to be moved got %s", key, got)
+			} else if want != "" && got != want {
+				t.Errorf("Expected %s to be moved to %s got %s", key, want, got)
+			}
+		}
+	}
+}
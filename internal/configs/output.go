@@ -0,0 +1,106 @@
+package configs
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"kubehcl.sh/kubehcl/internal/decode"
+	"kubehcl.sh/kubehcl/internal/lang/marks"
+)
+
+// Output exposes a value computed by the configuration
+type Output struct {
+	Name        string
+	Description string
+	Value       hcl.Expression
+	Sensitive   bool
+	DeclRange   hcl.Range
+}
+
+type Outputs []*Output
+
+var inputOutputBlockSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "value", Required: true},
+		{Name: "description", Required: false},
+		{Name: "sensitive", Required: false},
+	},
+}
+
+// Decode the output value
+func (o *Output) decode(ctx *hcl.EvalContext) (*decode.DecodedOutput, hcl.Diagnostics) {
+	dO := &decode.DecodedOutput{
+		Name:        o.Name,
+		Description: o.Description,
+		Sensitive:   o.Sensitive,
+		DeclRange:   o.DeclRange,
+	}
+	value, diags := o.Value.Value(ctx)
+	if o.Sensitive {
+		value = value.Mark(marks.Sensitive)
+	} else if marks.Contains(value, marks.Sensitive) {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Output refers to sensitive values",
+			Detail:   fmt.Sprintf("Output %s contains sensitive values, to confirm they can be exported add sensitive = true to the output", o.Name),
+			Subject:  o.Value.Range().Ptr(),
+		})
+	}
+	dO.Value = value
+	return dO, diags
+}
+
+// Decode multiple outputs
+func (o Outputs) Decode(ctx *hcl.EvalContext) (decode.DecodedOutputMap, hcl.Diagnostics) {
+	dOutputs := make(decode.DecodedOutputMap)
+	var diags hcl.Diagnostics
+	for _, output := range o {
+		dO, outputDiags := output.decode(ctx)
+		diags = append(diags, outputDiags...)
+		if _, exists := dOutputs[dO.Name]; exists {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Outputs must have different names",
+				Detail:   fmt.Sprintf("Two outputs have the same name: %s", dO.Name),
+				Subject:  &dO.DeclRange,
+			})
+		}
+		dOutputs[dO.Name] = dO
+	}
+	return dOutputs, diags
+}
+
+// Decode output block
+// Each block must contain a value and may contain a description and whether the value is sensitive
+func decodeOutputBlock(block *hcl.Block) (*Output, hcl.Diagnostics) {
+	output := &Output{
+		Name:      block.Labels[0],
+		DeclRange: block.DefRange,
+	}
+	content, diags := block.Body.Content(inputOutputBlockSchema)
+	if attr, exists := content.Attributes["value"]; exists {
+		output.Value = attr.Expr
+	}
+	if attr, exists := content.Attributes["description"]; exists {
+		diags = append(diags, gohcl.DecodeExpression(attr.Expr, nil, &output.Description)...)
+	}
+	if attr, exists := content.Attributes["sensitive"]; exists {
+		diags = append(diags, gohcl.DecodeExpression(attr.Expr, nil, &output.Sensitive)...)
+	}
+	return output, diags
+}
+
+// Decode multiple output blocks
+func DecodeOutputBlocks(blocks hcl.Blocks) (Outputs, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	var outputs Outputs
+	for _, block := range blocks {
+		output, outputDiags := decodeOutputBlock(block)
+		diags = append(diags, outputDiags...)
+		if output.Value != nil {
+			outputs = append(outputs, output)
+		}
+	}
+	return outputs, diags
+}
@@ -0,0 +1,146 @@
+package configs
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/spf13/afero"
+	"github.com/zclconf/go-cty/cty"
+	"kubehcl.sh/kubehcl/internal/lang/marks"
+	"kubehcl.sh/kubehcl/internal/logging"
+)
+
+func Test_Output(t *testing.T) {
+	tests := []struct {
+		folder     string
+		files      map[string]string
+		want       map[string]cty.Value
+		sensitive  map[string]bool
+		wantErrors bool
+	}{
+		{
+			folder: "outputs",
+			files: map[string]string{
+				"outputs/main.hcl": `variable "host" {
+  default = "example.com"
+}
+locals {
+  service = "web"
+}
+output "host" {
+  value       = "https://${var.host}"
+  description = "Ingress host"
+}
+output "service" {
+  value     = local.service
+  sensitive = true
+}`,
+			},
+			want: map[string]cty.Value{
+				"host":    cty.StringVal("https://example.com"),
+				"service": cty.StringVal("web").Mark(marks.Sensitive),
+			},
+			sensitive: map[string]bool{"service": true},
+		},
+		{
+			// Outputs can reference the config of the resources of the module
+			folder: "resource_outputs",
+			files: map[string]string{
+				"resource_outputs/main.hcl": `kube_resource "svc" {
+  apiVersion = "v1"
+  kind       = "Service"
+  metadata = {
+    name = "web-svc"
+  }
+}
+kube_resource "cm" {
+  count      = 2
+  apiVersion = "v1"
+  kind       = "ConfigMap"
+  metadata = {
+    name = "cm-${count.index}"
+  }
+}
+output "host" {
+  value       = kube_resource.svc.metadata.name
+  description = "Ingress host"
+}
+output "last" {
+  value = kube_resource.cm[2].metadata.name
+}`,
+			},
+			want: map[string]cty.Value{
+				"host": cty.StringVal("web-svc"),
+				"last": cty.StringVal("cm-2"),
+			},
+		},
+		{
+			folder: "missing_value",
+			files: map[string]string{
+				"missing_value/main.hcl": `output "host" {
+  description = "Ingress host"
+}`,
+			},
+			wantErrors: true,
+		},
+		{
+			folder: "duplicate_output",
+			files: map[string]string{
+				"duplicate_output/main.hcl": `output "host" {
+  value = "a"
+}
+output "host" {
+  value = "b"
+}`,
+			},
+			wantErrors: true,
+		},
+		{
+			folder: "undeclared_reference",
+			files: map[string]string{
+				"undeclared_reference/main.hcl": `output "host" {
+  value = var.missing
+}`,
+			},
+			wantErrors: true,
+		},
+	}
+
+	logging.SetLogger(false)
+	for _, test := range tests {
+		appFs := afero.NewMemMapFs()
+		for name, src := range test.files {
+			if err := afero.WriteFile(appFs, name, []byte(src), 0644); err != nil {
+				t.Fatalf("Couldn't write test file: %s", err)
+			}
+		}
+		mod, diags := decodeFolder(test.folder, appFs)
+		dm, decodeDiags := mod.decode(&DecodeOptions{}, 0, test.folder, "", "", nil, &hcl.EvalContext{}, appFs)
+		diags = append(diags, decodeDiags...)
+		if diags.HasErrors() && !test.wantErrors {
+			t.Errorf("Don't want errors but received: %s", diags.Errs())
+		} else if !diags.HasErrors() && test.wantErrors {
+			t.Errorf("Want errors but did not receive any: %s", test.folder)
+		} else if !test.wantErrors {
+			if len(dm.Outputs) != len(test.want) {
+				t.Errorf("Expected %d outputs got %d", len(test.want), len(dm.Outputs))
+			}
+			for name, value := range test.want {
+				output, exists := dm.Outputs[name]
+				if !exists {
+					t.Errorf("Output %s was not decoded", name)
+					continue
+				}
+				if !output.Value.RawEquals(value) {
+					t.Errorf("Expected output %s to be %#v got %#v", name, value, output.Value)
+				}
+				if output.Sensitive != test.sensitive[name] {
+					t.Errorf("Expected output %s sensitive to be %t", name, test.sensitive[name])
+				}
+			}
+			if dm.Outputs["host"].Description != "Ingress host" {
+				t.Errorf("Expected description of output host got: %s", dm.Outputs["host"].Description)
+			}
+		}
+	}
+}
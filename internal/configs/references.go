@@ -0,0 +1,319 @@
+package configs
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"kubehcl.sh/kubehcl/internal/decode"
+)
+
+// Returns the traversals of every expression in the body including nested blocks
+// depends_on is skipped since it lists dependencies and not values
+func bodyTraversals(body hcl.Body) []hcl.Traversal {
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return nil
+	}
+	var traversals []hcl.Traversal
+	for _, attr := range syntaxBody.Attributes {
+		if attr.Name == "depends_on" {
+			continue
+		}
+		traversals = append(traversals, attr.Expr.Variables()...)
+	}
+	for _, block := range syntaxBody.Blocks {
+		traversals = append(traversals, bodyTraversals(block.Body)...)
+	}
+	return traversals
+}
+
+// Returns the traversals of the expressions which may be nil
+func exprTraversals(exprs ...hcl.Expression) []hcl.Traversal {
+	var traversals []hcl.Traversal
+	for _, expr := range exprs {
+		if expr != nil {
+			traversals = append(traversals, expr.Variables()...)
+		}
+	}
+	return traversals
+}
+
+// Returns the name of the object referenced by a traversal such as module.<name> or local.<name>
+func referencedName(traversal hcl.Traversal, rootName string) (string, bool) {
+	if len(traversal) < 2 || traversal.RootName() != rootName {
+		return "", false
+	}
+	attr, ok := traversal[1].(hcl.TraverseAttr)
+	if !ok {
+		return "", false
+	}
+	return attr.Name, true
+}
+
+// Returns the modules referenced by the traversals shortened to module.<name>
+// Each module is returned once in the order it was first referenced
+func moduleReferences(traversals []hcl.Traversal) []hcl.Traversal {
+	return shortReferences(traversals, ModuleType)
+}
+
+// Returns the resources referenced by the traversals shortened to kube_resource.<name>
+func resourceReferences(traversals []hcl.Traversal) []hcl.Traversal {
+	return shortReferences(traversals, ResourceType)
+}
+
+// Returns the traversals starting with rootName shortened to <rootName>.<name>
+// Each name is returned once in the order it was first referenced
+func shortReferences(traversals []hcl.Traversal, rootName string) []hcl.Traversal {
+	var names []string
+	var references []hcl.Traversal
+	for _, traversal := range traversals {
+		name, ok := referencedName(traversal, rootName)
+		if !ok || slices.Contains(names, name) {
+			continue
+		}
+		names = append(names, name)
+		references = append(references, traversal[:2])
+	}
+	return references
+}
+
+// Returns the names of the locals referenced by the traversals
+func localReferences(traversals []hcl.Traversal) []string {
+	var names []string
+	for _, traversal := range traversals {
+		if name, ok := referencedName(traversal, "local"); ok && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Returns the modules referenced by the traversals directly or through locals which reference modules
+func expandReferences(traversals []hcl.Traversal, localModules map[string][]hcl.Traversal) []hcl.Traversal {
+	for _, name := range localReferences(traversals) {
+		traversals = append(traversals, localModules[name]...)
+	}
+	return moduleReferences(traversals)
+}
+
+// Returns the names of the objects referenced with rootName
+func referenceNames(references []hcl.Traversal, rootName string) []string {
+	var names []string
+	for _, reference := range references {
+		if name, ok := referencedName(reference, rootName); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Orders the items so each item comes after the items it references
+// Items keep their declaration order when they don't reference each other
+// Returns the indexes of the ordered items and the names of the items which reference each other in a cycle
+func orderByReferences(names []string, references [][]string) ([]int, []string) {
+	var ordered []int
+	done := make([]bool, len(names))
+	doneNames := make(map[string]bool)
+
+	for len(ordered) < len(names) {
+		progress := false
+		for i, name := range names {
+			if done[i] {
+				continue
+			}
+			ready := true
+			for _, reference := range references[i] {
+				// References to undeclared items are reported when the expressions are evaluated
+				if slices.Contains(names, reference) && reference != name && !doneNames[reference] {
+					ready = false
+				}
+			}
+			if ready {
+				ordered = append(ordered, i)
+				done[i] = true
+				doneNames[name] = true
+				progress = true
+			}
+		}
+		if !progress {
+			var cycle []string
+			for i, name := range names {
+				if !done[i] {
+					cycle = append(cycle, name)
+				}
+			}
+			return ordered, cycle
+		}
+	}
+	return ordered, nil
+}
+
+// Orders the child modules so each module comes after the modules its arguments reference
+func orderModules(modules ModuleList) (ModuleList, hcl.Diagnostics) {
+	names := make([]string, len(modules))
+	references := make([][]string, len(modules))
+	for i, module := range modules {
+		names[i] = module.Name
+		references[i] = referenceNames(module.References, ModuleType)
+	}
+	order, cycle := orderByReferences(names, references)
+	var ordered ModuleList
+	for _, i := range order {
+		ordered = append(ordered, modules[i])
+	}
+	if len(cycle) > 0 {
+		for i := range cycle {
+			cycle[i] = ModuleType + "." + cycle[i]
+		}
+		return ordered, hcl.Diagnostics{
+			&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Circular module references",
+				Detail:   fmt.Sprintf("Modules %s reference the outputs of each other", strings.Join(cycle, ", ")),
+			},
+		}
+	}
+	return ordered, hcl.Diagnostics{}
+}
+
+// Decodes the pending locals whose referenced modules were all decoded and adds them to the decoded locals
+// Returns the locals which still wait for other modules
+func decodeReadyLocals(pending Locals, localModules map[string][]hcl.Traversal, moduleValues map[string]cty.Value, decoded decode.DecodedLocalsMap, ctx *hcl.EvalContext) (Locals, hcl.Diagnostics) {
+	var ready, waiting Locals
+	for _, local := range pending {
+		isReady := true
+		for _, name := range referenceNames(localModules[local.Name], ModuleType) {
+			if _, exists := moduleValues[name]; !exists {
+				isReady = false
+			}
+		}
+		if isReady {
+			ready = append(ready, local)
+		} else {
+			waiting = append(waiting, local)
+		}
+	}
+	decodedLocals, diags := ready.Decode(ctx)
+	for name, local := range decodedLocals {
+		if _, exists := decoded[name]; exists {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Resource exists more than once",
+				Detail:   fmt.Sprintf("Resource was already declared %s", name),
+				Subject:  &local.DeclRange,
+			})
+		}
+		decoded[name] = local
+	}
+	return waiting, diags
+}
+
+// Returns the outputs of a module as an object
+func outputsValue(outputs decode.DecodedOutputMap) cty.Value {
+	values := make(map[string]cty.Value)
+	for name, output := range outputs {
+		values[name] = output.Value
+	}
+	return cty.ObjectVal(values)
+}
+
+// Returns the values of the child modules as an object
+func modulesValue(modules decode.DecodedModuleMap) cty.Value {
+	values := make(map[string]cty.Value)
+	for name, module := range modules {
+		values[name] = module.Value
+	}
+	return cty.ObjectVal(values)
+}
+
+// Returns the value of the decoded module as referenced by the caller
+// A single module is an object of its outputs, count creates a list and for_each a map of the outputs of each instance
+func (m *Module) value(dm *decode.DecodedModule, ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	if m.Count == nil && m.ForEach == nil {
+		return outputsValue(dm.Outputs), hcl.Diagnostics{}
+	}
+
+	var diags hcl.Diagnostics
+	instanceCtx := &hcl.EvalContext{
+		Variables: make(map[string]cty.Value),
+		Functions: ctx.Functions,
+	}
+	for key, val := range ctx.Variables {
+		instanceCtx.Variables[key] = val
+	}
+	modules := modulesValue(dm.Modules)
+	data := dataValue(dm.Data)
+
+	// Invalid count and for_each values are reported when the module call is decoded
+	if m.Count != nil {
+		count, _ := m.Count.Value(ctx)
+		count, err := convert.Convert(count, cty.Number)
+		if err == nil {
+			count, _ = count.UnmarkDeep()
+		}
+		if err != nil || count.IsNull() || !count.IsKnown() {
+			return cty.DynamicVal, diags
+		}
+		var instances []cty.Value
+		for i := cty.NumberIntVal(1); i.LessThanOrEqualTo(count) == cty.True; i = i.Add(cty.NumberIntVal(1)) {
+			instanceCtx.Variables["count"] = cty.ObjectVal(map[string]cty.Value{"index": i})
+			instance, instanceDiags := m.instanceValue(instanceCtx, modules, data)
+			diags = append(diags, instanceDiags...)
+			instances = append(instances, instance)
+		}
+		if len(instances) == 0 {
+			return cty.EmptyTupleVal, diags
+		}
+		return cty.TupleVal(instances), diags
+	}
+
+	forEach, _ := m.ForEach.Value(ctx)
+	forEach, _ = forEach.UnmarkDeep()
+	if forEach.IsNull() || !forEach.IsKnown() {
+		return cty.DynamicVal, diags
+	}
+	instances := make(map[string]cty.Value)
+	ty := forEach.Type()
+	if ty.IsMapType() || ty.IsObjectType() {
+		for key, val := range forEach.AsValueMap() {
+			instanceCtx.Variables["each"] = cty.ObjectVal(map[string]cty.Value{"key": cty.StringVal(key), "value": val})
+			instance, instanceDiags := m.instanceValue(instanceCtx, modules, data)
+			diags = append(diags, instanceDiags...)
+			instances[key] = instance
+		}
+	} else if ty.IsSetType() && ty.ElementType() == cty.String {
+		for _, val := range forEach.AsValueSet().Values() {
+			instanceCtx.Variables["each"] = cty.ObjectVal(map[string]cty.Value{"key": val, "value": val})
+			instance, instanceDiags := m.instanceValue(instanceCtx, modules, data)
+			diags = append(diags, instanceDiags...)
+			instances[val.AsString()] = instance
+		}
+	}
+	return cty.ObjectVal(instances), diags
+}
+
+// Evaluates the outputs of a single instance of a module created with count or for_each
+// The inputs, locals and outputs of the module are evaluated again with count or each set for the instance
+func (m *Module) instanceValue(ctx *hcl.EvalContext, modules cty.Value, data cty.Value) (cty.Value, hcl.Diagnostics) {
+	variables, diags := m.Inputs.Decode(ctx, m.scope)
+	moduleCtx, ctxDiags := decode.CreateContext(variables, decode.DecodedLocalsMap{}, m.scope)
+	diags = append(diags, ctxDiags...)
+	moduleCtx.Variables[ModuleType] = modules
+	moduleCtx.Variables[DataType] = data
+
+	locals, localsDiags := m.Locals.Decode(moduleCtx)
+	diags = append(diags, localsDiags...)
+	moduleCtx, ctxDiags = decode.CreateContext(variables, locals, m.scope)
+	diags = append(diags, ctxDiags...)
+	moduleCtx.Variables[ModuleType] = modules
+	moduleCtx.Variables[DataType] = data
+
+	outputs, outputsDiags := m.Outputs.Decode(moduleCtx)
+	diags = append(diags, outputsDiags...)
+	return outputsValue(outputs), diags
+}
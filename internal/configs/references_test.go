@@ -0,0 +1,363 @@
+package configs
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/spf13/afero"
+	"github.com/zclconf/go-cty/cty"
+	"kubehcl.sh/kubehcl/internal/decode"
+	"kubehcl.sh/kubehcl/internal/logging"
+)
+
+func Test_ModuleReferences(t *testing.T) {
+	tests := []struct {
+		folder     string
+		files      map[string]string
+		locals     map[string]cty.Value
+		values     map[string]cty.Value
+		edges      map[string][]string
+		wantErrors bool
+	}{
+		{
+			folder: "module_outputs",
+			files: map[string]string{
+				"module_outputs/main.hcl": `module "config" {
+  source = "./config"
+  name   = module.base.name
+}
+module "base" {
+  source = "./base"
+}
+locals {
+  name = module.config.name
+}
+kube_resource "namespace" {
+  apiVersion = "v1"
+  kind       = "Namespace"
+  metadata = {
+    name = local.name
+  }
+}`,
+				"module_outputs/base/main.hcl": `output "name" {
+  value = "base"
+}`,
+				"module_outputs/config/main.hcl": `variable "name" {}
+kube_resource "config" {
+  apiVersion = "v1"
+  kind       = "ConfigMap"
+  metadata = {
+    name = var.name
+  }
+}
+output "name" {
+  value = "${var.name}-config"
+}`,
+			},
+			locals: map[string]cty.Value{"name": cty.StringVal("base-config")},
+			values: map[string]cty.Value{
+				"base":   cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("base")}),
+				"config": cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("base-config")}),
+			},
+			edges: map[string][]string{
+				"kube_resource.namespace": {"module.config.kube_resource.config"},
+			},
+		},
+		{
+			folder: "module_instances",
+			files: map[string]string{
+				"module_instances/main.hcl": `module "counted" {
+  source = "./instance"
+  count  = 2
+  name   = "counted-${count.index}"
+}
+module "each" {
+  source   = "./instance"
+  for_each = { a = "first", b = "second" }
+  name     = each.value
+}
+locals {
+  first  = module.counted[0].name
+  second = module.each["b"].name
+}`,
+				"module_instances/instance/main.hcl": `variable "name" {
+  default = "instance"
+}
+output "name" {
+  value = var.name
+}`,
+			},
+			locals: map[string]cty.Value{
+				"first":  cty.StringVal("counted-1"),
+				"second": cty.StringVal("second"),
+			},
+			values: map[string]cty.Value{
+				"counted": cty.TupleVal([]cty.Value{
+					cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("counted-1")}),
+					cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("counted-2")}),
+				}),
+				"each": cty.ObjectVal(map[string]cty.Value{
+					"a": cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("first")}),
+					"b": cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("second")}),
+				}),
+			},
+		},
+		{
+			folder: "module_cycle",
+			files: map[string]string{
+				"module_cycle/main.hcl": `module "a" {
+  source = "./child"
+  name   = module.b.name
+}
+module "b" {
+  source = "./child"
+  name   = module.a.name
+}`,
+				"module_cycle/child/main.hcl": `variable "name" {}
+output "name" {
+  value = var.name
+}`,
+			},
+			wantErrors: true,
+		},
+		{
+			folder: "undeclared_output",
+			files: map[string]string{
+				"undeclared_output/main.hcl": `module "base" {
+  source = "./base"
+}
+locals {
+  name = module.base.missing
+}`,
+				"undeclared_output/base/main.hcl": `output "name" {
+  value = "base"
+}`,
+			},
+			wantErrors: true,
+		},
+	}
+
+	logging.SetLogger(false)
+	for _, test := range tests {
+		appFs := afero.NewMemMapFs()
+		for name, src := range test.files {
+			if err := afero.WriteFile(appFs, name, []byte(src), 0644); err != nil {
+				t.Fatalf("Couldn't write test file: %s", err)
+			}
+		}
+		mod, diags := decodeFolder(test.folder, appFs)
+		dm, decodeDiags := mod.decode(&DecodeOptions{}, 0, test.folder, "", "", nil, &hcl.EvalContext{}, appFs)
+		diags = append(diags, decodeDiags...)
+		if diags.HasErrors() && !test.wantErrors {
+			t.Errorf("Don't want errors but received: %s", diags.Errs())
+			continue
+		} else if !diags.HasErrors() && test.wantErrors {
+			t.Errorf("Want errors but did not receive any: %s", test.folder)
+			continue
+		} else if test.wantErrors {
+			continue
+		}
+
+		for name, value := range test.locals {
+			if local, exists := dm.Locals[name]; !exists || !local.Value.RawEquals(value) {
+				t.Errorf("Expected local %s to be %#v", name, value)
+			}
+		}
+		for name, value := range test.values {
+			if module, exists := dm.Modules[name]; !exists || !module.Value.RawEquals(value) {
+				t.Errorf("Expected module %s to be %#v", name, value)
+			}
+		}
+
+		g := &Graph{DecodedModule: dm, DisableKindOrdering: true}
+		if graphDiags := g.Init(); graphDiags.HasErrors() {
+			t.Errorf("Don't want graph errors but received: %s", graphDiags.Errs())
+			continue
+		}
+		for from, targets := range test.edges {
+			for _, to := range targets {
+				found := false
+				for _, v := range g.Vertices() {
+					r, ok := v.(*decode.DecodedResource)
+					if !ok || r.Name != from {
+						continue
+					}
+					for _, down := range g.DownEdges(r).List() {
+						if dr, ok := down.(*decode.DecodedResource); ok && dr.Name == to {
+							found = true
+						}
+					}
+				}
+				if !found {
+					t.Errorf("Expected %s to depend on %s", from, to)
+				}
+			}
+		}
+	}
+}
+
+func Test_ResourceReferences(t *testing.T) {
+	tests := []struct {
+		folder string
+		src    string
+		// instance is the instance of the deployment which is checked, kube_resource.deployment by default
+		instance   string
+		want       map[string]cty.Value
+		edges      []string
+		wantErrors bool
+	}{
+		{
+			folder: "resource_references",
+			src: `kube_resource "deployment" {
+  apiVersion = "apps/v1"
+  kind       = "Deployment"
+  metadata = {
+    name = "web"
+  }
+  spec = {
+    serviceName = kube_resource.svc.metadata.name
+    config      = kube_resource.cm["a"].data.key
+    first       = kube_resource.counted[1].metadata.name
+  }
+}
+kube_resource "svc" {
+  apiVersion = "v1"
+  kind       = "Service"
+  metadata = {
+    name = "web-svc"
+  }
+}
+kube_resource "cm" {
+  for_each   = { a = "first" }
+  apiVersion = "v1"
+  kind       = "ConfigMap"
+  metadata = {
+    name = each.key
+  }
+  data = {
+    key = each.value
+  }
+}
+kube_resource "counted" {
+  count      = 2
+  apiVersion = "v1"
+  kind       = "ConfigMap"
+  metadata = {
+    name = "counted-${count.index}"
+  }
+}`,
+			want: map[string]cty.Value{
+				"serviceName": cty.StringVal("web-svc"),
+				"config":      cty.StringVal("first"),
+				"first":       cty.StringVal("counted-1"),
+			},
+			edges: []string{"kube_resource.svc", "kube_resource.cm", "kube_resource.counted"},
+		},
+		{
+			// Counted instances are referenced by count.index as they are saved in the state
+			folder: "count_references",
+			src: `kube_resource "deployment" {
+  count      = 2
+  apiVersion = "apps/v1"
+  kind       = "Deployment"
+  metadata = {
+    name = "web-${count.index}"
+  }
+  spec = {
+    config = kube_resource.counted[count.index].metadata.name
+  }
+}
+kube_resource "counted" {
+  count      = 2
+  apiVersion = "v1"
+  kind       = "ConfigMap"
+  metadata = {
+    name = "counted-${count.index}"
+  }
+}`,
+			instance: "kube_resource.deployment[2]",
+			want: map[string]cty.Value{
+				"config": cty.StringVal("counted-2"),
+			},
+			edges: []string{"kube_resource.counted"},
+		},
+		{
+			folder: "resource_cycle",
+			src: `kube_resource "a" {
+  apiVersion = "v1"
+  kind       = "ConfigMap"
+  metadata = {
+    name = kube_resource.b.metadata.name
+  }
+}
+kube_resource "b" {
+  apiVersion = "v1"
+  kind       = "ConfigMap"
+  metadata = {
+    name = kube_resource.a.metadata.name
+  }
+}`,
+			wantErrors: true,
+		},
+		{
+			folder: "resource_missing",
+			src: `kube_resource "a" {
+  apiVersion = "v1"
+  kind       = "ConfigMap"
+  metadata = {
+    name = kube_resource.missing.metadata.name
+  }
+}`,
+			wantErrors: true,
+		},
+	}
+
+	logging.SetLogger(false)
+	for _, test := range tests {
+		appFs := afero.NewMemMapFs()
+		if err := afero.WriteFile(appFs, test.folder+"/main.hcl", []byte(test.src), 0644); err != nil {
+			t.Fatalf("Couldn't write test file: %s", err)
+		}
+		mod, diags := decodeFolder(test.folder, appFs)
+		dm, decodeDiags := mod.decode(&DecodeOptions{}, 0, test.folder, "", "", nil, &hcl.EvalContext{}, appFs)
+		diags = append(diags, decodeDiags...)
+		if diags.HasErrors() && !test.wantErrors {
+			t.Errorf("Don't want errors but received: %s", diags.Errs())
+			continue
+		} else if !diags.HasErrors() && test.wantErrors {
+			t.Errorf("Want errors but did not receive any: %s", test.folder)
+			continue
+		} else if test.wantErrors {
+			continue
+		}
+
+		instance := test.instance
+		if instance == "" {
+			instance = "kube_resource.deployment"
+		}
+		spec := dm.Resources["deployment"].Config[instance].GetAttr("spec")
+		for name, value := range test.want {
+			if !spec.GetAttr(name).RawEquals(value) {
+				t.Errorf("Expected %s to be %#v got %#v", name, value, spec.GetAttr(name))
+			}
+		}
+
+		g := &Graph{DecodedModule: dm, DisableKindOrdering: true}
+		if graphDiags := g.Init(); graphDiags.HasErrors() {
+			t.Errorf("Don't want graph errors but received: %s", graphDiags.Errs())
+			continue
+		}
+		deployment := dm.Resources["deployment"]
+		for _, to := range test.edges {
+			found := false
+			for _, down := range g.DownEdges(deployment).List() {
+				if dr, ok := down.(*decode.DecodedResource); ok && dr.Name == to {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Expected %s to depend on %s", deployment.Name, to)
+			}
+		}
+	}
+}
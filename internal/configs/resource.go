@@ -10,8 +10,11 @@ package configs
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
 	"kubehcl.sh/kubehcl/internal/addrs"
 	"kubehcl.sh/kubehcl/internal/decode"
 	// "kubehcl.sh/kubehcl/internal/dag"
@@ -35,15 +38,41 @@ func decodeDependsOn(attr *hcl.Attribute) ([]hcl.Traversal, hcl.Diagnostics) {
 
 type Resource struct {
 	decode.Deployable
+	Lifecycle decode.Lifecycle
 }
 
 type ResourceList []*Resource
 
 // Decode multiple resources into decoded resource list
+// Resources are decoded after the resources they reference and can access their config as kube_resource.<name>
+// The config of every resource is kept in the context as kube_resource for the outputs and checks of the module
 func (r ResourceList) Decode(ctx *hcl.EvalContext) (decode.DecodedResourceMap, hcl.Diagnostics) {
 	dR := make(decode.DecodedResourceMap)
 	var diags hcl.Diagnostics
-	for _, variable := range r {
+
+	names := make([]string, len(r))
+	references := make([][]string, len(r))
+	for i, resource := range r {
+		names[i] = resource.Name
+		references[i] = referenceNames(resourceReferences(resource.traversals()), ResourceType)
+	}
+	order, cycle := orderByReferences(names, references)
+	if len(cycle) > 0 {
+		for i := range cycle {
+			cycle[i] = ResourceType + "." + cycle[i]
+		}
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Circular resource references",
+			Detail:   fmt.Sprintf("Resources %s reference each other", strings.Join(cycle, ", ")),
+		})
+		return dR, diags
+	}
+
+	values := make(map[string]cty.Value)
+	for _, i := range order {
+		variable := r[i]
+		ctx.Variables[ResourceType] = cty.ObjectVal(values)
 		dV, varDiags := variable.decode(ctx)
 		diags = append(diags, varDiags...)
 		if _, ok := dR[dV.Name]; ok {
@@ -55,15 +84,40 @@ func (r ResourceList) Decode(ctx *hcl.EvalContext) (decode.DecodedResourceMap, h
 			})
 		}
 		dR[dV.Name] = dV
+		values[dV.Name] = cty.DynamicVal
+		if !varDiags.HasErrors() {
+			values[dV.Name] = variable.value(dV)
+		}
 	}
+	ctx.Variables[ResourceType] = cty.ObjectVal(values)
 
 	return dR, diags
 }
 
+// Returns the traversals of the expressions of the resource
+// Must be called before the resource is decoded since decoding removes the meta attributes from the body
+func (r *Resource) traversals() []hcl.Traversal {
+	return append(bodyTraversals(r.Config), exprTraversals(r.Count, r.ForEach)...)
+}
+
+// Returns the rendered config of the resource as referenced by other resources
+// A single resource is an object, count and for_each create an object of the config of each instance keyed by count.index or each.key
+func (r *Resource) value(dR *decode.DecodedResource) cty.Value {
+	addr := dR.Addr().String()
+	if r.Count != nil || r.ForEach != nil {
+		instances := make(map[string]cty.Value)
+		for key := range dR.Config {
+			instances[strings.TrimSuffix(strings.TrimPrefix(key, addr+"["), "]")] = dR.MarkedConfig(key)
+		}
+		return cty.ObjectVal(instances)
+	}
+	return dR.MarkedConfig(addr)
+}
+
 // Decode the deployable of the resource
 func (r *Resource) decode(ctx *hcl.EvalContext) (*decode.DecodedResource, hcl.Diagnostics) {
 	deployable, diags := r.Decode(ctx)
-	res := &decode.DecodedResource{DecodedDeployable: *deployable}
+	res := &decode.DecodedResource{DecodedDeployable: *deployable, Lifecycle: r.Lifecycle}
 
 	return res, diags
 }
@@ -79,12 +133,63 @@ var inputResourceBlockSchema = &hcl.BodySchema{
 		{
 			Name: "depends_on",
 		},
+		{
+			Name: "cluster",
+		},
+	},
+	Blocks: []hcl.BlockHeaderSchema{
+		{
+			Type: "lifecycle",
+		},
+	},
+}
+
+var lifecycleBlockSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{
+			Name: "recreate_on_immutable",
+		},
+	},
+	Blocks: []hcl.BlockHeaderSchema{
+		{
+			Type: "precondition",
+		},
+		{
+			Type: "postcondition",
+		},
 	},
-	Blocks: []hcl.BlockHeaderSchema{},
+}
+
+// Decode lifecycle block
+// The lifecycle arguments must be known before the resource is decoded thus they can only contain constant values
+// Preconditions and postconditions are added to the deployable of the resource
+func decodeLifecycleBlock(block *hcl.Block, deployable *decode.Deployable) (decode.Lifecycle, hcl.Diagnostics) {
+	lifecycle := decode.Lifecycle{DeclRange: block.DefRange}
+	content, diags := block.Body.Content(lifecycleBlockSchema)
+	diags = append(diags, decodeLifecycleConditions(content, deployable)...)
+	if attr, exists := content.Attributes["recreate_on_immutable"]; exists {
+		value, valDiags := attr.Expr.Value(nil)
+		diags = append(diags, valDiags...)
+		if valDiags.HasErrors() {
+			return lifecycle, diags
+		}
+		value, err := convert.Convert(value, cty.Bool)
+		if err != nil || value.IsNull() {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid recreate_on_immutable value",
+				Detail:   "The recreate_on_immutable argument must be true or false",
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+			return lifecycle, diags
+		}
+		lifecycle.RecreateOnImmutable = value.True()
+	}
+	return lifecycle, diags
 }
 
 // Decode resource block
-// Resource block can contain for_each or count and depends on
+// Resource block can contain for_each or count, depends on and the cluster it is deployed to
 // This language is used as a template language thus not limited to what you can put into a resource block
 func decodeResourceBlock(block *hcl.Block) (*Resource, hcl.Diagnostics) {
 	var resource = &Resource{
@@ -121,6 +226,28 @@ func decodeResourceBlock(block *hcl.Block) (*Resource, hcl.Diagnostics) {
 		resource.DependsOn = append(resource.DependsOn, traversal...)
 	}
 
+	if attr, exists := content.Attributes["cluster"]; exists {
+		cluster, clusterDiags := decodeClusterAttr(attr)
+		diags = append(diags, clusterDiags...)
+		resource.Cluster = cluster
+	}
+
+	for i, lifecycleBlock := range content.Blocks.OfType("lifecycle") {
+		if i > 0 {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate lifecycle block",
+				Detail:   fmt.Sprintf("Resource %s can contain only one lifecycle block", resource.Name),
+				Subject:  &lifecycleBlock.DefRange,
+				Context:  &resource.Lifecycle.DeclRange,
+			})
+			continue
+		}
+		lifecycle, lifecycleDiags := decodeLifecycleBlock(lifecycleBlock, &resource.Deployable)
+		diags = append(diags, lifecycleDiags...)
+		resource.Lifecycle = lifecycle
+	}
+
 	return resource, diags
 
 }
@@ -121,3 +121,82 @@ func Test_Resource(t *testing.T) {
 		}
 	}
 }
+
+func Test_ResourceLifecycle(t *testing.T) {
+	tests := []struct {
+		src        string
+		want       bool
+		wantErrors bool
+	}{
+		{
+			src: `kube_resource "foo" {
+  kind = "Job"
+  lifecycle {
+    recreate_on_immutable = true
+  }
+}`,
+			want: true,
+		},
+		{
+			src: `kube_resource "foo" {
+  kind = "Job"
+}`,
+			want: false,
+		},
+		{
+			src: `kube_resource "foo" {
+  kind = "Job"
+  lifecycle {
+    recreate_on_immutable = "maybe"
+  }
+}`,
+			wantErrors: true,
+		},
+		{
+			src: `kube_resource "foo" {
+  kind = "Job"
+  lifecycle {
+    unknown = true
+  }
+}`,
+			wantErrors: true,
+		},
+		{
+			src: `kube_resource "foo" {
+  kind = "Job"
+  lifecycle {}
+  lifecycle {}
+}`,
+			wantErrors: true,
+		},
+	}
+
+	for _, test := range tests {
+		file, diags := hclsyntax.ParseConfig([]byte(test.src), "test.hcl", hcl.InitialPos)
+		if diags.HasErrors() {
+			t.Fatalf("Couldn't parse test configuration: %s", diags.Errs())
+		}
+		content, _ := file.Body.Content(&hcl.BodySchema{Blocks: []hcl.BlockHeaderSchema{{Type: "kube_resource", LabelNames: []string{"name"}}}})
+		resources, diags := DecodeResourceBlocks(content.Blocks, addrs.AddressMap{})
+		if diags.HasErrors() && !test.wantErrors {
+			t.Errorf("Don't want errors but received: %s", diags.Errs())
+		} else if !diags.HasErrors() && test.wantErrors {
+			t.Errorf("Want errors but did not receive any")
+		} else if !test.wantErrors {
+			if resources[0].Lifecycle.RecreateOnImmutable != test.want {
+				t.Errorf("Expected recreate_on_immutable to be %t got: %t", test.want, resources[0].Lifecycle.RecreateOnImmutable)
+			}
+
+			decoded, decodeDiags := resources[0].decode(&hcl.EvalContext{Variables: map[string]cty.Value{}})
+			if decodeDiags.HasErrors() {
+				t.Errorf("Don't want errors but received: %s", decodeDiags.Errs())
+			}
+			if decoded.Config["kube_resource.foo"].Type().HasAttribute("lifecycle") {
+				t.Errorf("Lifecycle block should not be a part of the resource")
+			}
+			if decoded.Lifecycle.RecreateOnImmutable != test.want {
+				t.Errorf("Expected decoded recreate_on_immutable to be %t got: %t", test.want, decoded.Lifecycle.RecreateOnImmutable)
+			}
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package configs
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/spf13/afero"
+	"github.com/zclconf/go-cty/cty"
+	"kubehcl.sh/kubehcl/internal/decode"
+	"kubehcl.sh/kubehcl/internal/logging"
+)
+
+func Test_Scope(t *testing.T) {
+	files := map[string]string{
+		"scope/main.hcl": `locals {
+  config = file("config.yaml")
+  module = path.module
+  root   = path.root
+}
+module "child" {
+  source = "./child"
+}`,
+		"scope/config.yaml": "root",
+		"scope/child/main.hcl": `variable "name" {
+  default = "child"
+  validation {
+    condition     = fileexists("${var.name}.tpl")
+    error_message = "The template of the variable doesn't exist"
+  }
+}
+output "config" {
+  value = file("config.yaml")
+}
+output "files" {
+  value = fileset("templates", "*.tpl")
+}
+output "template" {
+  value = templatefile("${var.name}.tpl", { name = var.name })
+}
+output "module" {
+  value = path.module
+}
+output "root" {
+  value = path.root
+}`,
+		"scope/child/config.yaml":       "child",
+		"scope/child/child.tpl":         "hello ${name}",
+		"scope/child/templates/a.tpl":   "a",
+		"scope/child/templates/b.tpl":   "b",
+		"scope/child/templates/c.other": "c",
+	}
+
+	logging.SetLogger(false)
+	appFs := afero.NewMemMapFs()
+	for name, src := range files {
+		if err := afero.WriteFile(appFs, name, []byte(src), 0644); err != nil {
+			t.Fatalf("Couldn't write test file: %s", err)
+		}
+	}
+	mod, diags := decodeFolder("scope", appFs)
+	dm, decodeDiags := mod.decode(&DecodeOptions{}, 0, "scope", "", "", nil, &hcl.EvalContext{}, appFs)
+	diags = append(diags, decodeDiags...)
+	if diags.HasErrors() {
+		t.Fatalf("Don't want errors but received: %s", diags.Errs())
+	}
+
+	wantLocals := map[string]cty.Value{
+		"config": cty.StringVal("root"),
+		"module": cty.StringVal("scope"),
+		"root":   cty.StringVal("scope"),
+	}
+	for name, want := range wantLocals {
+		if got := dm.Locals[name].Value; !got.RawEquals(want) {
+			t.Errorf("Expected local %s to be %#v got %#v", name, want, got)
+		}
+	}
+
+	wantOutputs := map[string]cty.Value{
+		"config":   cty.StringVal("child"),
+		"files":    cty.SetVal([]cty.Value{cty.StringVal("a.tpl"), cty.StringVal("b.tpl")}),
+		"template": cty.StringVal("hello child"),
+		"module":   cty.StringVal("scope/child"),
+		"root":     cty.StringVal("scope"),
+	}
+	outputs := dm.Modules["child"].Value.AsValueMap()
+	for name, want := range wantOutputs {
+		if got := outputs[name]; !got.RawEquals(want) {
+			t.Errorf("Expected output %s to be %#v got %#v", name, want, got)
+		}
+	}
+}
+
+// stubClusterReader returns the capabilities of each cluster by its context and counts the reads
+type stubClusterReader struct {
+	capabilities map[string]*decode.Capabilities
+	reads        int
+}
+
+func (r *stubClusterReader) Capabilities(cluster *decode.DecodedCluster) (*decode.Capabilities, hcl.Diagnostics) {
+	r.reads++
+	return r.capabilities[cluster.Context], nil
+}
+
+func Test_Release(t *testing.T) {
+	files := map[string]string{
+		"release/main.hcl": `cluster "edge" {
+  context = "edge-context"
+}
+module "child" {
+  source = "./child"
+}
+module "edge" {
+  source  = "./child"
+  cluster = cluster.edge
+}
+module "other_edge" {
+  source  = "./child"
+  cluster = cluster.edge
+}
+output "release" {
+  value = release
+}
+output "version" {
+  value = cluster.version
+}`,
+		"release/child/main.hcl": `kube_resource "budget" {
+  apiVersion = contains(cluster.api_versions, "policy/v1") ? "policy/v1" : "policy/v1beta1"
+  kind       = "PodDisruptionBudget"
+  metadata = {
+    name = "${release.name}-budget"
+    labels = {
+      revision = release.revision
+      upgrade  = release.is_upgrade
+      version  = cluster.version
+    }
+  }
+}`,
+	}
+
+	logging.SetLogger(false)
+	appFs := afero.NewMemMapFs()
+	for name, src := range files {
+		if err := afero.WriteFile(appFs, name, []byte(src), 0644); err != nil {
+			t.Fatalf("Couldn't write test file: %s", err)
+		}
+	}
+	reader := &stubClusterReader{capabilities: map[string]*decode.Capabilities{
+		"edge-context": {KubeVersion: "v1.20.0", APIVersions: []string{"v1", "policy/v1beta1"}},
+	}}
+	opts := &DecodeOptions{
+		Release: &decode.Release{
+			Name:      "web",
+			Namespace: "apps",
+			Revision:  3,
+			IsUpgrade: true,
+			Capabilities: decode.Capabilities{
+				KubeVersion: "v1.30.2",
+				APIVersions: []string{"v1", "policy/v1", "policy/v1/PodDisruptionBudget"},
+			},
+		},
+		ClusterReader: reader,
+	}
+	mod, diags := decodeFolder("release", appFs)
+	dm, decodeDiags := mod.decode(opts, 0, "release", "", "", nil, &hcl.EvalContext{}, appFs)
+	diags = append(diags, decodeDiags...)
+	if diags.HasErrors() {
+		t.Fatalf("Don't want errors but received: %s", diags.Errs())
+	}
+
+	wantRelease := cty.ObjectVal(map[string]cty.Value{
+		"name":       cty.StringVal("web"),
+		"namespace":  cty.StringVal("apps"),
+		"revision":   cty.NumberIntVal(3),
+		"is_install": cty.False,
+		"is_upgrade": cty.True,
+	})
+	if got := dm.Outputs["release"].Value; !got.RawEquals(wantRelease) {
+		t.Errorf("Expected release to be %#v got %#v", wantRelease, got)
+	}
+	if got := dm.Outputs["version"].Value; !got.RawEquals(cty.StringVal("v1.30.2")) {
+		t.Errorf("Expected the root module to have the version of the default cluster got %#v", got)
+	}
+	if reader.reads != 1 {
+		t.Errorf("Expected the capabilities of cluster edge to be read once got %d reads", reader.reads)
+	}
+
+	// Modules deployed to a cluster alias have the capabilities of that cluster
+	tests := []struct {
+		module     string
+		apiVersion string
+		version    string
+	}{
+		{module: "child", apiVersion: "policy/v1", version: "v1.30.2"},
+		{module: "edge", apiVersion: "policy/v1beta1", version: "v1.20.0"},
+		{module: "other_edge", apiVersion: "policy/v1beta1", version: "v1.20.0"},
+	}
+	for _, test := range tests {
+		budget := dm.Modules[test.module].Resources["budget"].Config["kube_resource.budget"]
+		metadata := budget.GetAttr("metadata")
+		labels := metadata.GetAttr("labels")
+		want := map[string]cty.Value{
+			"apiVersion": cty.StringVal(test.apiVersion),
+			"name":       cty.StringVal("web-budget"),
+			"revision":   cty.NumberIntVal(3),
+			"upgrade":    cty.True,
+			"version":    cty.StringVal(test.version),
+		}
+		got := map[string]cty.Value{
+			"apiVersion": budget.GetAttr("apiVersion"),
+			"name":       metadata.GetAttr("name"),
+			"revision":   labels.GetAttr("revision"),
+			"upgrade":    labels.GetAttr("upgrade"),
+			"version":    labels.GetAttr("version"),
+		}
+		for name, want := range want {
+			if !got[name].RawEquals(want) {
+				t.Errorf("Expected %s of module %s to be %#v got %#v", name, test.module, want, got[name])
+			}
+		}
+	}
+}
@@ -18,15 +18,35 @@ type ModuleCall struct {
 type Module struct {
 	Name           string          `json:"Name"`
 	BackendStorage *BackendStorage `json:"BackendStorage"`
+	Clusters       Clusters        `json:"Clusters"`
 	Inputs         VariableMap     `json:"Inputs"`
 	Locals         Locals          `json:"Locals"`
+	Outputs        Outputs         `json:"Outputs"`
+	Data           DataList        `json:"Data"`
+	Moved          MovedList       `json:"Moved"`
+	Checks         CheckList       `json:"Checks"`
 	Annotations    Annotations     `json:"Annotations"`
 	Resources      ResourceList    `json:"Resources"`
 	ModuleCalls    ModuleCallList  `json:"ModuleCalls"`
 	DependsOn      []hcl.Traversal `json:"DependsOn"`
-	Source         string          `json:"Source"`
-	Version        string          `json:"Version"`
+	// References contains the modules referenced by the arguments of the module call
+	References []hcl.Traversal `json:"References"`
+	Count      hcl.Expression  `json:"Count"`
+	ForEach    hcl.Expression  `json:"ForEach"`
+	// Postconditions of the module call, self refers to the outputs of the module
+	Postconditions []*decode.Condition `json:"Postconditions"`
+	Cluster        string              `json:"Cluster"`
+	Source         string              `json:"Source"`
+	Version        string              `json:"Version"`
 	Scope          afero.Fs
+	// path is the address of the module such as module.foo. empty for the root module
+	path string
+	// root is the folder of the root module
+	root string
+	// scope is where the file functions of the module read from, set when the module is decoded
+	scope *decode.Scope
+	// clusters are the cluster aliases of the root module, shared by every module of the release
+	clusters *clusterCapabilities
 }
 
 type ModuleList []*Module
@@ -0,0 +1,254 @@
+package configs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"kubehcl.sh/kubehcl/internal/dag"
+	"kubehcl.sh/kubehcl/internal/decode"
+)
+
+// Kind of address a target points to
+type targetKind int
+
+const (
+	targetModule targetKind = iota
+	targetResource
+	targetInstance
+)
+
+// Target is a parsed --target address
+// Address is written the same way resource names and instance keys are written in the graph and the state
+// for example module.test, module.test.kube_resource.bar or module.test.kube_resource.bar[x]
+type Target struct {
+	Address string
+	kind    targetKind
+}
+
+type Targets []Target
+
+// Parses the target addresses such as kube_resource.foo, module.test or module.test.kube_resource.bar["x"]
+func ParseTargets(addresses []string) (Targets, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	var targets Targets
+	for _, address := range addresses {
+		target, targetDiags := parseTarget(address)
+		diags = append(diags, targetDiags...)
+		if !targetDiags.HasErrors() {
+			targets = append(targets, target)
+		}
+	}
+	return targets, diags
+}
+
+func invalidTarget(address, detail string) hcl.Diagnostics {
+	return hcl.Diagnostics{
+		&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid target",
+			Detail:   fmt.Sprintf("Target %s is invalid: %s", address, detail),
+		},
+	}
+}
+
+func parseTarget(address string) (Target, hcl.Diagnostics) {
+	traversal, diags := hclsyntax.ParseTraversalAbs([]byte(address), "<target>", hcl.InitialPos)
+	if diags.HasErrors() {
+		return Target{}, diags
+	}
+	target, detail := traversalTarget(traversal)
+	if detail != "" {
+		return Target{}, invalidTarget(address, detail)
+	}
+	return target, diags
+}
+
+// Converts a traversal such as module.test.kube_resource.bar["x"] into a target
+// Returns the reason the traversal is not a valid address, empty when it is valid
+func traversalTarget(traversal hcl.Traversal) (Target, string) {
+	var parts []string
+	var kind targetKind
+	for i := 0; i < len(traversal); i++ {
+		var step string
+		switch tt := traversal[i].(type) {
+		case hcl.TraverseRoot:
+			step = tt.Name
+		case hcl.TraverseAttr:
+			step = tt.Name
+		default:
+			return Target{}, "expected module or kube_resource"
+		}
+
+		if step != ModuleType && step != ResourceType {
+			return Target{}, fmt.Sprintf("allowed types are [%s,%s] got: %s", ResourceType, ModuleType, step)
+		}
+		if i+1 >= len(traversal) {
+			return Target{}, fmt.Sprintf("%s must be followed by a name", step)
+		}
+		name, ok := traversal[i+1].(hcl.TraverseAttr)
+		if !ok {
+			return Target{}, fmt.Sprintf("%s must be followed by a name", step)
+		}
+		parts = append(parts, step, name.Name)
+		i++
+
+		if step == ModuleType {
+			kind = targetModule
+			continue
+		}
+
+		kind = targetResource
+		if i+1 < len(traversal) {
+			index, ok := traversal[i+1].(hcl.TraverseIndex)
+			if !ok {
+				return Target{}, "a resource can only be followed by an instance key"
+			}
+			key, err := convert.Convert(index.Key, cty.String)
+			if err != nil || key.IsNull() {
+				return Target{}, "instance key must be a string or a number"
+			}
+			parts[len(parts)-1] = fmt.Sprintf("%s[%s]", name.Name, key.AsString())
+			kind = targetInstance
+			i++
+		}
+		if i+1 < len(traversal) {
+			return Target{}, "a resource must be the last part of the address"
+		}
+	}
+
+	return Target{Address: strings.Join(parts, "."), kind: kind}, ""
+}
+
+// Checks if the resource name or instance key is targeted
+func (t Target) Match(key string) bool {
+	switch t.kind {
+	case targetModule:
+		return strings.HasPrefix(key, t.Address+".")
+	case targetResource:
+		return key == t.Address || strings.HasPrefix(key, t.Address+"[")
+	default:
+		return key == t.Address
+	}
+}
+
+// Checks if the resource name or instance key is matched by any of the targets
+func (targets Targets) Match(key string) bool {
+	for _, target := range targets {
+		if target.Match(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks if the whole resource is targeted and not only some of its instances
+func (targets Targets) matchResource(r *decode.DecodedResource) bool {
+	for _, target := range targets {
+		if target.kind != targetInstance && target.Match(r.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the addresses of the targets which matched a resource and the matched resources
+func (g *Graph) matchTargets(targets Targets) (map[string]bool, dag.Set) {
+	matched := make(map[string]bool)
+	targeted := make(dag.Set)
+	for _, v := range g.Vertices() {
+		r, ok := v.(*decode.DecodedResource)
+		if !ok {
+			continue
+		}
+		for _, target := range targets {
+			if target.kind != targetInstance && target.Match(r.Name) {
+				matched[target.Address] = true
+				targeted.Add(r)
+			}
+			for key := range r.Config {
+				if target.Match(key) {
+					matched[target.Address] = true
+					targeted.Add(r)
+				}
+			}
+		}
+	}
+	return matched, targeted
+}
+
+// Returns a warning for each --replace address which does not match any resource in the graph
+func (g *Graph) CheckReplace(replace Targets) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	matched, _ := g.matchTargets(replace)
+	for _, target := range replace {
+		if !matched[target.Address] {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "Replace address does not match any resource",
+				Detail:   fmt.Sprintf("Replace address %s does not match any resource in the configuration, nothing will be replaced", target.Address),
+			})
+		}
+	}
+	return diags
+}
+
+// Restricts the graph to the targeted resources and their dependencies
+// Resources which are targeted only by instance keys keep only the targeted instances
+// Returns a warning for each target which does not match any resource in the configuration
+func (g *Graph) Target(targets Targets) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	if len(targets) == 0 {
+		return diags
+	}
+
+	matched, targeted := g.matchTargets(targets)
+	for _, target := range targets {
+		if !matched[target.Address] {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "Target does not match any resource",
+				Detail:   fmt.Sprintf("Target %s does not match any resource in the configuration, only resources in the state will be affected", target.Address),
+			})
+		}
+	}
+
+	dependencies := make(dag.Set)
+	for _, raw := range targeted {
+		ancestors, err := g.Ancestors(raw.(dag.Vertex))
+		if err != nil {
+			continue
+		}
+		for _, ancestor := range ancestors {
+			dependencies.Add(ancestor)
+		}
+	}
+
+	for _, v := range g.Vertices() {
+		r, ok := v.(*decode.DecodedResource)
+		if !ok {
+			continue
+		}
+		if !targeted.Include(r) && !dependencies.Include(r) {
+			g.Remove(r)
+			continue
+		}
+
+		// Dependencies and resources which are targeted as a whole keep all of their instances
+		if dependencies.Include(r) || targets.matchResource(r) {
+			continue
+		}
+		config := make(map[string]cty.Value)
+		for key, value := range r.Config {
+			if targets.Match(key) {
+				config[key] = value
+			}
+		}
+		r.Config = config
+	}
+
+	return diags
+}
@@ -0,0 +1,139 @@
+package configs
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"kubehcl.sh/kubehcl/internal/decode"
+)
+
+func Test_ParseTargets(t *testing.T) {
+	tests := map[string]string{
+		`kube_resource.foo`:                      "kube_resource.foo",
+		`module.test`:                            "module.test",
+		`module.test.kube_resource.bar["x"]`:     "module.test.kube_resource.bar[x]",
+		`module.a.module.b.kube_resource.bar[1]`: "module.a.module.b.kube_resource.bar[1]",
+		`module.test.kube_resource.bar`:          "module.test.kube_resource.bar",
+	}
+	for address, expected := range tests {
+		targets, diags := ParseTargets([]string{address})
+		if diags.HasErrors() {
+			t.Errorf("Failed to parse %s: %s", address, diags.Error())
+			continue
+		}
+		if targets[0].Address != expected {
+			t.Errorf("Expected %s got: %s", expected, targets[0].Address)
+		}
+	}
+
+	for _, address := range []string{`variable.foo`, `kube_resource`, `kube_resource.foo.bar`, `kube_resource.foo["x"].bar`} {
+		if _, diags := ParseTargets([]string{address}); !diags.HasErrors() {
+			t.Errorf("Expected %s to be invalid", address)
+		}
+	}
+}
+
+func Test_TargetMatch(t *testing.T) {
+	targets, diags := ParseTargets([]string{`module.test`, `kube_resource.foo`, `kube_resource.bar["x"]`})
+	if diags.HasErrors() {
+		t.Fatalf("Failed becuase %s", diags.Error())
+	}
+
+	matches := map[string]bool{
+		"module.test.kube_resource.a":    true,
+		"module.testing.kube_resource.a": false,
+		"kube_resource.foo":              true,
+		"kube_resource.foo[a]":           true,
+		"kube_resource.foobar":           false,
+		"kube_resource.bar[x]":           true,
+		"kube_resource.bar[y]":           false,
+	}
+	for key, expected := range matches {
+		if targets.Match(key) != expected {
+			t.Errorf("Expected match of %s to be %t", key, expected)
+		}
+	}
+}
+
+func Test_GraphTarget(t *testing.T) {
+	config := kindResource("config", "ConfigMap")
+	other := kindResource("other", "ConfigMap")
+	deployment := &decode.DecodedResource{
+		DecodedDeployable: decode.DecodedDeployable{
+			Name: "deployment",
+			Type: "r",
+			Config: map[string]cty.Value{
+				"kube_resource.deployment[a]": cty.ObjectVal(map[string]cty.Value{"kind": cty.StringVal("Deployment")}),
+				"kube_resource.deployment[b]": cty.ObjectVal(map[string]cty.Value{"kind": cty.StringVal("Deployment")}),
+			},
+			DependsOn: []hcl.Traversal{
+				{
+					hcl.TraverseRoot{Name: "kube_resource"},
+					hcl.TraverseAttr{Name: "config"},
+				},
+			},
+		},
+	}
+	mod := &decode.DecodedModule{
+		Name: rootNodeName,
+		Resources: decode.DecodedResourceMap{
+			"config":     config,
+			"other":      other,
+			"deployment": deployment,
+		},
+	}
+
+	g := &Graph{DecodedModule: mod, DisableKindOrdering: true}
+	diags := g.Init()
+	if diags.HasErrors() {
+		t.Fatalf("Failed becuase %s", diags.Error())
+	}
+
+	targets, diags := ParseTargets([]string{`kube_resource.deployment["a"]`, `kube_resource.missing`})
+	if diags.HasErrors() {
+		t.Fatalf("Failed becuase %s", diags.Error())
+	}
+	diags = g.Target(targets)
+	if diags.HasErrors() || len(diags) != 1 {
+		t.Errorf("Expected a single warning for the missing target got: %s", diags.Error())
+	}
+
+	if !g.HasVertex(deployment) || !g.HasVertex(config) {
+		t.Errorf("Expected the target and its dependencies to be kept")
+	}
+	if g.HasVertex(other) {
+		t.Errorf("Expected resources which are not targeted to be removed")
+	}
+	if _, exists := deployment.Config["kube_resource.deployment[b]"]; exists || len(deployment.Config) != 1 {
+		t.Errorf("Expected only the targeted instance to be kept got: %v", deployment.Config)
+	}
+}
+
+func Test_GraphCheckReplace(t *testing.T) {
+	config := kindResource("config", "ConfigMap")
+	mod := &decode.DecodedModule{
+		Name: rootNodeName,
+		Resources: decode.DecodedResourceMap{
+			"config": config,
+		},
+	}
+
+	g := &Graph{DecodedModule: mod, DisableKindOrdering: true}
+	diags := g.Init()
+	if diags.HasErrors() {
+		t.Fatalf("Failed becuase %s", diags.Error())
+	}
+
+	replace, diags := ParseTargets([]string{`kube_resource.config`, `kube_resource.missing`})
+	if diags.HasErrors() {
+		t.Fatalf("Failed becuase %s", diags.Error())
+	}
+	diags = g.CheckReplace(replace)
+	if diags.HasErrors() || len(diags) != 1 {
+		t.Errorf("Expected a single warning for the missing replace address got: %s", diags.Error())
+	}
+	if !g.HasVertex(config) {
+		t.Errorf("Expected check replace to keep the graph untouched")
+	}
+}
@@ -20,6 +20,7 @@ import (
 
 	// "kubehcl.sh/kubehcl/internal/addrs"
 	"kubehcl.sh/kubehcl/internal/decode"
+	"kubehcl.sh/kubehcl/internal/lang/marks"
 )
 
 // var variables VariableList
@@ -30,6 +31,15 @@ type Variable struct {
 	Type        cty.Type       // `json:"Type"`
 	DeclRange   hcl.Range      // `json:"DeclRange"`
 	HasDefault  bool           // `json:"HasDefault"` // for checking if needed request from the user
+	Sensitive   bool           // `json:"Sensitive"`
+	Validations []*Validation  // `json:"Validations"`
+}
+
+// Validation is a rule the value of a variable must satisfy
+type Validation struct {
+	Condition    hcl.Expression
+	ErrorMessage hcl.Expression
+	DeclRange    hcl.Range
 }
 
 type VariableMap map[string]*Variable
@@ -45,6 +55,17 @@ var inputVariableBlockSchema = &hcl.BodySchema{
 		{Name: "type", Required: false},
 		{Name: "default", Required: false},
 		{Name: "description", Required: false},
+		{Name: "sensitive", Required: false},
+	},
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "validation"},
+	},
+}
+
+var variableValidationBlockSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "condition", Required: true},
+		{Name: "error_message", Required: true},
 	},
 }
 
@@ -66,7 +87,7 @@ func (v *Variable) checkVariableName() hcl.Diagnostics {
 // Decode variable and verify the type of the variable matches the default value defined
 // If no type is defined each value will be accepted
 // Decode the value into a golang cty.value
-func (v *Variable) decode(ctx *hcl.EvalContext) (*decode.DecodedVariable, hcl.Diagnostics) {
+func (v *Variable) decode(ctx *hcl.EvalContext, scope *decode.Scope) (*decode.DecodedVariable, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
 	dV := &decode.DecodedVariable{
 		Name:        v.Name,
@@ -90,17 +111,77 @@ func (v *Variable) decode(ctx *hcl.EvalContext) (*decode.DecodedVariable, hcl.Di
 			val = cty.DynamicVal
 		}
 	}
+	// Sensitive values are redacted in the plan, template and diagnostics
+	if v.Sensitive {
+		val = val.Mark(marks.Sensitive)
+	}
 	dV.Default = val
+	if !diags.HasErrors() {
+		diags = append(diags, v.validate(dV, scope)...)
+	}
 
 	return dV, diags
 }
 
+// Evaluates the validation rules of the variable against its converted value
+// Failures point at the source of the value which is the default, the vars file, --var or the module call
+// The rules are evaluated in the scope of the module which declares the variable
+func (v *Variable) validate(dV *decode.DecodedVariable, scope *decode.Scope) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	if len(v.Validations) == 0 {
+		return diags
+	}
+	ctx, ctxDiags := decode.CreateContext(decode.DecodedVariableMap{v.Name: dV}, decode.DecodedLocalsMap{}, scope)
+	diags = append(diags, ctxDiags...)
+
+	for _, validation := range v.Validations {
+		condition, conditionDiags := validation.Condition.Value(ctx)
+		diags = append(diags, conditionDiags...)
+		if conditionDiags.HasErrors() || !condition.IsKnown() {
+			continue
+		}
+		condition, err := convert.Convert(condition, cty.Bool)
+		condition, _ = condition.Unmark()
+		if err != nil || condition.IsNull() {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid validation condition",
+				Detail:   fmt.Sprintf("The condition of the validation of var.%s must be true or false", v.Name),
+				Subject:  validation.Condition.Range().Ptr(),
+			})
+			continue
+		}
+		if condition.True() {
+			continue
+		}
+
+		message := fmt.Sprintf("The value of var.%s is invalid", v.Name)
+		errorMessage, messageDiags := validation.ErrorMessage.Value(ctx)
+		diags = append(diags, messageDiags...)
+		if !messageDiags.HasErrors() {
+			if errorMessage, err := convert.Convert(errorMessage, cty.String); err == nil && marks.Contains(errorMessage, marks.Sensitive) {
+				message = "The error message included a sensitive value, so it will not be displayed"
+			} else if err == nil && !errorMessage.IsNull() && errorMessage.IsKnown() {
+				message = errorMessage.AsString()
+			}
+		}
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid value for variable",
+			Detail:   fmt.Sprintf("%s\n\nThis was checked by the validation rule of var.%s at %s.", message, v.Name, validation.DeclRange.String()),
+			Subject:  v.Default.Range().Ptr(),
+		})
+	}
+	return diags
+}
+
 // Decode variable map
-func (v VariableMap) Decode(ctx *hcl.EvalContext) (decode.DecodedVariableMap, hcl.Diagnostics) {
+// The values are evaluated with ctx and the validation rules in the scope of the module
+func (v VariableMap) Decode(ctx *hcl.EvalContext, scope *decode.Scope) (decode.DecodedVariableMap, hcl.Diagnostics) {
 	dVars := make(decode.DecodedVariableMap)
 	var diags hcl.Diagnostics
 	for key, variable := range v {
-		dV, varDiags := variable.decode(ctx)
+		dV, varDiags := variable.decode(ctx, scope)
 		diags = append(diags, varDiags...)
 		if _, ok := dVars[key]; ok {
 			diags = append(diags, &hcl.Diagnostic{
@@ -166,11 +247,55 @@ func decodeVariableBlock(block *hcl.Block) (*Variable, hcl.Diagnostics) {
 		diags = append(diags, valDiags...)
 	}
 
+	if attr, exists := content.Attributes["sensitive"]; exists {
+		valDiags := gohcl.DecodeExpression(attr.Expr, nil, &variable.Sensitive)
+		diags = append(diags, valDiags...)
+	}
+
 	if attr, exists := content.Attributes["default"]; exists {
 		variable.Default = attr.Expr
 		variable.HasDefault = true
 	}
+
+	for _, block := range content.Blocks.OfType("validation") {
+		validation, validationDiags := decodeValidationBlock(variable.Name, block)
+		diags = append(diags, validationDiags...)
+		if validation != nil {
+			variable.Validations = append(variable.Validations, validation)
+		}
+	}
 	diags = append(diags, variable.checkVariableName()...)
 
 	return variable, diags
 }
+
+// Decode validation block
+// The condition and error message can only reference the variable itself
+func decodeValidationBlock(name string, block *hcl.Block) (*Validation, hcl.Diagnostics) {
+	content, diags := block.Body.Content(variableValidationBlockSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	validation := &Validation{
+		Condition:    content.Attributes["condition"].Expr,
+		ErrorMessage: content.Attributes["error_message"].Expr,
+		DeclRange:    block.DefRange,
+	}
+	for _, expr := range []hcl.Expression{validation.Condition, validation.ErrorMessage} {
+		for _, traversal := range expr.Variables() {
+			if referenced, ok := referencedName(traversal, "var"); ok && referenced == name {
+				continue
+			}
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid reference in variable validation",
+				Detail:   fmt.Sprintf("The validation of var.%s can only reference the variable itself", name),
+				Subject:  traversal.SourceRange().Ptr(),
+			})
+		}
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return validation, diags
+}
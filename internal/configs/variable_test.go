@@ -10,11 +10,15 @@ package configs
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/spf13/afero"
 	"github.com/zclconf/go-cty/cty"
+	"kubehcl.sh/kubehcl/internal/lang/marks"
+	"kubehcl.sh/kubehcl/internal/logging"
 )
 
 func Test_Variable(t *testing.T) {
@@ -87,3 +91,273 @@ func Test_Variable(t *testing.T) {
 		}
 	}
 }
+
+func Test_VariableValidation(t *testing.T) {
+	validatedVariable := `variable "replicas" {
+  type    = number
+  default = 1
+  validation {
+    condition     = var.replicas > 0
+    error_message = "replicas must be positive got ${var.replicas}"
+  }
+  validation {
+    condition     = var.replicas < 10
+    error_message = "replicas must be lower than 10"
+  }
+}
+`
+	tests := []struct {
+		folder     string
+		files      map[string]string
+		vals       []string
+		wantErrors []string
+		subject    string
+	}{
+		{
+			folder: "validation_default",
+			files:  map[string]string{"validation_default/main.hcl": validatedVariable},
+		},
+		{
+			folder:     "validation_commandline",
+			files:      map[string]string{"validation_commandline/main.hcl": validatedVariable},
+			vals:       []string{"replicas=0"},
+			wantErrors: []string{"replicas must be positive got 0"},
+			subject:    "commandline arguments",
+		},
+		{
+			folder: "validation_module_call",
+			files: map[string]string{
+				"validation_module_call/main.hcl": `module "web" {
+  source   = "./web"
+  replicas = 20
+}`,
+				"validation_module_call/web/main.hcl": validatedVariable,
+			},
+			wantErrors: []string{"replicas must be lower than 10"},
+			subject:    "validation_module_call/main.hcl",
+		},
+		{
+			folder: "validation_invalid_reference",
+			files: map[string]string{"validation_invalid_reference/main.hcl": `variable "max" {
+  default = 10
+}
+variable "replicas" {
+  default = 1
+  validation {
+    condition     = var.replicas < var.max
+    error_message = "replicas must be lower than max"
+  }
+}`},
+			wantErrors: []string{"can only reference the variable itself"},
+		},
+		{
+			folder: "validation_invalid_condition",
+			files: map[string]string{"validation_invalid_condition/main.hcl": `variable "image" {
+  default = "nginx"
+  validation {
+    condition     = var.image
+    error_message = "image must contain a tag"
+  }
+}`},
+			wantErrors: []string{"must be true or false"},
+		},
+	}
+
+	logging.SetLogger(false)
+	for _, test := range tests {
+		appFs := afero.NewMemMapFs()
+		for name, src := range test.files {
+			if err := afero.WriteFile(appFs, name, []byte(src), 0644); err != nil {
+				t.Fatalf("Couldn't write test file: %s", err)
+			}
+		}
+		mod, diags := decodeFolder(test.folder, appFs)
+		if !diags.HasErrors() {
+			_, decodeDiags := mod.decode(&DecodeOptions{}, 0, test.folder, "", "", test.vals, &hcl.EvalContext{}, appFs)
+			diags = append(diags, decodeDiags...)
+		}
+		if len(test.wantErrors) == 0 && diags.HasErrors() {
+			t.Errorf("Don't want errors but received: %s", diags.Errs())
+			continue
+		}
+		if len(test.wantErrors) > 0 && !diags.HasErrors() {
+			t.Errorf("Want errors but did not receive any: %s", test.folder)
+			continue
+		}
+		for _, want := range test.wantErrors {
+			found := false
+			for _, diag := range diags {
+				if strings.Contains(diag.Detail, want) {
+					found = true
+					if test.subject != "" && diag.Subject.Filename != test.subject {
+						t.Errorf("Expected the error to point at %s got %s", test.subject, diag.Subject.Filename)
+					}
+				}
+			}
+			if !found {
+				t.Errorf("Expected an error containing %q got: %s", want, diags.Errs())
+			}
+		}
+	}
+}
+
+func Test_VariableSensitive(t *testing.T) {
+	sensitiveVariable := `variable "password" {
+  default   = "secret"
+  sensitive = true
+}
+`
+	secret := `kube_resource "secret" {
+  apiVersion = "v1"
+  kind       = "Secret"
+  metadata = {
+    name = "db"
+  }
+  stringData = {
+    user     = "admin"
+    password = var.password
+  }
+}
+`
+	tests := []struct {
+		folder     string
+		files      map[string]string
+		module     string
+		sensitive  map[string][]cty.Path
+		outputs    []string
+		wantErrors []string
+	}{
+		{
+			folder: "sensitive_resource",
+			files: map[string]string{"sensitive_resource/main.hcl": sensitiveVariable + secret + `kube_resource "config" {
+  apiVersion = "v1"
+  kind       = "ConfigMap"
+  metadata = {
+    name = "config"
+  }
+  data = {
+    url = "postgres://admin:${kube_resource.secret.stringData.password}@db"
+  }
+}
+output "password" {
+  value     = var.password
+  sensitive = true
+}`},
+			sensitive: map[string][]cty.Path{
+				"secret": {cty.GetAttrPath("stringData").GetAttr("password")},
+				"config": {cty.GetAttrPath("data").GetAttr("url")},
+			},
+			outputs: []string{"password"},
+		},
+		{
+			folder: "sensitive_module",
+			files: map[string]string{
+				"sensitive_module/main.hcl": sensitiveVariable + `module "db" {
+  source   = "./db"
+  password = var.password
+}`,
+				"sensitive_module/db/main.hcl": `variable "password" {}
+` + secret,
+			},
+			module: "db",
+			sensitive: map[string][]cty.Path{
+				"secret": {cty.GetAttrPath("stringData").GetAttr("password")},
+			},
+		},
+		{
+			folder: "sensitive_output",
+			files: map[string]string{"sensitive_output/main.hcl": sensitiveVariable + `output "password" {
+  value = "${var.password}!"
+}`},
+			wantErrors: []string{"add sensitive = true to the output"},
+		},
+		{
+			folder: "sensitive_count",
+			files: map[string]string{"sensitive_count/main.hcl": `variable "replicas" {
+  default   = 2
+  sensitive = true
+}
+kube_resource "config" {
+  count      = var.replicas
+  apiVersion = "v1"
+  kind       = "ConfigMap"
+  metadata = {
+    name = "config-${count.index}"
+  }
+}`},
+			wantErrors: []string{"cannot be used as count arguments"},
+		},
+		{
+			folder: "sensitive_error_message",
+			files: map[string]string{"sensitive_error_message/main.hcl": `variable "password" {
+  default   = "short"
+  sensitive = true
+  validation {
+    condition     = length(var.password) > 8
+    error_message = "password ${var.password} is too short"
+  }
+}`},
+			wantErrors: []string{"will not be displayed"},
+		},
+	}
+
+	logging.SetLogger(false)
+	for _, test := range tests {
+		appFs := afero.NewMemMapFs()
+		for name, src := range test.files {
+			if err := afero.WriteFile(appFs, name, []byte(src), 0644); err != nil {
+				t.Fatalf("Couldn't write test file: %s", err)
+			}
+		}
+		mod, diags := decodeFolder(test.folder, appFs)
+		dm, decodeDiags := mod.decode(&DecodeOptions{}, 0, test.folder, "", "", nil, &hcl.EvalContext{}, appFs)
+		diags = append(diags, decodeDiags...)
+		// The graph renames the config keys of module resources, the sensitive paths must follow them
+		if !diags.HasErrors() {
+			g := &Graph{DecodedModule: dm}
+			diags = append(diags, g.Init()...)
+		}
+		if len(test.wantErrors) == 0 && diags.HasErrors() {
+			t.Errorf("Don't want errors but received: %s", diags.Errs())
+			continue
+		}
+		if len(test.wantErrors) > 0 {
+			for _, want := range test.wantErrors {
+				found := false
+				for _, diag := range diags {
+					if strings.Contains(diag.Detail, want) {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("Expected an error containing %q got: %s", want, diags.Errs())
+				}
+			}
+			continue
+		}
+
+		if !dm.Inputs["password"].Default.HasMark(marks.Sensitive) {
+			t.Errorf("Expected var.password to be marked as sensitive in %s", test.folder)
+		}
+		resources := dm.Resources
+		if test.module != "" {
+			resources = dm.Modules[test.module].Resources
+		}
+		for name, want := range test.sensitive {
+			resource := resources[name]
+			for key, config := range resource.Config {
+				if marks.Contains(config, marks.Sensitive) {
+					t.Errorf("Expected the config of %s to be unmarked", key)
+				}
+				if !reflect.DeepEqual(resource.Sensitive[key], want) {
+					t.Errorf("Expected the sensitive paths of %s to be %#v got %#v", key, want, resource.Sensitive[key])
+				}
+			}
+		}
+		for _, name := range test.outputs {
+			if output := dm.Outputs[name]; !output.Sensitive || !output.Value.HasMark(marks.Sensitive) {
+				t.Errorf("Expected output %s to be sensitive", name)
+			}
+		}
+	}
+}
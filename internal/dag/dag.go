@@ -10,6 +10,7 @@ This file was copied from https://github.com/opentofu/opentofu and retains its'
 package dag
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strings"
@@ -168,11 +169,29 @@ func (g *AcyclicGraph) Cycles() [][]Vertex {
 // This will walk nodes in parallel if it can. The resulting diagnostics
 // contains problems from all graphs visited, in no particular order.
 func (g *AcyclicGraph) Walk(cb WalkFunc) hcl.Diagnostics {
-	w := &Walker{Callback: cb, Reverse: true}
+	return g.WalkContext(context.Background(), cb)
+}
+
+// WalkContext walks the graph like Walk but stops scheduling new nodes once
+// the context is done. Nodes which are already being visited are left to
+// finish and the skipped nodes are reported as a single diagnostic.
+func (g *AcyclicGraph) WalkContext(ctx context.Context, cb WalkFunc) hcl.Diagnostics {
+	w := &Walker{Callback: cb, Reverse: true, Context: ctx}
 	w.Update(g)
 	return w.Wait()
 }
 
+// WalkSummary walks the graph like WalkContext and returns the outcome of
+// each node. If stopOnError is set no new nodes are scheduled once a node
+// failed, otherwise only the nodes which depend on the failed node are
+// skipped.
+func (g *AcyclicGraph) WalkSummary(ctx context.Context, stopOnError bool, cb WalkFunc) (*WalkSummary, hcl.Diagnostics) {
+	w := &Walker{Callback: cb, Reverse: true, Context: ctx, StopOnError: stopOnError}
+	w.Update(g)
+	diags := w.Wait()
+	return w.Summary(), diags
+}
+
 // simple convenience helper for converting a dag.Set to a []Vertex
 func AsVertexList(s Set) []Vertex {
 	vertexList := make([]Vertex, 0, len(s))
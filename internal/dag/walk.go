@@ -10,6 +10,8 @@ This file was copied from https://github.com/opentofu/opentofu was modified and
 package dag
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -49,6 +51,15 @@ type Walker struct {
 	// When false (default), the target depends on the source.
 	Reverse bool
 
+	// Context, if set, stops the walk from scheduling new vertices once it is
+	// done. Vertices which are already executing are left to finish.
+	Context context.Context
+
+	// StopOnError, if true, stops the walk from scheduling new vertices once
+	// a vertex returned errors. Vertices which are already executing are left
+	// to finish.
+	StopOnError bool
+
 	// changeLock must be held to modify any of the fields below. Only Update
 	// should modify these fields. Modifying them outside of Update can cause
 	// serious problems.
@@ -69,6 +80,28 @@ type Walker struct {
 	// Readers and writers of either map must hold diagsLock.
 	diagsMap       map[Vertex]hcl.Diagnostics
 	upstreamFailed map[Vertex]struct{}
+	// cancelled contains all the vertices which were skipped because the
+	// context was done before they were executed.
+	cancelled map[Vertex]struct{}
+	// stopped contains all the vertices which were skipped because another
+	// vertex failed while StopOnError is set.
+	stopped map[Vertex]struct{}
+	// failed is set once a vertex callback returned errors.
+	failed bool
+}
+
+// WalkSummary describes the outcome of each vertex of a walk.
+type WalkSummary struct {
+	// Succeeded contains the vertices whose callback returned no errors.
+	Succeeded []Vertex
+	// Failed contains the vertices whose callback returned errors.
+	Failed []Vertex
+	// Skipped contains the vertices which were never executed because an
+	// upstream vertex failed, or another vertex failed while StopOnError is set.
+	Skipped []Vertex
+	// Cancelled contains the vertices which were never executed because the
+	// context was done.
+	Cancelled []Vertex
 }
 
 func (w *Walker) init() {
@@ -132,13 +165,59 @@ func (w *Walker) Wait() hcl.Diagnostics {
 			// the downstream diagnostics are likely to be redundant.
 			continue
 		}
+		if _, cancelled := w.cancelled[v]; cancelled {
+			// Cancelled nodes are reported once for the whole walk below.
+			continue
+		}
+		if _, stopped := w.stopped[v]; stopped {
+			// Stopped nodes are reported once for the whole walk below.
+			continue
+		}
 		diags = append(diags, vDiags...)
 	}
+	if len(w.stopped) > 0 {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Operation stopped",
+			Detail:   fmt.Sprintf("%d resources were skipped because another resource failed", len(w.stopped)),
+		})
+	}
+	if len(w.cancelled) > 0 {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Operation cancelled",
+			Detail:   fmt.Sprintf("%d resources were skipped because the operation was cancelled: %s", len(w.cancelled), context.Cause(w.Context)),
+		})
+	}
 	w.diagsLock.Unlock()
 
 	return diags
 }
 
+// Summary returns the outcome of each executed vertex. It must be called
+// after Wait.
+func (w *Walker) Summary() *WalkSummary {
+	summary := &WalkSummary{}
+	w.diagsLock.Lock()
+	defer w.diagsLock.Unlock()
+	for v, vDiags := range w.diagsMap {
+		_, upstream := w.upstreamFailed[v]
+		_, stopped := w.stopped[v]
+		_, cancelled := w.cancelled[v]
+		switch {
+		case cancelled:
+			summary.Cancelled = append(summary.Cancelled, v)
+		case upstream || stopped:
+			summary.Skipped = append(summary.Skipped, v)
+		case vDiags.HasErrors():
+			summary.Failed = append(summary.Failed, v)
+		default:
+			summary.Succeeded = append(summary.Succeeded, v)
+		}
+	}
+	return summary
+}
+
 // Update updates the currently executing walk with the given graph.
 // This will perform a diff of the vertices and edges and update the walker.
 // Already completed vertices remain completed (including any errors during
@@ -383,8 +462,27 @@ func (w *Walker) walkVertex(v Vertex, info *walkerVertex) {
 
 	// Run our callback or note that our upstream failed
 	var diags hcl.Diagnostics
-	var upstreamFailed bool
-	if depsSuccess {
+	var upstreamFailed, cancelled, stopped bool
+	w.diagsLock.Lock()
+	stop := w.StopOnError && w.failed
+	w.diagsLock.Unlock()
+	if w.Context != nil && w.Context.Err() != nil {
+		// The walk was cancelled, the error makes sure that nothing
+		// downstream is executed either.
+		diags = diags.Append(&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Operation cancelled",
+		})
+		cancelled = true
+	} else if stop {
+		// Another vertex failed, the error makes sure that nothing
+		// downstream is executed either.
+		diags = diags.Append(&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Operation stopped",
+		})
+		stopped = true
+	} else if depsSuccess {
 		diags = w.Callback(v)
 	} else {
 		// log.Printf("[TRACE] dag/walk: upstream of %q errored, so skipping", VertexName(v))
@@ -412,6 +510,21 @@ func (w *Walker) walkVertex(v Vertex, info *walkerVertex) {
 	if upstreamFailed {
 		w.upstreamFailed[v] = struct{}{}
 	}
+	if w.cancelled == nil {
+		w.cancelled = make(map[Vertex]struct{})
+	}
+	if cancelled {
+		w.cancelled[v] = struct{}{}
+	}
+	if w.stopped == nil {
+		w.stopped = make(map[Vertex]struct{})
+	}
+	if stopped {
+		w.stopped[v] = struct{}{}
+	}
+	if !upstreamFailed && !cancelled && !stopped && diags.HasErrors() {
+		w.failed = true
+	}
 	w.diagsLock.Unlock()
 }
 
@@ -14,7 +14,9 @@ Licesne: https://www.mozilla.org/en-US/MPL/2.0/
 package dag
 
 import (
+	"context"
 	"reflect"
+	"sort"
 	"sync"
 	"testing"
 	"time"
@@ -310,3 +312,133 @@ func walkCbRecord(order *[]interface{}) WalkFunc {
 		return nil
 	}
 }
+
+func TestWalker_cancel(t *testing.T) {
+	var g AcyclicGraph
+	g.Add(1)
+	g.Add(2)
+	g.Add(3)
+	g.Connect(BasicEdge(1, 2))
+	g.Connect(BasicEdge(2, 3))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel the walk while the first vertex is executing
+	var order []interface{}
+	recordCb := walkCbRecord(&order)
+	cb := func(v Vertex) hcl.Diagnostics {
+		if v == 1 {
+			cancel()
+		}
+		return recordCb(v)
+	}
+
+	w := &Walker{Callback: cb, Context: ctx}
+	w.Update(&g)
+	diags := w.Wait()
+	if !diags.HasErrors() {
+		t.Fatal("expect error")
+	}
+	if len(diags) != 1 || diags[0].Summary != "Operation cancelled" {
+		t.Fatalf("expected a single cancellation diagnostic got: %s", diags.Error())
+	}
+
+	// The executing vertex finishes and nothing else is scheduled
+	expected := []interface{}{1}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("wrong order\ngot:  %#v\nwant: %#v", order, expected)
+	}
+}
+
+func TestWalker_stopOnError(t *testing.T) {
+	var g AcyclicGraph
+	g.Add(1)
+	g.Add(2)
+	g.Add(3)
+	g.Connect(BasicEdge(2, 3))
+
+	var order []interface{}
+	recordCb := walkCbRecord(&order)
+	var w *Walker
+	started := make(chan struct{})
+	cb := func(v Vertex) hcl.Diagnostics {
+		switch v {
+		case 1:
+			// Fail once the other vertex is executing
+			<-started
+			return hcl.Diagnostics{&hcl.Diagnostic{Severity: hcl.DiagError}}
+		case 2:
+			close(started)
+			// Wait for the failure so the vertex which depends on this one is scheduled after it
+			for {
+				w.diagsLock.Lock()
+				failed := w.failed
+				w.diagsLock.Unlock()
+				if failed {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}
+		return recordCb(v)
+	}
+
+	w = &Walker{Callback: cb, StopOnError: true}
+	w.Update(&g)
+	diags := w.Wait()
+	if !diags.HasErrors() {
+		t.Fatal("expect error")
+	}
+
+	// The executing vertex finishes and nothing else is scheduled
+	expected := []interface{}{2}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("wrong order\ngot:  %#v\nwant: %#v", order, expected)
+	}
+	summary := w.Summary()
+	if !reflect.DeepEqual(summary.Skipped, []Vertex{3}) {
+		t.Errorf("wrong skipped vertices\ngot:  %#v\nwant: %#v", summary.Skipped, []Vertex{3})
+	}
+	if diags[len(diags)-1].Summary != "Operation stopped" {
+		t.Errorf("expected a stop diagnostic got: %s", diags.Error())
+	}
+}
+
+func TestWalker_summary(t *testing.T) {
+	var g AcyclicGraph
+	g.Add(1)
+	g.Add(2)
+	g.Add(3)
+	g.Add(4)
+	g.Connect(BasicEdge(1, 2))
+	g.Connect(BasicEdge(2, 3))
+
+	cb := func(v Vertex) hcl.Diagnostics {
+		if v == 2 {
+			return hcl.Diagnostics{&hcl.Diagnostic{Severity: hcl.DiagError}}
+		}
+		return nil
+	}
+
+	w := &Walker{Callback: cb}
+	w.Update(&g)
+	if diags := w.Wait(); len(diags) != 1 {
+		t.Fatalf("expected only the diagnostic of the failed vertex got: %d", len(diags))
+	}
+
+	summary := w.Summary()
+	sortVertices := func(vertices []Vertex) []Vertex {
+		sort.Slice(vertices, func(i, j int) bool { return vertices[i].(int) < vertices[j].(int) })
+		return vertices
+	}
+	if got := sortVertices(summary.Succeeded); !reflect.DeepEqual(got, []Vertex{1, 4}) {
+		t.Errorf("wrong succeeded vertices\ngot:  %#v\nwant: %#v", got, []Vertex{1, 4})
+	}
+	if !reflect.DeepEqual(summary.Failed, []Vertex{2}) {
+		t.Errorf("wrong failed vertices\ngot:  %#v\nwant: %#v", summary.Failed, []Vertex{2})
+	}
+	if !reflect.DeepEqual(summary.Skipped, []Vertex{3}) {
+		t.Errorf("wrong skipped vertices\ngot:  %#v\nwant: %#v", summary.Skipped, []Vertex{3})
+	}
+}
@@ -28,7 +28,12 @@ type Deployable struct {
 	Config    hcl.Body        `json:"Config"`
 	Type      string          `json:"Type"`
 	DependsOn []hcl.Traversal `json:"DependsOn"`
-	DeclRange hcl.Range       `json:"DeclRange"`
+	Cluster   string          `json:"Cluster"`
+	// Preconditions are checked for each instance when the deployable is decoded
+	Preconditions []*Condition `json:"Preconditions"`
+	// Postconditions are checked for each instance of a resource once it was applied
+	Postconditions []*Condition `json:"Postconditions"`
+	DeclRange      hcl.Range    `json:"DeclRange"`
 }
 
 var commonAttributes = &hcl.BodySchema{
@@ -42,8 +47,15 @@ var commonAttributes = &hcl.BodySchema{
 		{
 			Name: "depends_on",
 		},
+		{
+			Name: "cluster",
+		},
+	},
+	Blocks: []hcl.BlockHeaderSchema{
+		{
+			Type: "lifecycle",
+		},
 	},
-	Blocks: []hcl.BlockHeaderSchema{},
 }
 
 func (d *Deployable) addr() addrs.Deployable {
@@ -53,6 +65,15 @@ func (d *Deployable) addr() addrs.Deployable {
 	}
 }
 
+func isCommonBlock(blockType string) bool {
+	for _, blockS := range commonAttributes.Blocks {
+		if blockS.Type == blockType {
+			return true
+		}
+	}
+	return false
+}
+
 type expandable struct {
 	ForEach cty.Value
 }
@@ -175,6 +196,7 @@ func checkIfExists(m map[string]cty.Value, key string, ran *hcl.Range) hcl.Diagn
 	return hcl.Diagnostics{}
 }
 
+// Marks such as sensitive are kept on the attributes derived from marked values
 func decodeUnknownBody(ctx *hcl.EvalContext, body *hclsyntax.Body, check bool) (cty.Value, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
 	attrMap := make(map[string]cty.Value)
@@ -235,10 +257,12 @@ func decodeUnknownBody(ctx *hcl.EvalContext, body *hclsyntax.Body, check bool) (
 // It will return a deployable with configmap which contains all the resources available to be deployed
 func (r Deployable) Decode(ctx *hcl.EvalContext) (*DecodedDeployable, hcl.Diagnostics) {
 	dR := &DecodedDeployable{
-		Name:      r.Name,
-		Type:      r.Type,
-		DependsOn: r.DependsOn,
-		DeclRange: r.DeclRange,
+		Name:           r.Name,
+		Type:           r.Type,
+		DependsOn:      r.DependsOn,
+		Cluster:        r.Cluster,
+		Postconditions: r.Postconditions,
+		DeclRange:      r.DeclRange,
 	}
 	deployMap := make(map[string]cty.Value)
 	var diags hcl.Diagnostics
@@ -250,6 +274,14 @@ func (r Deployable) Decode(ctx *hcl.EvalContext) (*DecodedDeployable, hcl.Diagno
 	for _, attrS := range commonAttributes.Attributes {
 		delete(body.Attributes, attrS.Name)
 	}
+	// Meta blocks such as lifecycle are not part of the kubernetes object
+	var blocks hclsyntax.Blocks
+	for _, block := range body.Blocks {
+		if !isCommonBlock(block.Type) {
+			blocks = append(blocks, block)
+		}
+	}
+	body.Blocks = blocks
 	if r.Count != nil {
 		count, countDiags := decodeCountExpr(ctx, r.Count)
 		diags = append(diags, countDiags...)
@@ -263,7 +295,9 @@ func (r Deployable) Decode(ctx *hcl.EvalContext) (*DecodedDeployable, hcl.Diagno
 			if err != nil {
 				panic("Always can convert int")
 			}
-			deployMap[fmt.Sprintf("%s[%s]", r.addr().String(), val.AsString())] = Attributes
+			key := fmt.Sprintf("%s[%s]", r.addr().String(), val.AsString())
+			deployMap[key] = Attributes
+			diags = append(diags, r.checkInstance(ctx, key, dR)...)
 			delete(ctx.Variables, "count")
 		}
 		// check configuration of the resource
@@ -283,7 +317,9 @@ func (r Deployable) Decode(ctx *hcl.EvalContext) (*DecodedDeployable, hcl.Diagno
 				ctx.Variables["each"] = cty.ObjectVal(map[string]cty.Value{"key": cty.StringVal(key), "value": val})
 				Attributes, forEachDiags := decodeUnknownBody(ctx, body, false)
 				diags = append(diags, forEachDiags...)
-				deployMap[fmt.Sprintf("%s[%s]", r.addr().String(), key)] = Attributes
+				instanceKey := fmt.Sprintf("%s[%s]", r.addr().String(), key)
+				deployMap[instanceKey] = Attributes
+				diags = append(diags, r.checkInstance(ctx, instanceKey, dR)...)
 				delete(ctx.Variables, "each")
 				didOperate = true
 			}
@@ -292,7 +328,9 @@ func (r Deployable) Decode(ctx *hcl.EvalContext) (*DecodedDeployable, hcl.Diagno
 				ctx.Variables["each"] = cty.ObjectVal(map[string]cty.Value{"key": val, "value": val})
 				Attributes, forEachDiags := decodeUnknownBody(ctx, body, false)
 				diags = append(diags, forEachDiags...)
-				deployMap[fmt.Sprintf("%s[%s]", r.addr().String(), val.AsString())] = Attributes
+				key := fmt.Sprintf("%s[%s]", r.addr().String(), val.AsString())
+				deployMap[key] = Attributes
+				diags = append(diags, r.checkInstance(ctx, key, dR)...)
 				delete(ctx.Variables, "each")
 				didOperate = true
 			}
@@ -309,6 +347,18 @@ func (r Deployable) Decode(ctx *hcl.EvalContext) (*DecodedDeployable, hcl.Diagno
 		Attributes, regDiags := decodeUnknownBody(ctx, body, false)
 		diags = append(diags, regDiags...)
 		deployMap[r.addr().String()] = Attributes
+		diags = append(diags, r.checkInstance(ctx, r.addr().String(), dR)...)
+	}
+	// Marks can't be converted to json, the paths of the sensitive values are kept to redact them in the output
+	for key, config := range deployMap {
+		unmarked, paths := unmarkSensitive(config)
+		deployMap[key] = unmarked
+		if len(paths) > 0 {
+			if dR.Sensitive == nil {
+				dR.Sensitive = make(map[string][]cty.Path)
+			}
+			dR.Sensitive[key] = paths
+		}
 	}
 	dR.Config = deployMap
 	return dR, diags
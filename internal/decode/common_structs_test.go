@@ -147,7 +147,7 @@ func Test_commonStruct(t *testing.T) {
 		} else if !diags.HasErrors() && test.wantErrors {
 			t.Errorf("Error not received but was expected")
 		} else if !reflect.DeepEqual(d, test.want) && !test.wantErrors {
-			t.Errorf("Wanted \n%s got  \n%s", test.want, d)
+			t.Errorf("Wanted \n%v got  \n%v", test.want, d)
 		}
 	}
 }
@@ -0,0 +1,104 @@
+package decode
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"kubehcl.sh/kubehcl/internal/addrs"
+	"kubehcl.sh/kubehcl/internal/lang/marks"
+)
+
+// Condition is a precondition, a postcondition or an assertion of a check block
+type Condition struct {
+	Condition    hcl.Expression
+	ErrorMessage hcl.Expression
+	DeclRange    hcl.Range
+}
+
+// Evaluates the condition and returns a diagnostic with the error message when it is false
+// Conditions which are not known such as ones referring to modules created with count are skipped
+// checkedBy describes the rule for example "precondition of kube_resource.foo"
+func (c *Condition) Check(ctx *hcl.EvalContext, severity hcl.DiagnosticSeverity, summary, checkedBy string) hcl.Diagnostics {
+	condition, diags := c.Condition.Value(ctx)
+	if diags.HasErrors() || !condition.IsKnown() {
+		return diags
+	}
+	condition, err := convert.Convert(condition, cty.Bool)
+	if err == nil {
+		condition, _ = condition.Unmark()
+	}
+	if err != nil || condition.IsNull() {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid condition result",
+			Detail:   fmt.Sprintf("The condition of the %s must be true or false", checkedBy),
+			Subject:  c.Condition.Range().Ptr(),
+		})
+	}
+	if condition.True() {
+		return diags
+	}
+
+	message := fmt.Sprintf("The %s failed", checkedBy)
+	errorMessage, messageDiags := c.ErrorMessage.Value(ctx)
+	diags = append(diags, messageDiags...)
+	if !messageDiags.HasErrors() {
+		if errorMessage, err := convert.Convert(errorMessage, cty.String); err == nil && marks.Contains(errorMessage, marks.Sensitive) {
+			message = "The error message included a sensitive value, so it will not be displayed"
+		} else if err == nil && !errorMessage.IsNull() && errorMessage.IsKnown() {
+			message = errorMessage.AsString()
+		}
+	}
+	return append(diags, &hcl.Diagnostic{
+		Severity: severity,
+		Summary:  summary,
+		Detail:   fmt.Sprintf("%s\n\nThis was checked by the %s at %s.", message, checkedBy, c.DeclRange.String()),
+		Subject:  c.Condition.Range().Ptr(),
+	})
+}
+
+// Copies the variables of the context so the instance values such as count.index are kept after decoding
+func snapshotContext(ctx *hcl.EvalContext) *hcl.EvalContext {
+	return &hcl.EvalContext{
+		Variables: maps.Clone(ctx.Variables),
+		Functions: ctx.Functions,
+	}
+}
+
+// Checks the preconditions of an instance of the deployable
+// The evaluation context of the instance is kept when a resource has postconditions
+func (r Deployable) checkInstance(ctx *hcl.EvalContext, key string, dR *DecodedDeployable) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	summary := "Resource precondition failed"
+	if r.Type == addrs.MType {
+		summary = "Module precondition failed"
+	}
+	for _, precondition := range r.Preconditions {
+		diags = append(diags, precondition.Check(ctx, hcl.DiagError, summary, "precondition of "+key)...)
+	}
+	if len(r.Postconditions) > 0 && r.Type == addrs.RType {
+		if dR.PostconditionContexts == nil {
+			dR.PostconditionContexts = make(map[string]*hcl.EvalContext)
+		}
+		dR.PostconditionContexts[key] = snapshotContext(ctx)
+	}
+	return diags
+}
+
+// Checks the postconditions of an applied instance, self refers to the live object
+func (d *DecodedDeployable) CheckPostconditions(key string, self cty.Value) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	ctx, exists := d.PostconditionContexts[key]
+	if !exists {
+		return diags
+	}
+	ctx = snapshotContext(ctx)
+	ctx.Variables["self"] = self
+	for _, postcondition := range d.Postconditions {
+		diags = append(diags, postcondition.Check(ctx, hcl.DiagError, "Resource postcondition failed", "postcondition of "+key)...)
+	}
+	return diags
+}
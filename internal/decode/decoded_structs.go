@@ -23,7 +23,17 @@ type DecodedDeployable struct {
 	Config    map[string]cty.Value
 	Type      string
 	DependsOn []hcl.Traversal
-	DeclRange hcl.Range
+	// Cluster is the alias of the cluster the deployable is deployed to, empty for the default cluster
+	Cluster string
+	// References contains the modules and resources referenced by the expressions of the deployable
+	References []hcl.Traversal
+	// Sensitive contains the paths of the sensitive values of each config, the config itself is unmarked
+	Sensitive map[string][]cty.Path
+	// Postconditions are checked against the live object of each instance once it was applied
+	// PostconditionContexts contains the evaluation context of each instance, nil when there are no postconditions
+	Postconditions        []*Condition
+	PostconditionContexts map[string]*hcl.EvalContext
+	DeclRange             hcl.Range
 }
 
 func (d *DecodedDeployable) Addr() addrs.Deployable {
@@ -36,9 +46,20 @@ func (d *DecodedDeployable) Addr() addrs.Deployable {
 type DependsOn struct {
 	Trav  []hcl.Traversal
 	Depth int
+	// Implicit dependencies come from references in expressions, modules without resources are ignored
+	Implicit bool
 }
+
+// Lifecycle changes how a resource is handled when it is applied
+// RecreateOnImmutable deletes and creates the resource again when an update fails because a field is immutable
+type Lifecycle struct {
+	RecreateOnImmutable bool
+	DeclRange           hcl.Range
+}
+
 type DecodedResource struct {
 	DecodedDeployable
+	Lifecycle            Lifecycle
 	Depth                int
 	Dependencies         []DependsOn
 	DependenciesAppended []DependsOn
@@ -67,6 +88,48 @@ type DecodedBackendStorage struct {
 	DeclRange hcl.Range
 }
 
+// DecodedCluster is the connection of a cluster alias
+// Empty fields are taken from the command line settings
+type DecodedCluster struct {
+	Name       string
+	Context    string
+	Kubeconfig string
+	Namespace  string
+	DeclRange  hcl.Range
+}
+
+type DecodedClusterMap map[string]*DecodedCluster
+
+// DecodedOutput is a value exposed by the configuration
+type DecodedOutput struct {
+	Name        string
+	Description string
+	Value       cty.Value
+	Sensitive   bool
+	DeclRange   hcl.Range
+}
+
+type DecodedOutputMap map[string]*DecodedOutput
+
+// DecodedData is a kube_data block which reads existing objects from the cluster
+type DecodedData struct {
+	Name string
+	// Address of the block including the modules such as module.foo.kube_data.bar
+	Address    string
+	APIVersion string
+	Kind       string
+	ObjectName string
+	Namespace  string
+	// List reads the objects matching the label selector instead of a single object
+	List          bool
+	LabelSelector map[string]string
+	// Value is an object with the read object or with the list of the read objects
+	Value     cty.Value
+	DeclRange hcl.Range
+}
+
+type DecodedDataMap map[string]*DecodedData
+
 type DecodedLocal struct {
 	Name      string
 	Value     cty.Value
@@ -121,14 +184,25 @@ type DecodedModule struct {
 	Name           string
 	Inputs         DecodedVariableMap
 	Locals         DecodedLocalsMap
+	Outputs        DecodedOutputMap
+	Data           DecodedDataMap
 	Annotations    DecodedAnnotationsMap
 	Resources      DecodedResourceMap
 	ModuleCalls    DecodedModuleCallMap
 	Modules        DecodedModuleMap
 	BackendStorage *DecodedBackendStorage
-	Depth          int
-	DependsOn      []hcl.Traversal
-	Dependencies   []DependsOn
+	Clusters       DecodedClusterMap
+	// Moved contains the moved blocks of the module, nil when there are none
+	Moved     DecodedMovedList
+	Cluster   string
+	Depth     int
+	DependsOn []hcl.Traversal
+	// References contains the modules referenced by the arguments of the module call
+	References   []hcl.Traversal
+	Dependencies []DependsOn
+	// Value is the value of the module outputs as referenced by the caller
+	// An object for a single module, a list for count and a map for for_each
+	Value cty.Value
 }
 
 type DecodedModuleMap map[string]*DecodedModule
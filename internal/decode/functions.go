@@ -10,6 +10,7 @@ package decode
 
 import (
 	"github.com/hashicorp/hcl/v2/ext/tryfunc"
+	"github.com/spf13/afero"
 	ctyyaml "github.com/zclconf/go-cty-yaml"
 	"github.com/zclconf/go-cty/cty"
 	"github.com/zclconf/go-cty/cty/function"
@@ -17,7 +18,7 @@ import (
 	"kubehcl.sh/kubehcl/internal/funcs"
 )
 
-func makeBaseFunctionTable(baseDir string) map[string]function.Function {
+func makeBaseFunctionTable(fsys afero.Fs, baseDir string) map[string]function.Function {
 	// Some of our functions are just directly the cty stdlib functions.
 	// Others are implemented in the subdirectory "funcs" here in this
 	// repository. New functions should generally start out their lives
@@ -57,16 +58,16 @@ func makeBaseFunctionTable(baseDir string) map[string]function.Function {
 		"element":          stdlib.ElementFunc,
 		"endswith":         funcs.EndsWithFunc,
 		"chunklist":        stdlib.ChunklistFunc,
-		"file":             funcs.MakeFileFunc(baseDir, false),
-		"fileexists":       funcs.MakeFileExistsFunc(baseDir),
-		"fileset":          funcs.MakeFileSetFunc(baseDir),
-		"filebase64":       funcs.MakeFileFunc(baseDir, true),
-		"filebase64sha256": funcs.MakeFileBase64Sha256Func(baseDir),
-		"filebase64sha512": funcs.MakeFileBase64Sha512Func(baseDir),
-		"filemd5":          funcs.MakeFileMd5Func(baseDir),
-		"filesha1":         funcs.MakeFileSha1Func(baseDir),
-		"filesha256":       funcs.MakeFileSha256Func(baseDir),
-		"filesha512":       funcs.MakeFileSha512Func(baseDir),
+		"file":             funcs.MakeFileFunc(fsys, baseDir, false),
+		"fileexists":       funcs.MakeFileExistsFunc(fsys, baseDir),
+		"fileset":          funcs.MakeFileSetFunc(fsys, baseDir),
+		"filebase64":       funcs.MakeFileFunc(fsys, baseDir, true),
+		"filebase64sha256": funcs.MakeFileBase64Sha256Func(fsys, baseDir),
+		"filebase64sha512": funcs.MakeFileBase64Sha512Func(fsys, baseDir),
+		"filemd5":          funcs.MakeFileMd5Func(fsys, baseDir),
+		"filesha1":         funcs.MakeFileSha1Func(fsys, baseDir),
+		"filesha256":       funcs.MakeFileSha256Func(fsys, baseDir),
+		"filesha512":       funcs.MakeFileSha512Func(fsys, baseDir),
 		"flatten":          stdlib.FlattenFunc,
 		"floor":            stdlib.FloorFunc,
 		"format":           stdlib.FormatFunc,
@@ -147,7 +148,7 @@ func makeBaseFunctionTable(baseDir string) map[string]function.Function {
 		"zipmap":           stdlib.ZipmapFunc,
 	}
 
-	ret["templatefile"] = funcs.MakeTemplateFileFunc(baseDir, func() map[string]function.Function {
+	ret["templatefile"] = funcs.MakeTemplateFileFunc(fsys, baseDir, func() map[string]function.Function {
 		// The templatefile function prevents recursive calls to itself
 		// by copying this map and overwriting the "templatefile" entry.
 		return ret
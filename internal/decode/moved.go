@@ -0,0 +1,69 @@
+package decode
+
+import (
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// DecodedMoved renames an address saved in the state before the state is compared with the configuration
+// From and To are full addresses such as module.foo.kube_resource.bar or kube_resource.bar[1]
+type DecodedMoved struct {
+	From string
+	To   string
+	// Module is set when both addresses are modules, every address inside the module is moved
+	Module    bool
+	DeclRange hcl.Range
+}
+
+type DecodedMovedList []*DecodedMoved
+
+func isInstance(address string) bool {
+	return strings.HasSuffix(address, "]")
+}
+
+// Returns the address the key is moved to, false when the move doesn't apply to the key
+// A resource moves all of its instances and a module moves every address inside of it
+func (m *DecodedMoved) Rewrite(key string) (string, bool) {
+	if key == m.From {
+		return m.To, true
+	}
+	if m.Module {
+		if rest, ok := strings.CutPrefix(key, m.From+"."); ok {
+			return m.To + "." + rest, true
+		}
+		return "", false
+	}
+	if isInstance(m.From) || isInstance(m.To) {
+		return "", false
+	}
+	if rest, ok := strings.CutPrefix(key, m.From+"["); ok {
+		return m.To + "[" + rest, true
+	}
+	return "", false
+}
+
+// Returns the final address of the key after following the chain of moves, false when the key is not moved
+// Each move is applied at most once so a cycle returns the address reached before the move repeats
+func (moves DecodedMovedList) Resolve(key string) (string, bool) {
+	used := make([]bool, len(moves))
+	moved := false
+	for {
+		next := false
+		for i, m := range moves {
+			if used[i] {
+				continue
+			}
+			if to, ok := m.Rewrite(key); ok {
+				key = to
+				used[i] = true
+				moved = true
+				next = true
+				break
+			}
+		}
+		if !next {
+			return key, moved
+		}
+	}
+}
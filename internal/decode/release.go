@@ -0,0 +1,59 @@
+package decode
+
+import (
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Release describes the release being decoded and the default cluster it is deployed to
+// It is exposed to every module as release and the capabilities of its cluster as cluster
+type Release struct {
+	Name      string
+	Namespace string
+	// Revision is the revision being deployed, it starts at 1 and increases with every install of the release
+	Revision  int
+	IsInstall bool
+	IsUpgrade bool
+	// Capabilities of the default cluster
+	Capabilities
+}
+
+// Capabilities describe a cluster the release is deployed to
+type Capabilities struct {
+	// KubeVersion is the version of the cluster such as v1.30.2
+	KubeVersion string
+	// APIVersions are the group/versions and group/version/kinds served by the cluster such as policy/v1 and apps/v1/Deployment
+	APIVersions []string
+}
+
+// Returns the value of release
+func (r *Release) value() cty.Value {
+	if r == nil {
+		r = &Release{}
+	}
+	return cty.ObjectVal(map[string]cty.Value{
+		"name":       cty.StringVal(r.Name),
+		"namespace":  cty.StringVal(r.Namespace),
+		"revision":   cty.NumberIntVal(int64(r.Revision)),
+		"is_install": cty.BoolVal(r.IsInstall),
+		"is_upgrade": cty.BoolVal(r.IsUpgrade),
+	})
+}
+
+// Returns the value of cluster
+func (c *Capabilities) value() cty.Value {
+	if c == nil {
+		c = &Capabilities{}
+	}
+	apiVersions := cty.SetValEmpty(cty.String)
+	if len(c.APIVersions) > 0 {
+		vals := make([]cty.Value, 0, len(c.APIVersions))
+		for _, apiVersion := range c.APIVersions {
+			vals = append(vals, cty.StringVal(apiVersion))
+		}
+		apiVersions = cty.SetVal(vals)
+	}
+	return cty.ObjectVal(map[string]cty.Value{
+		"version":      cty.StringVal(c.KubeVersion),
+		"api_versions": apiVersions,
+	})
+}
@@ -0,0 +1,59 @@
+package decode
+
+import (
+	"os"
+
+	"github.com/spf13/afero"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Scope is where the module reads its files from and the release it is decoded for
+// Relative paths given to the file functions are resolved against the folder of the module within Fs
+// Fs is the in-memory filesystem of modules pulled from a repo and the os filesystem otherwise
+type Scope struct {
+	Fs afero.Fs
+	// Folder of the module where the expression is declared
+	Module string
+	// Folder of the root module
+	Root string
+	// Release is exposed as release, nil when decoded without a release
+	Release *Release
+	// Cluster is exposed as cluster, nil when the module is deployed to the default cluster of the release
+	Cluster *Capabilities
+}
+
+// The scope of a context created without a module, files are read from the working directory
+func (s *Scope) orDefault() *Scope {
+	if s != nil {
+		return s
+	}
+	return &Scope{
+		Fs:     afero.NewOsFs(),
+		Module: ".",
+		Root:   ".",
+	}
+}
+
+// Returns the value of path which exposes path.module, path.root and path.cwd
+func (s *Scope) pathValue() cty.Value {
+	cwd, err := os.Getwd()
+	if err != nil {
+		cwd = "."
+	}
+	return cty.ObjectVal(map[string]cty.Value{
+		"module": cty.StringVal(s.Module),
+		"root":   cty.StringVal(s.Root),
+		"cwd":    cty.StringVal(cwd),
+	})
+}
+
+// Returns the value of cluster which exposes the capabilities of the cluster the module is deployed to
+func (s *Scope) clusterValue() cty.Value {
+	if s.Cluster != nil {
+		return s.Cluster.value()
+	}
+	if s.Release != nil {
+		return s.Release.Capabilities.value()
+	}
+	return (*Capabilities)(nil).value()
+}
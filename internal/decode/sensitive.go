@@ -0,0 +1,87 @@
+package decode
+
+import (
+	"github.com/zclconf/go-cty/cty"
+	"kubehcl.sh/kubehcl/internal/lang/marks"
+)
+
+// Redacted replaces sensitive values in the plan and template output
+const Redacted = "(sensitive value)"
+
+// Removes the marks of the value so it can be converted to json
+// Returns the paths of the values which were marked as sensitive, nil when there are none
+func unmarkSensitive(val cty.Value) (cty.Value, []cty.Path) {
+	unmarked, pvm := val.UnmarkDeepWithPaths()
+	var paths []cty.Path
+	for _, pv := range pvm {
+		if _, sensitive := pv.Marks[marks.Sensitive]; sensitive {
+			paths = append(paths, pv.Path)
+		}
+	}
+	return unmarked, paths
+}
+
+// Returns the config of the deployable with the sensitive values marked again
+// This is the value seen by expressions which reference the deployable
+func (d *DecodedDeployable) MarkedConfig(key string) cty.Value {
+	config := d.Config[key]
+	paths := d.Sensitive[key]
+	if len(paths) == 0 {
+		return config
+	}
+	pvm := make([]cty.PathValueMarks, 0, len(paths))
+	for _, path := range paths {
+		pvm = append(pvm, cty.PathValueMarks{Path: path, Marks: cty.NewValueMarks(marks.Sensitive)})
+	}
+	return config.MarkWithPaths(pvm)
+}
+
+// Redact replaces the values at the sensitive paths with Redacted
+// Collections containing a redacted value become tuples and objects since the type of their elements changes
+func Redact(val cty.Value, paths []cty.Path) cty.Value {
+	if len(paths) == 0 {
+		return val
+	}
+	return redact(val, cty.Path{}, paths)
+}
+
+func redact(val cty.Value, path cty.Path, paths []cty.Path) cty.Value {
+	contains := false
+	for _, sensitive := range paths {
+		if sensitive.Equals(path) {
+			return cty.StringVal(Redacted)
+		}
+		if len(sensitive) > len(path) && sensitive.HasPrefix(path) {
+			contains = true
+		}
+	}
+	if !contains || val.IsNull() || !val.IsKnown() {
+		return val
+	}
+
+	ty := val.Type()
+	switch {
+	case ty.IsObjectType() || ty.IsMapType():
+		attrs := make(map[string]cty.Value)
+		for it := val.ElementIterator(); it.Next(); {
+			key, elem := it.Element()
+			elemPath := path.GetAttr(key.AsString())
+			if ty.IsMapType() {
+				elemPath = path.Index(key)
+			}
+			attrs[key.AsString()] = redact(elem, elemPath, paths)
+		}
+		return cty.ObjectVal(attrs)
+	case ty.IsListType() || ty.IsTupleType() || ty.IsSetType():
+		var elems []cty.Value
+		for it := val.ElementIterator(); it.Next(); {
+			key, elem := it.Element()
+			elems = append(elems, redact(elem, path.Index(key), paths))
+		}
+		if len(elems) == 0 {
+			return cty.EmptyTupleVal
+		}
+		return cty.TupleVal(elems)
+	}
+	return val
+}
@@ -0,0 +1,56 @@
+package decode
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+	"kubehcl.sh/kubehcl/internal/lang/marks"
+)
+
+func Test_Redact(t *testing.T) {
+	config := cty.ObjectVal(map[string]cty.Value{
+		"kind": cty.StringVal("Secret"),
+		"data": cty.MapVal(map[string]cty.Value{
+			"user":     cty.StringVal("admin"),
+			"password": cty.StringVal("secret").Mark(marks.Sensitive),
+		}),
+		"ports": cty.ListVal([]cty.Value{cty.NumberIntVal(80), cty.NumberIntVal(443).Mark(marks.Sensitive)}),
+	})
+
+	unmarked, paths := unmarkSensitive(config)
+	if marks.Contains(unmarked, marks.Sensitive) {
+		t.Fatalf("Expected the config to be unmarked got %#v", unmarked)
+	}
+	if len(paths) != 2 {
+		t.Fatalf("Expected 2 sensitive paths got %#v", paths)
+	}
+
+	redacted := Redact(unmarked, paths)
+	want := cty.ObjectVal(map[string]cty.Value{
+		"kind": cty.StringVal("Secret"),
+		"data": cty.ObjectVal(map[string]cty.Value{
+			"user":     cty.StringVal("admin"),
+			"password": cty.StringVal(Redacted),
+		}),
+		"ports": cty.TupleVal([]cty.Value{cty.NumberIntVal(80), cty.StringVal(Redacted)}),
+	})
+	if !redacted.RawEquals(want) {
+		t.Errorf("Expected %#v got %#v", want, redacted)
+	}
+
+	if !Redact(unmarked, nil).RawEquals(unmarked) {
+		t.Errorf("Expected the config to stay the same without sensitive paths")
+	}
+
+	dD := &DecodedDeployable{
+		Config:    map[string]cty.Value{"kube_resource.secret": unmarked},
+		Sensitive: map[string][]cty.Path{"kube_resource.secret": paths},
+	}
+	marked := dD.MarkedConfig("kube_resource.secret")
+	if !marked.GetAttr("data").Index(cty.StringVal("password")).HasMark(marks.Sensitive) {
+		t.Errorf("Expected the password to be marked again got %#v", marked)
+	}
+	if marked.GetAttr("kind").HasMark(marks.Sensitive) {
+		t.Errorf("Expected the kind to stay unmarked got %#v", marked)
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/hashicorp/hcl/v2"
 	"github.com/zclconf/go-cty/cty"
 	"github.com/zclconf/go-cty/cty/convert"
+	"kubehcl.sh/kubehcl/internal/lang/marks"
 )
 
 const (
@@ -24,20 +25,26 @@ const (
 )
 
 // Creates hcl context with the variables and locals to decode all values within the blocks
-func CreateContext(variables DecodedVariableMap, locals DecodedLocalsMap) (*hcl.EvalContext, hcl.Diagnostics) {
+// The file functions, path, release and cluster values are resolved from the scope of the module, a nil scope uses the working directory
+func CreateContext(variables DecodedVariableMap, locals DecodedLocalsMap, scope *Scope) (*hcl.EvalContext, hcl.Diagnostics) {
 	variableMap, diags := variables.getMapValues()
 	localMap := locals.getMapValues()
 	maps.Copy(variableMap, localMap)
+	scope = scope.orDefault()
+	variableMap["path"] = scope.pathValue()
+	variableMap["release"] = scope.Release.value()
+	variableMap["cluster"] = scope.clusterValue()
 	// fmt.Printf("%s",vals["var"].AsValueMap())
 	return &hcl.EvalContext{
 		Variables: variableMap,
-		Functions: makeBaseFunctionTable("./"),
+		Functions: makeBaseFunctionTable(scope.Fs, scope.Module),
 	}, diags
 }
 
 // Decode count expression into a cty.value with type number
 func decodeCountExpr(ctx *hcl.EvalContext, expr hcl.Expression) (cty.Value, hcl.Diagnostics) {
 	val, diags := expr.Value(ctx)
+	val, diags = unmarkMetaArgument(ctx, expr, val, "count", diags)
 	if countVal, err := convert.Convert(val, cty.Number); err != nil {
 		diags = append(diags, &hcl.Diagnostic{
 			Severity: hcl.DiagError,
@@ -59,6 +66,7 @@ func decodeCountExpr(ctx *hcl.EvalContext, expr hcl.Expression) (cty.Value, hcl.
 // Decode for each expression into a set,object or map type
 func decodeForExpr(ctx *hcl.EvalContext, expr hcl.Expression) (cty.Value, hcl.Diagnostics) {
 	val, diags := expr.Value(ctx)
+	val, diags = unmarkMetaArgument(ctx, expr, val, "for_each", diags)
 	ty := val.Type()
 	var isAllowedType bool
 	allowedTypesMessage := "map, or set of strings"
@@ -76,3 +84,21 @@ func decodeForExpr(ctx *hcl.EvalContext, expr hcl.Expression) (cty.Value, hcl.Di
 	}
 	return val, diags
 }
+
+// Sensitive values can't be used in count and for_each since they appear in the addresses of the resources
+// The value is returned unmarked so decoding can continue
+func unmarkMetaArgument(ctx *hcl.EvalContext, expr hcl.Expression, val cty.Value, name string, diags hcl.Diagnostics) (cty.Value, hcl.Diagnostics) {
+	if !marks.Contains(val, marks.Sensitive) {
+		return val, diags
+	}
+	diags = append(diags, &hcl.Diagnostic{
+		Severity:    hcl.DiagError,
+		Summary:     fmt.Sprintf("Invalid %s argument", name),
+		Detail:      fmt.Sprintf("Sensitive values, or values derived from sensitive values, cannot be used as %s arguments since they appear in the addresses of the resources.", name),
+		Subject:     expr.Range().Ptr(),
+		Expression:  expr,
+		EvalContext: ctx,
+	})
+	unmarked, _ := val.UnmarkDeep()
+	return unmarked, diags
+}
@@ -133,7 +133,7 @@ func Test_utils(t *testing.T) {
 
 	for _, test := range tests {
 
-		ctx, diags := CreateContext(test.variables, test.locals)
+		ctx, diags := CreateContext(test.variables, test.locals, nil)
 		if diags.HasErrors() {
 			t.Errorf("Error received and was not expected: %s", diags.Errs())
 		} else {
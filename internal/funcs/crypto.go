@@ -25,6 +25,7 @@ import (
 
 	uuidv5 "github.com/google/uuid"
 	uuid "github.com/hashicorp/go-uuid"
+	"github.com/spf13/afero"
 	"github.com/zclconf/go-cty/cty"
 	"github.com/zclconf/go-cty/cty/function"
 	"github.com/zclconf/go-cty/cty/gocty"
@@ -85,8 +86,8 @@ var Base64Sha256Func = makeStringHashFunction(sha256.New, base64.StdEncoding.Enc
 
 // MakeFileBase64Sha256Func constructs a function that is like Base64Sha256Func but reads the
 // contents of a file rather than hashing a given literal string.
-func MakeFileBase64Sha256Func(baseDir string) function.Function {
-	return makeFileHashFunction(baseDir, sha256.New, base64.StdEncoding.EncodeToString)
+func MakeFileBase64Sha256Func(fsys afero.Fs, baseDir string) function.Function {
+	return makeFileHashFunction(fsys, baseDir, sha256.New, base64.StdEncoding.EncodeToString)
 }
 
 // Base64Sha512Func constructs a function that computes the SHA256 hash of a given string
@@ -95,8 +96,8 @@ var Base64Sha512Func = makeStringHashFunction(sha512.New, base64.StdEncoding.Enc
 
 // MakeFileBase64Sha512Func constructs a function that is like Base64Sha512Func but reads the
 // contents of a file rather than hashing a given literal string.
-func MakeFileBase64Sha512Func(baseDir string) function.Function {
-	return makeFileHashFunction(baseDir, sha512.New, base64.StdEncoding.EncodeToString)
+func MakeFileBase64Sha512Func(fsys afero.Fs, baseDir string) function.Function {
+	return makeFileHashFunction(fsys, baseDir, sha512.New, base64.StdEncoding.EncodeToString)
 }
 
 // BcryptFunc constructs a function that computes a hash of the given string using the Blowfish cipher.
@@ -143,8 +144,8 @@ var Md5Func = makeStringHashFunction(md5.New, hex.EncodeToString)
 
 // MakeFileMd5Func constructs a function that is like Md5Func but reads the
 // contents of a file rather than hashing a given literal string.
-func MakeFileMd5Func(baseDir string) function.Function {
-	return makeFileHashFunction(baseDir, md5.New, hex.EncodeToString)
+func MakeFileMd5Func(fsys afero.Fs, baseDir string) function.Function {
+	return makeFileHashFunction(fsys, baseDir, md5.New, hex.EncodeToString)
 }
 
 // RsaDecryptFunc constructs a function that decrypts an RSA-encrypted ciphertext.
@@ -203,8 +204,8 @@ var Sha1Func = makeStringHashFunction(sha1.New, hex.EncodeToString)
 
 // MakeFileSha1Func constructs a function that is like Sha1Func but reads the
 // contents of a file rather than hashing a given literal string.
-func MakeFileSha1Func(baseDir string) function.Function {
-	return makeFileHashFunction(baseDir, sha1.New, hex.EncodeToString)
+func MakeFileSha1Func(fsys afero.Fs, baseDir string) function.Function {
+	return makeFileHashFunction(fsys, baseDir, sha1.New, hex.EncodeToString)
 }
 
 // Sha256Func constructs a function that computes the SHA256 hash of a given string
@@ -213,8 +214,8 @@ var Sha256Func = makeStringHashFunction(sha256.New, hex.EncodeToString)
 
 // MakeFileSha256Func constructs a function that is like Sha256Func but reads the
 // contents of a file rather than hashing a given literal string.
-func MakeFileSha256Func(baseDir string) function.Function {
-	return makeFileHashFunction(baseDir, sha256.New, hex.EncodeToString)
+func MakeFileSha256Func(fsys afero.Fs, baseDir string) function.Function {
+	return makeFileHashFunction(fsys, baseDir, sha256.New, hex.EncodeToString)
 }
 
 // Sha512Func constructs a function that computes the SHA512 hash of a given string
@@ -223,8 +224,8 @@ var Sha512Func = makeStringHashFunction(sha512.New, hex.EncodeToString)
 
 // MakeFileSha512Func constructs a function that is like Sha512Func but reads the
 // contents of a file rather than hashing a given literal string.
-func MakeFileSha512Func(baseDir string) function.Function {
-	return makeFileHashFunction(baseDir, sha512.New, hex.EncodeToString)
+func MakeFileSha512Func(fsys afero.Fs, baseDir string) function.Function {
+	return makeFileHashFunction(fsys, baseDir, sha512.New, hex.EncodeToString)
 }
 
 func makeStringHashFunction(hf func() hash.Hash, enc func([]byte) string) function.Function {
@@ -247,7 +248,7 @@ func makeStringHashFunction(hf func() hash.Hash, enc func([]byte) string) functi
 	})
 }
 
-func makeFileHashFunction(baseDir string, hf func() hash.Hash, enc func([]byte) string) function.Function {
+func makeFileHashFunction(fsys afero.Fs, baseDir string, hf func() hash.Hash, enc func([]byte) string) function.Function {
 	return function.New(&function.Spec{
 		Params: []function.Parameter{
 			{
@@ -259,7 +260,7 @@ func makeFileHashFunction(baseDir string, hf func() hash.Hash, enc func([]byte)
 		RefineResult: refineNotNull,
 		Impl: func(args []cty.Value, retType cty.Type) (ret cty.Value, err error) {
 			path := args[0].AsString()
-			f, err := openFile(baseDir, path)
+			f, err := openFile(fsys, baseDir, path)
 			if err != nil {
 				return cty.UnknownVal(cty.String), err
 			}
@@ -24,6 +24,7 @@ import (
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/afero"
 	"github.com/zclconf/go-cty/cty"
 	"github.com/zclconf/go-cty/cty/function"
 )
@@ -31,7 +32,7 @@ import (
 // MakeFileFunc constructs a function that takes a file path and returns the
 // contents of that file, either directly as a string (where valid UTF-8 is
 // required) or as a string containing base64 bytes.
-func MakeFileFunc(baseDir string, encBase64 bool) function.Function {
+func MakeFileFunc(fsys afero.Fs, baseDir string, encBase64 bool) function.Function {
 	return function.New(&function.Spec{
 		Params: []function.Parameter{
 			{
@@ -45,7 +46,7 @@ func MakeFileFunc(baseDir string, encBase64 bool) function.Function {
 		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
 			pathArg, pathMarks := args[0].Unmark()
 			path := pathArg.AsString()
-			src, err := readFileBytes(baseDir, path, pathMarks)
+			src, err := readFileBytes(fsys, baseDir, path, pathMarks)
 			if err != nil {
 				err = function.NewArgError(0, err)
 				return cty.UnknownVal(cty.String), err
@@ -121,10 +122,10 @@ func (err ErrorTemplateRecursionLimit) Error() string {
 //
 // As a special exception, a referenced template file may call the templatefile
 // function, with a recursion depth limit providing an error when reached
-func MakeTemplateFileFunc(baseDir string, funcsCb func() map[string]function.Function) function.Function {
-	return makeTemplateFileFuncImpl(baseDir, funcsCb, 0)
+func MakeTemplateFileFunc(fsys afero.Fs, baseDir string, funcsCb func() map[string]function.Function) function.Function {
+	return makeTemplateFileFuncImpl(fsys, baseDir, funcsCb, 0)
 }
-func makeTemplateFileFuncImpl(baseDir string, funcsCb func() map[string]function.Function, depth int) function.Function {
+func makeTemplateFileFuncImpl(fsys afero.Fs, baseDir string, funcsCb func() map[string]function.Function, depth int) function.Function {
 	params := []function.Parameter{
 		{
 			Name:        "path",
@@ -149,7 +150,7 @@ func makeTemplateFileFuncImpl(baseDir string, funcsCb func() map[string]function
 
 		// We re-use File here to ensure the same filename interpretation
 		// as it does, along with its other safety checks.
-		templateValue, err := File(baseDir, cty.StringVal(path).WithMarks(marks))
+		templateValue, err := File(fsys, baseDir, cty.StringVal(path).WithMarks(marks))
 		if err != nil {
 			return nil, err
 		}
@@ -171,7 +172,7 @@ func makeTemplateFileFuncImpl(baseDir string, funcsCb func() map[string]function
 		for name, fn := range givenFuncs {
 			if name == "templatefile" {
 				// Increment the recursion depth counter
-				funcs[name] = makeTemplateFileFuncImpl(baseDir, funcsCb, depth+1)
+				funcs[name] = makeTemplateFileFuncImpl(fsys, baseDir, funcsCb, depth+1)
 				continue
 			}
 			funcs[name] = fn
@@ -216,7 +217,7 @@ func makeTemplateFileFuncImpl(baseDir string, funcsCb func() map[string]function
 
 // MakeFileExistsFunc constructs a function that takes a path
 // and determines whether a file exists at that path
-func MakeFileExistsFunc(baseDir string) function.Function {
+func MakeFileExistsFunc(fsys afero.Fs, baseDir string) function.Function {
 	return function.New(&function.Spec{
 		Params: []function.Parameter{
 			{
@@ -242,7 +243,7 @@ func MakeFileExistsFunc(baseDir string) function.Function {
 			// Ensure that the path is canonical for the host OS
 			path = filepath.Clean(path)
 
-			fi, err := os.Stat(path)
+			fi, err := fsys.Stat(path)
 			if err != nil {
 				if os.IsNotExist(err) {
 					return cty.False.WithMarks(pathMarks), nil
@@ -284,7 +285,7 @@ func MakeFileExistsFunc(baseDir string) function.Function {
 
 // MakeFileSetFunc constructs a function that takes a glob pattern
 // and enumerates a file set from that pattern
-func MakeFileSetFunc(baseDir string) function.Function {
+func MakeFileSetFunc(fsys afero.Fs, baseDir string) function.Function {
 	return function.New(&function.Spec{
 		Params: []function.Parameter{
 			{
@@ -317,14 +318,14 @@ func MakeFileSetFunc(baseDir string) function.Function {
 			// automatically cleaned during this operation.
 			pattern = filepath.Join(path, pattern)
 
-			matches, err := doublestar.FilepathGlob(pattern)
+			matches, err := globFiles(fsys, pattern)
 			if err != nil {
 				return cty.UnknownVal(cty.Set(cty.String)), fmt.Errorf("failed to glob pattern %s: %w", redactIfSensitive(pattern, marks...), err)
 			}
 
 			var matchVals []cty.Value
 			for _, match := range matches {
-				fi, err := os.Stat(match)
+				fi, err := fsys.Stat(match)
 
 				if err != nil {
 					return cty.UnknownVal(cty.Set(cty.String)), fmt.Errorf("failed to stat %s: %w", redactIfSensitive(match, marks...), err)
@@ -357,6 +358,28 @@ func MakeFileSetFunc(baseDir string) function.Function {
 	})
 }
 
+// globFiles returns the paths of fsys which match the pattern
+// The files are walked from the part of the pattern which has no wildcards
+func globFiles(fsys afero.Fs, pattern string) ([]string, error) {
+	if !doublestar.ValidatePathPattern(pattern) {
+		return nil, doublestar.ErrBadPattern
+	}
+	base, _ := doublestar.SplitPattern(filepath.ToSlash(pattern))
+
+	var matches []string
+	err := afero.Walk(fsys, filepath.FromSlash(base), func(path string, info os.FileInfo, err error) error {
+		// Folders which don't exist or can't be read don't match anything
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		if matched, _ := doublestar.PathMatch(pattern, path); matched {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	return matches, err
+}
+
 // BasenameFunc constructs a function that takes a string containing a filesystem path
 // and removes all except the last portion from it.
 var BasenameFunc = function.New(&function.Spec{
@@ -422,7 +445,7 @@ var PathExpandFunc = function.New(&function.Spec{
 	},
 })
 
-func openFile(baseDir, path string) (*os.File, error) {
+func openFile(fsys afero.Fs, baseDir, path string) (afero.File, error) {
 	path, err := homedir.Expand(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to expand ~: %w", err)
@@ -435,11 +458,11 @@ func openFile(baseDir, path string) (*os.File, error) {
 	// Ensure that the path is canonical for the host OS
 	path = filepath.Clean(path)
 
-	return os.Open(path)
+	return fsys.Open(path)
 }
 
-func readFileBytes(baseDir, path string, marks cty.ValueMarks) ([]byte, error) {
-	f, err := openFile(baseDir, path)
+func readFileBytes(fsys afero.Fs, baseDir, path string, marks cty.ValueMarks) ([]byte, error) {
+	f, err := openFile(fsys, baseDir, path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			// An extra OpenTofu-specific hint for this situation
@@ -462,30 +485,33 @@ func readFileBytes(baseDir, path string, marks cty.ValueMarks) ([]byte, error) {
 // The file must contain valid UTF-8 bytes, or this function will return an error.
 //
 // The underlying function implementation works relative to a particular base
-// directory, so this wrapper takes a base directory string and uses it to
-// construct the underlying function before calling it.
-func File(baseDir string, path cty.Value) (cty.Value, error) {
-	fn := MakeFileFunc(baseDir, false)
+// directory of a filesystem, so this wrapper takes the filesystem and a base
+// directory string and uses them to construct the underlying function before
+// calling it.
+func File(fsys afero.Fs, baseDir string, path cty.Value) (cty.Value, error) {
+	fn := MakeFileFunc(fsys, baseDir, false)
 	return fn.Call([]cty.Value{path})
 }
 
 // FileExists determines whether a file exists at the given path.
 //
 // The underlying function implementation works relative to a particular base
-// directory, so this wrapper takes a base directory string and uses it to
-// construct the underlying function before calling it.
-func FileExists(baseDir string, path cty.Value) (cty.Value, error) {
-	fn := MakeFileExistsFunc(baseDir)
+// directory of a filesystem, so this wrapper takes the filesystem and a base
+// directory string and uses them to construct the underlying function before
+// calling it.
+func FileExists(fsys afero.Fs, baseDir string, path cty.Value) (cty.Value, error) {
+	fn := MakeFileExistsFunc(fsys, baseDir)
 	return fn.Call([]cty.Value{path})
 }
 
 // FileSet enumerates a set of files given a glob pattern
 //
 // The underlying function implementation works relative to a particular base
-// directory, so this wrapper takes a base directory string and uses it to
-// construct the underlying function before calling it.
-func FileSet(baseDir string, path, pattern cty.Value) (cty.Value, error) {
-	fn := MakeFileSetFunc(baseDir)
+// directory of a filesystem, so this wrapper takes the filesystem and a base
+// directory string and uses them to construct the underlying function before
+// calling it.
+func FileSet(fsys afero.Fs, baseDir string, path, pattern cty.Value) (cty.Value, error) {
+	fn := MakeFileSetFunc(fsys, baseDir)
 	return fn.Call([]cty.Value{path, pattern})
 }
 
@@ -494,10 +520,11 @@ func FileSet(baseDir string, path, pattern cty.Value) (cty.Value, error) {
 // The bytes from the file are encoded as base64 before returning.
 //
 // The underlying function implementation works relative to a particular base
-// directory, so this wrapper takes a base directory string and uses it to
-// construct the underlying function before calling it.
-func FileBase64(baseDir string, path cty.Value) (cty.Value, error) {
-	fn := MakeFileFunc(baseDir, true)
+// directory of a filesystem, so this wrapper takes the filesystem and a base
+// directory string and uses them to construct the underlying function before
+// calling it.
+func FileBase64(fsys afero.Fs, baseDir string, path cty.Value) (cty.Value, error) {
+	fn := MakeFileFunc(fsys, baseDir, true)
 	return fn.Call([]cty.Value{path})
 }
 
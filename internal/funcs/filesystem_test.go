@@ -17,6 +17,7 @@ import (
 	"testing"
 
 	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/afero"
 	"github.com/zclconf/go-cty/cty"
 	"github.com/zclconf/go-cty/cty/function"
 	"github.com/zclconf/go-cty/cty/function/stdlib"
@@ -59,7 +60,7 @@ func TestFile(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("File(\".\", %#v)", test.Path), func(t *testing.T) {
-			got, err := File(".", test.Path)
+			got, err := File(afero.NewOsFs(), ".", test.Path)
 
 			if test.Err != "" {
 				if err == nil {
@@ -204,10 +205,10 @@ func TestTemplateFile(t *testing.T) {
 		},
 	}
 
-	templateFileFn := MakeTemplateFileFunc(".", func() map[string]function.Function {
+	templateFileFn := MakeTemplateFileFunc(afero.NewOsFs(), ".", func() map[string]function.Function {
 		return map[string]function.Function{
 			"join":         stdlib.JoinFunc,
-			"templatefile": MakeFileFunc(".", false), // just a placeholder, since templatefile itself overrides this
+			"templatefile": MakeFileFunc(afero.NewOsFs(), ".", false), // just a placeholder, since templatefile itself overrides this
 		}
 	})
 
@@ -335,7 +336,7 @@ func TestFileExists(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("FileExists(\".\", %#v)", test.Path), func(t *testing.T) {
-			got, err := FileExists(".", test.Path)
+			got, err := FileExists(afero.NewOsFs(), ".", test.Path)
 
 			if test.Err != "" {
 				if err == nil {
@@ -557,7 +558,7 @@ func TestFileSet(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("FileSet(\".\", %#v, %#v)", test.Path, test.Pattern), func(t *testing.T) {
-			got, err := FileSet(".", test.Path, test.Pattern)
+			got, err := FileSet(afero.NewOsFs(), ".", test.Path, test.Pattern)
 
 			if test.Err != "" {
 				if err == nil {
@@ -603,7 +604,7 @@ func TestFileBase64(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("FileBase64(\".\", %#v)", test.Path), func(t *testing.T) {
-			got, err := FileBase64(".", test.Path)
+			got, err := FileBase64(afero.NewOsFs(), ".", test.Path)
 
 			if test.Err {
 				if err == nil {
@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"io"
 	"log/slog"
 	"os"
 )
@@ -8,10 +9,14 @@ import (
 var KubeLogger *slog.Logger
 
 func SetLogger(isDebug bool) {
+	SetLoggerWriter(isDebug, os.Stdout)
+}
+
+// SetLoggerWriter writes the debug logs to w
+func SetLoggerWriter(isDebug bool, w io.Writer) {
 	if isDebug {
-		KubeLogger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+		KubeLogger = slog.New(slog.NewTextHandler(w, nil))
 	} else {
 		KubeLogger = slog.New(slog.DiscardHandler)
 	}
-
 }
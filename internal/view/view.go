@@ -20,9 +20,11 @@ import (
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/mitchellh/colorstring"
+	"github.com/zclconf/go-cty/cty"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"kubehcl.sh/kubehcl/internal/configs"
+	"kubehcl.sh/kubehcl/internal/decode"
 	"kubehcl.sh/kubehcl/internal/format"
 	"kubehcl.sh/kubehcl/internal/terminal"
 	"kubehcl.sh/kubehcl/internal/tfdiags"
@@ -45,6 +47,8 @@ type ResourceChange struct {
 	FromValue any
 	ToValue   any
 	Status    Status
+	// Sensitive changes are printed without their values
+	Sensitive bool
 }
 
 func (r *ResourceChange) hasNext() bool {
@@ -76,6 +80,12 @@ func (r *ResourceChange) nextKind() Kind {
 type CompareResources struct {
 	Current runtime.Object
 	Wanted  runtime.Object
+	// Replace marks resources which are deleted and created again instead of being modified
+	Replace bool
+	// Sensitive contains the paths of the sensitive values of the wanted resource
+	Sensitive []cty.Path
+	// MovedFrom is the address the resource was saved under before it was moved by a moved block
+	MovedFrom string
 }
 
 type ViewArgs struct {
@@ -95,6 +105,11 @@ type ViewArgs struct {
 	Concise bool
 
 	// ShowSensitive is used to display the value of variables marked as sensitive.
+	ShowSensitive bool
+
+	// Stderr prints warnings to stderr as well, used when stdout is reserved
+	// for machine readable output.
+	Stderr bool
 }
 
 // View is the base layer for command views, encapsulating a set of I/O
@@ -122,6 +137,9 @@ type View struct {
 	// showSensitive is used to display the value of variables marked as sensitive.
 	showSensitive bool
 
+	// stderr prints warnings to stderr as well.
+	stderr bool
+
 	// This unfortunate wart is required to enable rendering of diagnostics which
 	// have associated source code in the configuration. This function pointer
 	// will be dereferenced as late as possible when rendering diagnostics in
@@ -166,6 +184,8 @@ func (v *View) Configure(view *ViewArgs) {
 	v.consolidateWarnings = view.ConsolidateWarnings
 	v.consolidateErrors = view.ConsolidateErrors
 	v.concise = view.Concise
+	v.showSensitive = view.ShowSensitive
+	v.stderr = view.Stderr
 }
 
 // SetConfigSources overrides the default no-op callback with a new function
@@ -207,7 +227,11 @@ func (v *View) Diagnostics(diags tfdiags.Diagnostics) {
 		if useCompact {
 			msg := format.DiagnosticWarningsCompact(diags, v.colorize)
 			msg = "\n" + msg + "\nTo see the full warning notes, run kubehcl without -compact-warnings.\n"
-			_, _ = v.streams.Print(msg)
+			if v.stderr {
+				_, _ = v.streams.Eprint(msg)
+			} else {
+				_, _ = v.streams.Print(msg)
+			}
 			return
 		}
 	}
@@ -220,7 +244,7 @@ func (v *View) Diagnostics(diags tfdiags.Diagnostics) {
 			msg = format.Diagnostic(diag, v.configSources(), v.colorize, v.streams.Stderr.Columns())
 		}
 
-		if diag.Severity() == tfdiags.Error {
+		if diag.Severity() == tfdiags.Error || v.stderr {
 			_, _ = v.streams.Eprint(msg)
 		} else {
 			_, _ = v.streams.Print(msg)
@@ -249,11 +273,35 @@ func (v *View) SetShowSensitive(showSensitive bool) {
 func (v *View) DiagPrinter(diags hcl.Diagnostics, viewDef *ViewArgs) {
 	v.SetConfigSources(configs.Parser().Files)
 	var d tfdiags.Diagnostics
+	if viewDef.ShowSensitive {
+		diags = unmarkDiagnostics(diags)
+	}
 	d = d.Append(diags)
 	v.Configure(viewDef)
 	v.Diagnostics(d)
 }
 
+// Removes the sensitive marks from the values of the diagnostics so they are displayed
+func unmarkDiagnostics(diags hcl.Diagnostics) hcl.Diagnostics {
+	unmarked := make(hcl.Diagnostics, 0, len(diags))
+	for _, diag := range diags {
+		if diag.EvalContext != nil {
+			copied := *diag
+			variables := make(map[string]cty.Value, len(diag.EvalContext.Variables))
+			for name, value := range diag.EvalContext.Variables {
+				variables[name], _ = value.UnmarkDeep()
+			}
+			copied.EvalContext = &hcl.EvalContext{
+				Variables: variables,
+				Functions: diag.EvalContext.Functions,
+			}
+			diag = &copied
+		}
+		unmarked = append(unmarked, diag)
+	}
+	return unmarked
+}
+
 func (v *View) PlanPrinter(m map[string]*CompareResources, viewDef *ViewArgs) {
 	v.SetConfigSources(configs.Parser().Files)
 	v.Configure(viewDef)
@@ -270,13 +318,26 @@ func (v *View) planPlainPrinter(m map[string]*CompareResources) {
 	_, _ = v.streams.Println("+ create")
 	_, _ = v.streams.Println("~ modify")
 	_, _ = v.streams.Println("- remove")
+	_, _ = v.streams.Println("-/+ replace")
 	_, _ = v.streams.Println()
 	_, _ = v.streams.Println("Kubehcl will perform the following actions:")
 	_, _ = v.streams.Println()
 	for key, value := range m {
-		changeMap := v.getChanges(value.Current, value.Wanted)
-		if len(changeMap) > 0 {
-			_, _ = v.streams.Printf("%s {", key)
+		changeMap := v.getChanges(value.Current, value.Wanted, value.Sensitive)
+		if value.MovedFrom != "" {
+			key = fmt.Sprintf("%s (moved from %s)", key, value.MovedFrom)
+			if len(changeMap) == 0 && !value.Replace {
+				_, _ = v.streams.Println(key)
+				_, _ = v.streams.Println()
+				continue
+			}
+		}
+		if len(changeMap) > 0 || value.Replace {
+			if value.Replace {
+				_, _ = v.streams.Printf("-/+ %s {", key)
+			} else {
+				_, _ = v.streams.Printf("%s {", key)
+			}
 			_, _ = v.streams.Println()
 			_, _ = v.streams.Println()
 			msg := v.StringifyChangeMap(changeMap, "")
@@ -481,7 +542,7 @@ func generateResourceChanges(from, to map[string]any) ResourceChangeMap {
 
 }
 
-func (v *View) getChanges(from, to runtime.Object) ResourceChangeMap {
+func (v *View) getChanges(from, to runtime.Object, sensitive []cty.Path) ResourceChangeMap {
 	var f map[string]any
 	if from == nil {
 		f = make(map[string]any)
@@ -496,14 +557,62 @@ func (v *View) getChanges(from, to runtime.Object) ResourceChangeMap {
 		t = to.(*unstructured.Unstructured).Object
 	}
 
-	return generateResourceChanges(f, t)
+	changeMap := generateResourceChanges(f, t)
+	markSensitiveChanges(changeMap, sensitive)
+	return changeMap
+
+}
+
+// Marks the changes at the sensitive paths, the changes below them are printed as a single sensitive value
+func markSensitiveChanges(changeMap ResourceChangeMap, paths []cty.Path) {
+	for _, path := range paths {
+		current := changeMap
+		for i, step := range path {
+			change, exists := current[changeKey(step)]
+			if !exists {
+				break
+			}
+			if i == len(path)-1 || !change.hasNext() {
+				change.Sensitive = true
+				break
+			}
+			current = change.ChangeMap
+		}
+	}
+}
 
+// Returns the key of the change map matching the step of the path
+func changeKey(step cty.PathStep) string {
+	switch step := step.(type) {
+	case cty.GetAttrStep:
+		return step.Name
+	case cty.IndexStep:
+		switch step.Key.Type() {
+		case cty.String:
+			return step.Key.AsString()
+		case cty.Number:
+			return fmt.Sprintf("[%s]", step.Key.AsBigFloat().Text('f', -1))
+		}
+	}
+	return ""
 }
 
 func (v *View) StringifyChangeMap(changeMap ResourceChangeMap, spaces string) string {
 	spaces += "   "
 	msg := ""
 	for key, value := range changeMap {
+		if value.Sensitive && !v.showSensitive {
+			newSpaces := spaces[:len(spaces)-2]
+			switch value.Status {
+			case ADDED:
+				msg += fmt.Sprintf("%s+++++ %s = %s\n", newSpaces, key, decode.Redacted)
+			case REMOVED:
+				msg += fmt.Sprintf("%s----- %s = %s\n", newSpaces, key, decode.Redacted)
+			default:
+				msg += fmt.Sprintf("%s~~~~~ %s = %s\n", newSpaces, key, decode.Redacted)
+			}
+			continue
+		}
 		// isListKey := strings.HasPrefix(key, "[") && strings.HasSuffix(key, "]")
 		switch value.nextKind() {
 		case MAP:
@@ -546,13 +655,24 @@ func (v *View) planColoredPrinter(m map[string]*CompareResources) {
 	_, _ = v.streams.Println(colorstring.Color("[bold][green]+[reset] create"))
 	_, _ = v.streams.Println(colorstring.Color("[bold][yellow]~[reset] modify"))
 	_, _ = v.streams.Println(colorstring.Color("[bold][red]-[reset] remove"))
+	_, _ = v.streams.Println(colorstring.Color("[bold][red]-[reset]/[bold][green]+[reset] replace"))
 	_, _ = v.streams.Println()
 	_, _ = v.streams.Println("Kubehcl will perform the following actions:")
 	_, _ = v.streams.Println()
 	for key, value := range m {
-		changeMap := v.getChanges(value.Current, value.Wanted)
-		if len(changeMap) > 0 {
-			if value.Current == nil {
+		changeMap := v.getChanges(value.Current, value.Wanted, value.Sensitive)
+		if value.MovedFrom != "" {
+			key = fmt.Sprintf("%s (moved from %s)", key, value.MovedFrom)
+			if len(changeMap) == 0 && !value.Replace {
+				_, _ = v.streams.Println(key)
+				_, _ = v.streams.Println()
+				continue
+			}
+		}
+		if len(changeMap) > 0 || value.Replace {
+			if value.Replace {
+				_, _ = v.streams.Printf("%s %s {", colorstring.Color("[bold][red]-[reset]/[bold][green]+[reset]"), key)
+			} else if value.Current == nil {
 				_, _ = v.streams.Printf("%s %s {", colorstring.Color("[bold][green]+[reset]"), key)
 			} else if value.Wanted == nil {
 				_, _ = v.streams.Printf("%s %s {", colorstring.Color("[bold][red]-[reset]"), key)
@@ -0,0 +1,288 @@
+/*
+This file was inspired from https://github.com/helm/helm
+This file has been modified from the original version
+Changes made to fit kubehcl purposes
+This file retains its' original license
+// SPDX-License-Identifier: Apache-2.0
+Licesne: https://www.apache.org/licenses/LICENSE-2.0
+*/
+package kubeclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"helm.sh/helm/v4/pkg/kube"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+	"k8s.io/apimachinery/pkg/util/mergepatch"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/cli-runtime/pkg/resource"
+	"kubehcl.sh/kubehcl/internal/decode"
+	"kubehcl.sh/kubehcl/internal/logging"
+	"kubehcl.sh/kubehcl/settings"
+)
+
+const (
+	ServerSideTrue  = "true"
+	ServerSideFalse = "false"
+	ServerSideAuto  = "auto"
+)
+
+// Applies the apply settings to the config
+func (cfg *Config) ConfigureApply(apply *settings.ApplySettings) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	switch apply.ServerSide {
+	case ServerSideTrue, ServerSideFalse, ServerSideAuto:
+	default:
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid server-side option",
+			Detail:   fmt.Sprintf("Server-side must be one of [true, false, auto] got: %s", apply.ServerSide),
+		})
+	}
+
+	if apply.ServerSide == ServerSideFalse && apply.ForceConflicts {
+		logging.KubeLogger.Info("force-conflicts is ignored when using client-side apply")
+	}
+
+	if apply.FieldManager == "" {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Field manager can't be empty",
+		})
+	}
+
+	if diags.HasErrors() {
+		return diags
+	}
+
+	cfg.FieldManager = apply.FieldManager
+	cfg.ForceConflicts = apply.ForceConflicts
+	cfg.ServerSide = apply.ServerSide
+	return diags
+}
+
+// Checks if the server rejected the server-side apply request because it doesn't support it
+// https://github.com/kubernetes/kubectl/blob/197123726db24c61aa0f78d1f0ba6e91a2ec2f35/pkg/cmd/apply/apply.go#L439
+func isIncompatibleServer(err error) bool {
+	var statusErr apierrors.APIStatus
+	return errors.As(err, &statusErr) && statusErr.Status().Code == http.StatusUnsupportedMediaType
+}
+
+// Sends the resource to the api server using server-side apply with the configured field manager
+// The resource is created if it doesn't exist
+func (cfg *Config) patchServerSide(target *resource.Info) (runtime.Object, error) {
+	helper := resource.NewHelper(target.Client, target.Mapping).
+		DryRun(cfg.DryRun).
+		WithFieldManager(cfg.FieldManager).
+		WithFieldValidation(string(kube.FieldValidationDirectiveStrict))
+
+	data, err := runtime.Encode(unstructured.UnstructuredJSONScheme, target.Object)
+	if err != nil {
+		return nil, err
+	}
+	force := cfg.ForceConflicts
+	return helper.Patch(target.Namespace, target.Name, types.ApplyPatchType, data, &metav1.PatchOptions{Force: &force})
+}
+
+// Creates a three-way merge patch between the original, the wanted and the live object
+// Unstructured objects such as custom resources don't support strategic merge patch and use a json merge patch
+func clientSidePatch(original runtime.Object, target *resource.Info, live runtime.Object) ([]byte, types.PatchType, error) {
+	oldData, err := json.Marshal(original)
+	if err != nil {
+		return nil, types.StrategicMergePatchType, fmt.Errorf("serializing current configuration: %w", err)
+	}
+	newData, err := json.Marshal(target.Object)
+	if err != nil {
+		return nil, types.StrategicMergePatchType, fmt.Errorf("serializing target configuration: %w", err)
+	}
+	currentData, err := json.Marshal(live)
+	if err != nil {
+		return nil, types.StrategicMergePatchType, fmt.Errorf("serializing live configuration: %w", err)
+	}
+
+	versioned := kube.AsVersioned(target)
+	if _, isUnstructured := versioned.(runtime.Unstructured); isUnstructured || isCRD(kube.ResourceList{target}) {
+		preconditions := []mergepatch.PreconditionFunc{
+			mergepatch.RequireKeyUnchanged("apiVersion"),
+			mergepatch.RequireKeyUnchanged("kind"),
+			mergepatch.RequireMetadataKeyUnchanged("name"),
+		}
+		patch, err := jsonmergepatch.CreateThreeWayJSONMergePatch(oldData, newData, currentData, preconditions...)
+		return patch, types.MergePatchType, err
+	}
+
+	patchMeta, err := strategicpatch.NewPatchMetaFromStruct(versioned)
+	if err != nil {
+		return nil, types.StrategicMergePatchType, fmt.Errorf("unable to create patch metadata from object: %w", err)
+	}
+	patch, err := strategicpatch.CreateThreeWayMergePatch(oldData, newData, currentData, patchMeta, true)
+	return patch, types.StrategicMergePatchType, err
+}
+
+// Patches the live resource using client-side apply with the configured field manager
+func (cfg *Config) patchClientSide(original runtime.Object, target *resource.Info, live runtime.Object) (runtime.Object, error) {
+	patch, patchType, err := clientSidePatch(original, target, live)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create patch: %w", err)
+	}
+	if patch == nil || string(patch) == "{}" {
+		return live, nil
+	}
+
+	helper := resource.NewHelper(target.Client, target.Mapping).DryRun(cfg.DryRun).WithFieldManager(cfg.FieldManager)
+	return helper.Patch(target.Namespace, target.Name, patchType, patch, nil)
+}
+
+// Creates the wanted resources which don't exist and updates the others with the configured field manager
+// Resources of the current list which are not wanted anymore are deleted unless they are kept
+// The field manager is passed with every request since the kube client only supports a global one
+// In dry run the requests are sent with DryRun: All and nothing is deleted
+func (cfg *Config) applyResources(current, wanted kube.ResourceList, serverSide bool) (*kube.Result, error) {
+	res := &kube.Result{}
+	for _, target := range wanted {
+		kind := target.Mapping.GroupVersionKind.Kind
+		helper := resource.NewHelper(target.Client, target.Mapping).DryRun(cfg.DryRun).WithFieldManager(cfg.FieldManager)
+		live, err := helper.Get(target.Namespace, target.Name)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return res, fmt.Errorf("could not get information about the resource: %w", err)
+		}
+
+		var obj runtime.Object
+		if err != nil {
+			res.Created = append(res.Created, target)
+			if serverSide {
+				obj, err = cfg.patchServerSide(target)
+			} else {
+				obj, err = helper.Create(target.Namespace, true, target.Object)
+			}
+		} else {
+			original := current.Get(target)
+			if original == nil {
+				return res, fmt.Errorf("original object %s with the name %q not found", kind, target.Name)
+			}
+			res.Updated = append(res.Updated, target)
+			if serverSide {
+				obj, err = cfg.patchServerSide(target)
+			} else {
+				obj, err = cfg.patchClientSide(original.Object, target, live)
+			}
+		}
+		if err != nil {
+			return res, err
+		}
+		if err := target.Refresh(obj, true); err != nil {
+			return res, fmt.Errorf("failed to refresh %s %q: %w", kind, target.Name, err)
+		}
+	}
+
+	if cfg.DryRun {
+		return res, nil
+	}
+	for _, info := range current.Difference(wanted) {
+		if err := info.Get(); err != nil || isKept(kube.ResourceList{info}) {
+			continue
+		}
+		if _, errs := cfg.Client.DeleteWithPropagationPolicy(kube.ResourceList{info}, cfg.propagation()); len(errs) == 0 {
+			res.Deleted = append(res.Deleted, info)
+		}
+	}
+	return res, nil
+}
+
+// Updates the resource using the configured apply mode
+// In auto mode client-side apply is used when the server doesn't support server-side apply
+// Transient errors are retried according to the retry policy
+func (cfg *Config) update(current, wanted kube.ResourceList, policy RetryPolicy) (*kube.Result, error) {
+	var res *kube.Result
+	serverSide := cfg.ServerSide != ServerSideFalse
+	err := policy.do(cfg.ctx, "Update", wanted[0].Name, func() error {
+		var updateErr error
+		res, updateErr = cfg.applyResources(current, wanted, serverSide)
+		if updateErr != nil && serverSide && cfg.ServerSide == ServerSideAuto && isIncompatibleServer(updateErr) {
+			logging.KubeLogger.Info(fmt.Sprintf("Server-side apply is not available falling back to client-side apply for %s", wanted[0].Name))
+			serverSide = false
+			res, updateErr = cfg.applyResources(current, wanted, false)
+		}
+		return updateErr
+	})
+	return res, err
+}
+
+// Applies the resource and converts the errors into diagnostics
+// When an immutable field is changed the resource is recreated if its lifecycle allows it
+func (cfg *Config) apply(current, wanted kube.ResourceList, policy RetryPolicy, name string, lifecycle decode.Lifecycle) (*kube.Result, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	res, err := cfg.update(current, wanted, policy)
+	if err == nil {
+		return res, diags
+	}
+
+	if isImmutable(err) {
+		if lifecycle.RecreateOnImmutable {
+			logging.KubeLogger.Info(fmt.Sprintf("Resource %s changes an immutable field, recreating it: %s", name, err.Error()))
+			return cfg.replace(wanted, name, policy)
+		}
+		diags = append(diags, immutableDiagnostic(err, wanted, name))
+		return res, diags
+	}
+
+	if conflictDiag := conflictDiagnostic(err, wanted); conflictDiag != nil {
+		diags = append(diags, conflictDiag)
+		return res, diags
+	}
+	diags = append(diags, &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Couldn't update resource",
+		Detail:   fmt.Sprintf("Kind: %s,\nResource:%s\nerr: %s", wanted[0].Mapping.GroupVersionKind.Kind, wanted[0].Name, err.Error()),
+	})
+	return res, diags
+}
+
+// Returns the fields and managers of a server-side apply conflict
+// Returns nil if the error is not a field manager conflict
+func fieldManagerConflicts(err error) []string {
+	var statusErr apierrors.APIStatus
+	if !errors.As(err, &statusErr) || !apierrors.IsConflict(err) {
+		return nil
+	}
+
+	details := statusErr.Status().Details
+	if details == nil {
+		return nil
+	}
+
+	var conflicts []string
+	for _, cause := range details.Causes {
+		if cause.Type != metav1.CauseTypeFieldManagerConflict {
+			continue
+		}
+		conflicts = append(conflicts, fmt.Sprintf("  %s: %s", cause.Field, cause.Message))
+	}
+	return conflicts
+}
+
+// Converts a server-side apply conflict into a diagnostic which names the conflicting managers and fields
+// Returns nil if the error is not a conflict
+func conflictDiagnostic(err error, wanted kube.ResourceList) *hcl.Diagnostic {
+	conflicts := fieldManagerConflicts(err)
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Field manager conflict",
+		Detail: fmt.Sprintf("Kind: %s,\nResource:%s\nThe following fields are owned by other field managers:\n%s\nTo take ownership of these fields use --force-conflicts, or remove them from the configuration",
+			wanted[0].Mapping.GroupVersionKind.Kind, wanted[0].Name, strings.Join(conflicts, "\n")),
+	}
+}
@@ -0,0 +1,303 @@
+package kubeclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"helm.sh/helm/v4/pkg/kube"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/rest/fake"
+	"kubehcl.sh/kubehcl/internal/logging"
+)
+
+var unstructuredSerializer = resource.UnstructuredPlusDefaultContentConfig().NegotiatedSerializer
+
+// Returns a config map with the given data
+func testConfigMap(data map[string]any) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]any{
+			"name":      "test",
+			"namespace": "default",
+		},
+		"data": data,
+	}}
+}
+
+// Returns the info of a config map whose requests are sent to handler
+func testInfo(obj *unstructured.Unstructured, handler func(req *http.Request) (*http.Response, error)) *resource.Info {
+	return &resource.Info{
+		Client: &fake.RESTClient{
+			NegotiatedSerializer: unstructuredSerializer,
+			GroupVersion:         schema.GroupVersion{Version: "v1"},
+			Client:               fake.CreateHTTPClient(handler),
+		},
+		Mapping: &meta.RESTMapping{
+			Resource:         schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+			GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+			Scope:            meta.RESTScopeNamespace,
+		},
+		Namespace: "default",
+		Name:      "test",
+		Object:    obj,
+	}
+}
+
+func jsonResponse(req *http.Request, code int, obj any) (*http.Response, error) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	return &http.Response{StatusCode: code, Header: header, Request: req, Body: io.NopCloser(bytes.NewReader(body))}, nil
+}
+
+func statusResponse(req *http.Request, code int, reason metav1.StatusReason) (*http.Response, error) {
+	return jsonResponse(req, code, &metav1.Status{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Status"},
+		Status:   metav1.StatusFailure,
+		Code:     int32(code),
+		Reason:   reason,
+	})
+}
+
+func Test_update(t *testing.T) {
+	logging.SetLogger(false)
+	tests := []struct {
+		serverSide string
+		exists     bool
+		// applyCode is the status code of server-side apply requests
+		applyCode    int
+		wantRequests []string
+		wantCreated  bool
+		wantErrors   bool
+	}{
+		{
+			serverSide:   ServerSideTrue,
+			applyCode:    http.StatusCreated,
+			wantRequests: []string{"GET", "PATCH " + string(types.ApplyPatchType)},
+			wantCreated:  true,
+		},
+		{
+			serverSide:   ServerSideTrue,
+			exists:       true,
+			applyCode:    http.StatusOK,
+			wantRequests: []string{"GET", "PATCH " + string(types.ApplyPatchType)},
+		},
+		{
+			serverSide:   ServerSideFalse,
+			wantRequests: []string{"GET", "POST"},
+			wantCreated:  true,
+		},
+		{
+			serverSide:   ServerSideFalse,
+			exists:       true,
+			wantRequests: []string{"GET", "PATCH " + string(types.StrategicMergePatchType)},
+		},
+		{
+			serverSide:   ServerSideAuto,
+			exists:       true,
+			applyCode:    http.StatusUnsupportedMediaType,
+			wantRequests: []string{"GET", "PATCH " + string(types.ApplyPatchType), "GET", "PATCH " + string(types.StrategicMergePatchType)},
+		},
+		{
+			serverSide:   ServerSideAuto,
+			applyCode:    http.StatusUnsupportedMediaType,
+			wantRequests: []string{"GET", "PATCH " + string(types.ApplyPatchType), "GET", "POST"},
+			wantCreated:  true,
+		},
+		{
+			serverSide:   ServerSideTrue,
+			exists:       true,
+			applyCode:    http.StatusUnsupportedMediaType,
+			wantRequests: []string{"GET", "PATCH " + string(types.ApplyPatchType)},
+			wantErrors:   true,
+		},
+	}
+
+	for _, test := range tests {
+		live := testConfigMap(map[string]any{"key": "old"})
+		var requests []string
+		handler := func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodGet {
+				requests = append(requests, req.Method)
+				if !test.exists {
+					return statusResponse(req, http.StatusNotFound, metav1.StatusReasonNotFound)
+				}
+				return jsonResponse(req, http.StatusOK, live.Object)
+			}
+
+			patchType := req.Header.Get("Content-Type")
+			if req.Method == http.MethodPatch {
+				requests = append(requests, req.Method+" "+patchType)
+			} else {
+				requests = append(requests, req.Method)
+			}
+			if manager := req.URL.Query().Get("fieldManager"); manager != "custom" {
+				t.Errorf("Expected field manager custom on %s but received %q", req.Method, manager)
+			}
+			if patchType == string(types.ApplyPatchType) {
+				if force := req.URL.Query().Get("force"); force != "false" {
+					t.Errorf("Expected force to be false but received %q", force)
+				}
+				if test.applyCode != http.StatusOK && test.applyCode != http.StatusCreated {
+					return statusResponse(req, test.applyCode, metav1.StatusReasonUnsupportedMediaType)
+				}
+				return jsonResponse(req, test.applyCode, testConfigMap(map[string]any{"key": "new"}).Object)
+			}
+			return jsonResponse(req, http.StatusOK, testConfigMap(map[string]any{"key": "new"}).Object)
+		}
+
+		cfg := &Config{ctx: context.Background(), FieldManager: "custom", ServerSide: test.serverSide}
+		wanted := kube.ResourceList{testInfo(testConfigMap(map[string]any{"key": "new"}), handler)}
+		var current kube.ResourceList
+		if test.exists {
+			current = kube.ResourceList{testInfo(live.DeepCopy(), handler)}
+		}
+
+		res, err := cfg.update(current, wanted, RetryPolicy{Attempts: 1})
+		if err != nil && !test.wantErrors {
+			t.Errorf("Don't want errors but received: %s", err)
+		} else if err == nil && test.wantErrors {
+			t.Errorf("Want errors but did not receive any")
+		}
+
+		if len(requests) != len(test.wantRequests) {
+			t.Errorf("Expected requests %v but received %v", test.wantRequests, requests)
+		} else {
+			for i := range requests {
+				if requests[i] != test.wantRequests[i] {
+					t.Errorf("Expected requests %v but received %v", test.wantRequests, requests)
+					break
+				}
+			}
+		}
+
+		if test.wantErrors {
+			continue
+		}
+		if test.wantCreated && len(res.Created) != 1 {
+			t.Errorf("Expected the resource to be created but received %d created resources", len(res.Created))
+		} else if !test.wantCreated && len(res.Updated) != 1 {
+			t.Errorf("Expected the resource to be updated but received %d updated resources", len(res.Updated))
+		}
+	}
+}
+
+func Test_fieldManagerConflicts(t *testing.T) {
+	gr := schema.GroupResource{Resource: "configmaps"}
+	tests := []struct {
+		err  error
+		want []string
+	}{
+		{
+			err: apierrors.NewApplyConflict([]metav1.StatusCause{
+				{Type: metav1.CauseTypeFieldManagerConflict, Field: ".data.key", Message: `conflict with "kubectl"`},
+				{Type: metav1.CauseTypeFieldValueInvalid, Field: ".data.other", Message: "invalid"},
+				{Type: metav1.CauseTypeFieldManagerConflict, Field: ".data.second", Message: `conflict with "helm"`},
+			}, "Apply failed with 2 conflicts"),
+			want: []string{`  .data.key: conflict with "kubectl"`, `  .data.second: conflict with "helm"`},
+		},
+		{
+			err: apierrors.NewConflict(gr, "test", errors.New("the object has been modified")),
+		},
+		{
+			err: apierrors.NewNotFound(gr, "test"),
+		},
+		{
+			err: errors.New("conflict"),
+		},
+	}
+
+	for _, test := range tests {
+		conflicts := fieldManagerConflicts(test.err)
+		if len(conflicts) != len(test.want) {
+			t.Errorf("Expected conflicts %v but received %v", test.want, conflicts)
+			continue
+		}
+		for i := range conflicts {
+			if conflicts[i] != test.want[i] {
+				t.Errorf("Expected conflicts %v but received %v", test.want, conflicts)
+				break
+			}
+		}
+	}
+}
+
+func Test_applyResourcesPrune(t *testing.T) {
+	logging.SetLogger(false)
+	tests := []struct {
+		annotations map[string]any
+		wantDeleted bool
+	}{
+		{wantDeleted: true},
+		{annotations: map[string]any{ResourcePolicyAnno: KeepPolicy}},
+	}
+
+	for _, test := range tests {
+		old := testConfigMap(map[string]any{"key": "old"})
+		old.SetName("old")
+		old.SetAnnotations(nil)
+		if test.annotations != nil {
+			old.Object["metadata"].(map[string]any)["annotations"] = test.annotations
+		}
+		var deleted []string
+		handler := func(req *http.Request) (*http.Response, error) {
+			switch req.Method {
+			case http.MethodGet:
+				if req.URL.Path == "/namespaces/default/configmaps/old" {
+					return jsonResponse(req, http.StatusOK, old.Object)
+				}
+				return jsonResponse(req, http.StatusOK, testConfigMap(map[string]any{"key": "old"}).Object)
+			case http.MethodDelete:
+				deleted = append(deleted, req.URL.Path)
+				var options metav1.DeleteOptions
+				if err := json.NewDecoder(req.Body).Decode(&options); err != nil || options.PropagationPolicy == nil || *options.PropagationPolicy != metav1.DeletePropagationOrphan {
+					t.Errorf("Expected the pruned resource to be deleted with the orphan propagation policy")
+				}
+				return jsonResponse(req, http.StatusOK, &metav1.Status{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Status"}, Status: metav1.StatusSuccess})
+			}
+			return jsonResponse(req, http.StatusOK, testConfigMap(map[string]any{"key": "new"}).Object)
+		}
+
+		oldInfo := testInfo(old.DeepCopy(), handler)
+		oldInfo.Name = "old"
+		current := kube.ResourceList{testInfo(testConfigMap(map[string]any{"key": "old"}), handler), oldInfo}
+		wanted := kube.ResourceList{testInfo(testConfigMap(map[string]any{"key": "new"}), handler)}
+		cfg := &Config{ctx: context.Background(), FieldManager: "kubehcl", DeletionPropagation: metav1.DeletePropagationOrphan}
+		res, err := cfg.applyResources(current, wanted, true)
+		if err != nil {
+			t.Errorf("Don't want errors but received: %s", err)
+			continue
+		}
+
+		if test.wantDeleted && (len(deleted) != 1 || len(res.Deleted) != 1) {
+			t.Errorf("Expected the old config map to be deleted but received %v", deleted)
+		} else if !test.wantDeleted && len(deleted) != 0 {
+			t.Errorf("Expected the kept config map not to be deleted but received %v", deleted)
+		}
+	}
+}
+
+// Returns the body of the request
+func mustReadBody(t *testing.T, req *http.Request) []byte {
+	t.Helper()
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("Couldn't read the request body: %s", err)
+	}
+	return body
+}
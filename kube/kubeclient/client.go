@@ -13,6 +13,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/hcl/v2"
@@ -28,20 +29,72 @@ import (
 )
 
 type Config struct {
+	// ctx is the context of the command, once it is done no new requests are started
+	ctx      context.Context
 	Settings *settings.EnvSettings
 	Client   *kube.Client
 	Storage  storage.Storage
 	Name     string
-	Timeout  time.Duration
+	// Cluster is the alias of the cluster the config connects to, empty for the default cluster
+	Cluster string
+	Timeout time.Duration
 	// StorageKind string
 	// WaitStrategy kube.WaitStrategy
 	Version string
+	// DeletionPropagation is the cascading strategy used when deleting resources
+	DeletionPropagation metav1.DeletionPropagation
+	// FieldManager is the name of the manager used to track field ownership
+	FieldManager string
+	// ForceConflicts forces server-side apply changes against conflicts
+	ForceConflicts bool
+	// ServerSide is the apply mode, one of true, false or auto
+	ServerSide string
+	// DryRun sends requests to the api server without persisting anything
+	DryRun bool
+	// pendingNamespaces are the namespaces which don't exist yet during a dry run
+	pendingNamespaces map[string]bool
+	namespaceMutex    sync.Mutex
+	// Retry is the global retry policy for transient api errors
+	Retry RetryPolicy
+	// Replace reports if the resource instance must be deleted and created again instead of being updated
+	Replace func(key string) bool
+	// OnPhase is called when a resource instance moves to a new phase during create
+	OnPhase PhaseFunc
+	// OnRollout is called when the rollout progress of a workload changes while waiting for it
+	OnRollout RolloutFunc
+}
+
+// Phase of a resource instance while it is being created or updated
+type Phase string
+
+const (
+	PhaseApplied Phase = "applied"
+	PhaseWaiting Phase = "waiting"
+	// PhaseReplacing is reported before a resource instance is deleted in order to be created again
+	PhaseReplacing Phase = "replacing"
+)
+
+type PhaseFunc func(key string, phase Phase)
+
+// Returns the wait timeout of a single resource
+// The timeout is shortened when the deadline of the context is sooner
+func (cfg *Config) waitTimeout() time.Duration {
+	if deadline, ok := cfg.ctx.Deadline(); ok {
+		return max(min(cfg.Timeout, time.Until(deadline)), 0)
+	}
+	return cfg.Timeout
+}
+
+func (cfg *Config) notify(key string, phase Phase) {
+	if cfg.OnPhase != nil {
+		cfg.OnPhase(key, phase)
+	}
 }
 
 // Applies the settings and creates a config to create,destroy and  validate all configuration files
-func New(name string, conf *settings.EnvSettings, storageKind string) (*Config, hcl.Diagnostics) {
+func New(ctx context.Context, name string, conf *settings.EnvSettings, storageKind string) (*Config, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
-	cfg := &Config{}
+	cfg := &Config{ctx: ctx}
 	// cfg.StorageKind = storageKind
 	cfg.Settings = conf
 	cfg.Client = kube.New(cfg.Settings.RESTClientGetter())
@@ -104,7 +157,7 @@ func (cfg *Config) validateNamespace() hcl.Diagnostics {
 	}
 
 	var diags hcl.Diagnostics
-	if _, err := client.CoreV1().Namespaces().Get(context.Background(), cfg.Settings.Namespace(), metav1.GetOptions{}); apierrors.IsNotFound(err) {
+	if _, err := client.CoreV1().Namespaces().Get(cfg.ctx, cfg.Settings.Namespace(), metav1.GetOptions{}); apierrors.IsNotFound(err) {
 		diags = append(diags, &hcl.Diagnostic{
 			Severity: hcl.DiagError,
 			Summary:  fmt.Sprintf("Namespace \"%s\" does not exist", cfg.Settings.Namespace()),
@@ -115,11 +168,12 @@ func (cfg *Config) validateNamespace() hcl.Diagnostics {
 }
 
 // Build resource build the resource from cty.value type into a json
+// The resource is added to the state only once it was built
 func (cfg *Config) buildResource(key string, value cty.Value, rg *hcl.Range) (kube.ResourceList, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
 	data, err := ctyjson.Marshal(value, value.Type())
 	if err != nil {
-		diags = append(diags, &hcl.Diagnostic{
+		return nil, append(diags, &hcl.Diagnostic{
 			Severity: hcl.DiagError,
 			Summary:  "Couldn't convert resource config to json",
 			Detail:   fmt.Sprintf("%s", err),
@@ -127,19 +181,30 @@ func (cfg *Config) buildResource(key string, value cty.Value, rg *hcl.Range) (ku
 		})
 	}
 
-	cfg.Storage.Add(key, data)
 	reader := bytes.NewReader(data)
 	kubeResourceList, buildErr := cfg.Client.Build(reader, true)
+	if buildErr == nil && len(kubeResourceList) == 0 {
+		buildErr = fmt.Errorf("%s doesn't contain a kubernetes object", key)
+	}
 	if buildErr != nil {
-		diags = append(diags, &hcl.Diagnostic{
+		return nil, append(diags, &hcl.Diagnostic{
 			Severity: hcl.DiagError,
 			Summary:  "Couldn't build resource",
 			Detail:   fmt.Sprintf("%s", buildErr),
 			Subject:  rg,
 		})
 	}
-	obj := kubeResourceList[0].Object.(*unstructured.Unstructured)
-	obj.Object["metadata"].(map[string]any)["annotations"].(map[string]any)["kubectl.kubernetes.io/last-applied-configuration"] = string(data)
+
+	cfg.Storage.Add(key, data)
+	if obj, ok := kubeResourceList[0].Object.(*unstructured.Unstructured); ok {
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations["kubectl.kubernetes.io/last-applied-configuration"] = string(data)
+		annotations[ReleaseAnno] = cfg.Name
+		obj.SetAnnotations(annotations)
+	}
 	return kubeResourceList, diags
 }
 
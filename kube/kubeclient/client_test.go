@@ -0,0 +1,77 @@
+package kubeclient
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func Test_buildResource(t *testing.T) {
+	handler := func(req *http.Request) (*http.Response, error) {
+		t.Errorf("Unexpected request %s %s", req.Method, req.URL.Path)
+		return nil, nil
+	}
+	tests := []struct {
+		value      cty.Value
+		wantErrors bool
+	}{
+		{
+			value: cty.ObjectVal(map[string]cty.Value{
+				"apiVersion": cty.StringVal("v1"),
+				"kind":       cty.StringVal("ConfigMap"),
+				"metadata": cty.ObjectVal(map[string]cty.Value{
+					"name":        cty.StringVal("test"),
+					"annotations": cty.ObjectVal(map[string]cty.Value{"kubehcl.sh/managed": cty.StringVal("true")}),
+				}),
+			}),
+		},
+		{
+			// Resources without annotations are annotated with their last applied configuration as well
+			value: cty.ObjectVal(map[string]cty.Value{
+				"apiVersion": cty.StringVal("v1"),
+				"kind":       cty.StringVal("ConfigMap"),
+				"metadata":   cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("test")}),
+			}),
+		},
+		{
+			value: cty.ObjectVal(map[string]cty.Value{
+				"apiVersion": cty.StringVal("v1"),
+				"kind":       cty.StringVal("Unknown"),
+				"metadata":   cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("test")}),
+			}),
+			wantErrors: true,
+		},
+	}
+
+	for _, test := range tests {
+		state := &memStorage{current: make(map[string][]byte)}
+		cfg := &Config{Name: "web", Client: testClient(t, handler), Storage: state}
+		resources, diags := cfg.buildResource("kube_resource.test", test.value, &hcl.Range{})
+		if diags.HasErrors() && !test.wantErrors {
+			t.Errorf("Don't want errors but received: %s", diags.Errs())
+		} else if !diags.HasErrors() && test.wantErrors {
+			t.Errorf("Want errors but did not receive any for %s", test.value.GoString())
+		}
+
+		if test.wantErrors {
+			if resources != nil || state.Get("kube_resource.test") != nil {
+				t.Errorf("Expected a resource which couldn't be built not to be returned or saved in the state")
+			}
+			continue
+		}
+		if len(resources) != 1 || state.Get("kube_resource.test") == nil {
+			t.Errorf("Expected the resource to be built and saved in the state")
+			continue
+		}
+		obj := resources[0].Object.(*unstructured.Unstructured)
+		if obj.GetAnnotations()["kubectl.kubernetes.io/last-applied-configuration"] != string(state.Get("kube_resource.test")) {
+			t.Errorf("Expected the last applied configuration to be the saved state but received %v", obj.GetAnnotations())
+		}
+		if obj.GetAnnotations()[ReleaseAnno] != "web" {
+			t.Errorf("Expected the resource to be annotated with its release but received %v", obj.GetAnnotations())
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package kubeclient
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/hashicorp/hcl/v2"
+	"kubehcl.sh/kubehcl/internal/decode"
+	"kubehcl.sh/kubehcl/internal/logging"
+	"kubehcl.sh/kubehcl/kube/kubeclient/storage"
+	"kubehcl.sh/kubehcl/settings"
+)
+
+// DefaultCluster is the alias of the cluster the command line settings connect to
+const DefaultCluster = ""
+
+// Clusters holds a config for each cluster of the release
+// The state of the default cluster records the connection of every other cluster and the addresses it owns
+type Clusters struct {
+	configs     map[string]*Config
+	connections map[string]storage.ClusterConnection
+	// configured contains the aliases declared in the configuration, other aliases are only known from the state
+	configured map[string]bool
+}
+
+// Creates a config for the default cluster and for each cluster alias
+// Clusters which were removed from the configuration but still own resources in the state are connected as well so their resources can be deleted
+func NewClusters(ctx context.Context, name string, conf *settings.EnvSettings, storageKind string, clusters decode.DecodedClusterMap) (*Clusters, hcl.Diagnostics) {
+	cfg, diags := New(ctx, name, conf, storageKind)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	c := &Clusters{
+		configs:     map[string]*Config{DefaultCluster: cfg},
+		connections: make(map[string]storage.ClusterConnection),
+		configured:  make(map[string]bool),
+	}
+	for alias, cluster := range clusters {
+		c.connections[alias] = storage.ClusterConnection{
+			Context:    cluster.Context,
+			Kubeconfig: cluster.Kubeconfig,
+			Namespace:  cluster.Namespace,
+		}
+		c.configured[alias] = true
+	}
+
+	state, stateDiags := cfg.Storage.GetClusterState()
+	diags = append(diags, stateDiags...)
+	for alias, connection := range state.Clusters {
+		if _, exists := c.connections[alias]; !exists {
+			logging.KubeLogger.Info(fmt.Sprintf("Cluster %s was removed from the configuration, connecting to it through the state", alias))
+			c.connections[alias] = connection
+		}
+	}
+
+	for _, alias := range c.Aliases() {
+		if alias == DefaultCluster {
+			continue
+		}
+		connection := c.connections[alias]
+		clusterCfg, clusterDiags := New(ctx, name, conf.ForCluster(connection.Context, connection.Kubeconfig, connection.Namespace), storageKind)
+		for _, diag := range clusterDiags {
+			diag.Summary = fmt.Sprintf("cluster.%s: %s", alias, diag.Summary)
+		}
+		diags = append(diags, clusterDiags...)
+		if clusterDiags.HasErrors() {
+			continue
+		}
+		clusterCfg.Cluster = alias
+		c.configs[alias] = clusterCfg
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	diags = append(diags, c.checkLocations()...)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return c, diags
+}
+
+// Returns the name of the cluster alias in diagnostics
+func clusterName(alias string) string {
+	if alias == DefaultCluster {
+		return "the default cluster"
+	}
+	return "cluster." + alias
+}
+
+// Rejects clusters which connect to the same api server and namespace
+// Such clusters would share the state and the lock of the release
+func (c *Clusters) checkLocations() hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	owners := make(map[string]string)
+	for _, alias := range c.Aliases() {
+		location, err := c.configs[alias].Settings.StateLocation()
+		if err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("%s: Couldn't resolve the api server", clusterName(alias)),
+				Detail:   err.Error(),
+			})
+			continue
+		}
+		if owner, exists := owners[location]; exists {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate cluster",
+				Detail:   fmt.Sprintf("%s connects to the same api server and namespace as %s (%s), both would share the state and the lock of the release", clusterName(alias), clusterName(owner), location),
+			})
+			continue
+		}
+		owners[location] = alias
+	}
+	return diags
+}
+
+// Returns the default cluster followed by the sorted cluster aliases
+func (c *Clusters) Aliases() []string {
+	aliases := []string{DefaultCluster}
+	for alias := range c.connections {
+		aliases = append(aliases, alias)
+	}
+	slices.Sort(aliases[1:])
+	return aliases
+}
+
+// Returns the config of the default cluster
+func (c *Clusters) Default() *Config {
+	return c.configs[DefaultCluster]
+}
+
+// Returns the config of the cluster alias, the empty alias is the default cluster
+func (c *Clusters) For(alias string) *Config {
+	return c.configs[alias]
+}
+
+// Returns the configs of all clusters, the default cluster is first
+func (c *Clusters) All() []*Config {
+	var configs []*Config
+	for _, alias := range c.Aliases() {
+		configs = append(configs, c.configs[alias])
+	}
+	return configs
+}
+
+// Records the connection of each cluster and the addresses it owns in the state of the default cluster
+// Clusters which were removed from the configuration are forgotten once they own no addresses
+func (c *Clusters) recordClusterState() {
+	state := &storage.ClusterState{
+		Clusters: make(map[string]storage.ClusterConnection),
+		Owners:   make(map[string]string),
+	}
+	for _, alias := range c.Aliases()[1:] {
+		keys := c.configs[alias].Storage.Keys()
+		if !c.configured[alias] && len(keys) == 0 {
+			continue
+		}
+		state.Clusters[alias] = c.connections[alias]
+		for _, key := range keys {
+			state.Owners[key] = alias
+		}
+	}
+	c.Default().Storage.SetClusterState(state)
+}
+
+// Updates the state of each cluster
+// The state of a removed cluster which owns no addresses is deleted
+func (c *Clusters) UpdateState() hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	c.recordClusterState()
+	for _, alias := range c.Aliases()[1:] {
+		cfg := c.configs[alias]
+		if !c.configured[alias] && len(cfg.Storage.Keys()) == 0 {
+			diags = append(diags, cfg.Storage.DeleteState()...)
+			continue
+		}
+		diags = append(diags, cfg.Storage.UpdateState()...)
+	}
+	diags = append(diags, c.Default().Storage.UpdateState()...)
+	return diags
+}
+
+// Deletes the resources of every cluster
+// The default cluster is handled last since its state is needed to connect to the other clusters
+func (c *Clusters) DeleteAllResources(targeted func(key string) bool) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, alias := range c.Aliases()[1:] {
+		_, deleteDiags := c.configs[alias].DeleteAllResources(targeted)
+		diags = append(diags, deleteDiags...)
+	}
+	if diags.HasErrors() {
+		return diags
+	}
+	if targeted != nil {
+		c.recordClusterState()
+	}
+	_, deleteDiags := c.Default().DeleteAllResources(targeted)
+	return append(diags, deleteDiags...)
+}
+
+// Renames the addresses saved in the state of every cluster according to the moved blocks
+func (c *Clusters) Move(moved decode.DecodedMovedList) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, cfg := range c.All() {
+		_, moveDiags := cfg.Move(moved)
+		diags = append(diags, moveDiags...)
+	}
+	return diags
+}
+
+// Locks the state of every cluster
+// When a cluster can't be locked the clusters which were already locked are unlocked
+func (c *Clusters) Lock() hcl.Diagnostics {
+	var locked []*Config
+	for _, cfg := range c.All() {
+		if diags := cfg.Storage.Lock(); diags.HasErrors() {
+			for _, lockedCfg := range locked {
+				diags = append(diags, lockedCfg.Storage.Unlock()...)
+			}
+			return diags
+		}
+		locked = append(locked, cfg)
+	}
+	return hcl.Diagnostics{}
+}
+
+// Unlocks the state of every cluster
+func (c *Clusters) Unlock() hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, cfg := range c.All() {
+		diags = append(diags, cfg.Storage.Unlock()...)
+	}
+	return diags
+}
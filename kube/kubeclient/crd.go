@@ -0,0 +1,40 @@
+package kubeclient
+
+import (
+	"helm.sh/helm/v4/pkg/kube"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"kubehcl.sh/kubehcl/internal/logging"
+)
+
+const crdKind = "CustomResourceDefinition"
+
+// Checks if the resource contains a custom resource definition
+func isCRD(resource kube.ResourceList) bool {
+	for _, info := range resource {
+		if info.Mapping != nil && info.Mapping.GroupVersionKind.Kind == crdKind {
+			return true
+		}
+	}
+	return false
+}
+
+// Invalidates the cached discovery information so custom resources of a newly established definition can be mapped
+// The wait of a custom resource definition completes only after its Established condition is true
+func (cfg *Config) refreshDiscovery() {
+	getter := cfg.Settings.RESTClientGetter()
+	discoveryClient, err := getter.ToDiscoveryClient()
+	if err != nil {
+		logging.KubeLogger.Warn("Couldn't get the discovery client to refresh the api resources", "err", err)
+		return
+	}
+	discoveryClient.Invalidate()
+
+	restMapper, err := getter.ToRESTMapper()
+	if err != nil {
+		logging.KubeLogger.Warn("Couldn't get the rest mapper to refresh the api resources", "err", err)
+		return
+	}
+	if resettable, ok := restMapper.(meta.ResettableRESTMapper); ok {
+		resettable.Reset()
+	}
+}
@@ -0,0 +1,165 @@
+package kubeclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"kubehcl.sh/kubehcl/internal/decode"
+	"kubehcl.sh/kubehcl/settings"
+)
+
+// DataReader reads the objects of kube_data blocks from the cluster of the settings
+// The clients are created on the first read so configurations without kube_data don't connect to the cluster
+type DataReader struct {
+	ctx      context.Context
+	settings *settings.EnvSettings
+	client   dynamic.Interface
+	mapper   meta.RESTMapper
+}
+
+func NewDataReader(ctx context.Context, conf *settings.EnvSettings) *DataReader {
+	return &DataReader{ctx: ctx, settings: conf}
+}
+
+// Creates the dynamic client and the rest mapper
+func (r *DataReader) connect(rg *hcl.Range) hcl.Diagnostics {
+	if r.client != nil {
+		return hcl.Diagnostics{}
+	}
+	getter := r.settings.RESTClientGetter()
+	restConfig, err := getter.ToRESTConfig()
+	if err == nil {
+		r.client, err = dynamic.NewForConfig(restConfig)
+	}
+	if err == nil {
+		r.mapper, err = getter.ToRESTMapper()
+	}
+	if err != nil {
+		r.client = nil
+		return hcl.Diagnostics{
+			&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Couldn't create kubernetes client",
+				Detail:   fmt.Sprintf("Client for kube_data couldn't be created, error: %s", err),
+				Subject:  rg,
+			},
+		}
+	}
+	return hcl.Diagnostics{}
+}
+
+// Reads the object or the objects matching the label selector of the kube_data block
+// Objects without a namespace are read from the namespace of the settings
+func (r *DataReader) Read(data *decode.DecodedData) (cty.Value, hcl.Diagnostics) {
+	diags := r.connect(&data.DeclRange)
+	if diags.HasErrors() {
+		return cty.DynamicVal, diags
+	}
+	gv, err := schema.ParseGroupVersion(data.APIVersion)
+	if err != nil {
+		return cty.DynamicVal, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid apiVersion",
+			Detail:   fmt.Sprintf("apiVersion of %s is invalid: %s", data.Address, err),
+			Subject:  &data.DeclRange,
+		})
+	}
+	mapping, err := r.mapper.RESTMapping(gv.WithKind(data.Kind).GroupKind(), gv.Version)
+	if err != nil {
+		return cty.DynamicVal, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Unknown kind",
+			Detail:   fmt.Sprintf("Kind %s of %s is not served by the cluster: %s", data.Kind, data.Address, err),
+			Subject:  &data.DeclRange,
+		})
+	}
+
+	var resource dynamic.ResourceInterface = r.client.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		namespace := data.Namespace
+		if namespace == "" {
+			namespace = r.settings.Namespace()
+		}
+		resource = r.client.Resource(mapping.Resource).Namespace(namespace)
+	}
+
+	if data.List {
+		list, err := resource.List(r.ctx, metav1.ListOptions{LabelSelector: labels.SelectorFromSet(data.LabelSelector).String()})
+		if err != nil {
+			return cty.DynamicVal, append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Couldn't list kube_data objects",
+				Detail:   fmt.Sprintf("Objects of %s couldn't be listed: %s", data.Address, err),
+				Subject:  &data.DeclRange,
+			})
+		}
+		var objects []cty.Value
+		for _, item := range list.Items {
+			object, objectDiags := objectValue(&item, data)
+			diags = append(diags, objectDiags...)
+			objects = append(objects, object)
+		}
+		if len(objects) == 0 {
+			return cty.EmptyTupleVal, diags
+		}
+		return cty.TupleVal(objects), diags
+	}
+
+	obj, err := resource.Get(r.ctx, data.ObjectName, metav1.GetOptions{})
+	if err != nil {
+		summary := "Couldn't read kube_data object"
+		if apierrors.IsNotFound(err) {
+			summary = "kube_data object does not exist"
+		}
+		return cty.DynamicVal, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  summary,
+			Detail:   fmt.Sprintf("Object %s of %s couldn't be read: %s", data.ObjectName, data.Address, err),
+			Subject:  &data.DeclRange,
+		})
+	}
+	object, objectDiags := objectValue(obj, data)
+	return object, append(diags, objectDiags...)
+}
+
+// Converts the object into a cty value, managed fields are removed
+func liveValue(obj map[string]any) (cty.Value, error) {
+	unstructured.RemoveNestedField(obj, "metadata", "managedFields")
+	src, err := json.Marshal(obj)
+	var ty cty.Type
+	if err == nil {
+		ty, err = ctyjson.ImpliedType(src)
+	}
+	var value cty.Value
+	if err == nil {
+		value, err = ctyjson.Unmarshal(src, ty)
+	}
+	return value, err
+}
+
+// Converts the object of a kube_data block into a cty value
+func objectValue(obj *unstructured.Unstructured, data *decode.DecodedData) (cty.Value, hcl.Diagnostics) {
+	value, err := liveValue(obj.Object)
+	if err != nil {
+		return cty.DynamicVal, hcl.Diagnostics{
+			&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Couldn't convert kube_data object",
+				Detail:   fmt.Sprintf("Object %s of %s couldn't be converted: %s", obj.GetName(), data.Address, err),
+				Subject:  &data.DeclRange,
+			},
+		}
+	}
+	return value, hcl.Diagnostics{}
+}
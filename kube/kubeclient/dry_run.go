@@ -0,0 +1,128 @@
+package kubeclient
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"helm.sh/helm/v4/pkg/kube"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/resource"
+	"kubehcl.sh/kubehcl/internal/decode"
+)
+
+const (
+	DryRunNone   = "none"
+	DryRunServer = "server"
+)
+
+// Validates the dry run option
+func ParseDryRun(dryRun string) hcl.Diagnostics {
+	switch dryRun {
+	case DryRunNone, DryRunServer:
+		return hcl.Diagnostics{}
+	}
+	return hcl.Diagnostics{
+		&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid dry-run option",
+			Detail:   fmt.Sprintf("Dry-run must be one of [none, server] got: %s", dryRun),
+		},
+	}
+}
+
+// Records a namespace which doesn't exist yet and is only created by the install
+func (cfg *Config) addPendingNamespace(namespace string) {
+	cfg.namespaceMutex.Lock()
+	defer cfg.namespaceMutex.Unlock()
+	if cfg.pendingNamespaces == nil {
+		cfg.pendingNamespaces = make(map[string]bool)
+	}
+	cfg.pendingNamespaces[namespace] = true
+}
+
+// Checks if the namespace doesn't exist yet and is only created by the install
+func (cfg *Config) isPendingNamespace(namespace string) bool {
+	cfg.namespaceMutex.Lock()
+	defer cfg.namespaceMutex.Unlock()
+	return cfg.pendingNamespaces[namespace]
+}
+
+// Sends the resource to the api server with DryRun: All using the configured apply mode
+// Admission webhooks, quota and validation run without persisting anything
+// Resources in a namespace which doesn't exist yet are skipped since the api server rejects them
+func (cfg *Config) dryRunResource(current, wanted kube.ResourceList, policy RetryPolicy) (runtime.Object, hcl.Diagnostics) {
+	info := wanted[0]
+	if info.Namespace != "" && cfg.isPendingNamespace(info.Namespace) {
+		return nil, hcl.Diagnostics{
+			&hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "Resource was not sent to the api server",
+				Detail:   fmt.Sprintf("Kind: %s,\nResource:%s\nNamespace %s doesn't exist yet and is created by the install, the api server can't dry run resources in it", info.Mapping.GroupVersionKind.Kind, info.Name, info.Namespace),
+			},
+		}
+	}
+
+	if info.Mapping.GroupVersionKind.Kind == "Namespace" {
+		if _, err := resource.NewHelper(info.Client, info.Mapping).Get("", info.Name); apierrors.IsNotFound(err) {
+			cfg.addPendingNamespace(info.Name)
+		}
+	}
+
+	if _, err := cfg.update(current, wanted, policy); err != nil {
+		if conflictDiag := conflictDiagnostic(err, wanted); conflictDiag != nil {
+			return nil, hcl.Diagnostics{conflictDiag}
+		}
+		return nil, hcl.Diagnostics{
+			&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Resource was rejected by the api server",
+				Detail:   fmt.Sprintf("Kind: %s,\nResource:%s\nerr: %s", info.Mapping.GroupVersionKind.Kind, info.Name, err.Error()),
+			},
+		}
+	}
+	return info.Object, hcl.Diagnostics{}
+}
+
+// ServerDryRun returns the objects the api server would return for each instance of the resource
+// The state is never updated
+func (cfg *Config) ServerDryRun(resource *decode.DecodedResource) (map[string]runtime.Object, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	objects := make(map[string]runtime.Object)
+	for key, value := range resource.Config {
+		wanted, buildDiags := cfg.buildResource(key, value, &resource.DeclRange)
+		diags = append(diags, buildDiags...)
+		if buildDiags.HasErrors() {
+			continue
+		}
+
+		current, stateDiags := cfg.Storage.BuildResourceFromState(wanted, key, false)
+		diags = append(diags, stateDiags...)
+		if stateDiags.HasErrors() {
+			continue
+		}
+
+		policy, policyDiags := cfg.retryPolicy(wanted)
+		diags = append(diags, policyDiags...)
+		if policyDiags.HasErrors() {
+			continue
+		}
+
+		obj, dryRunDiags := cfg.dryRunResource(current, wanted, policy)
+		diags = append(diags, dryRunDiags...)
+		if obj == nil {
+			continue
+		}
+		if accessor, ok := obj.(*unstructured.Unstructured); ok {
+			accessor.SetManagedFields(nil)
+		}
+		objects[key] = obj
+	}
+
+	for _, diag := range diags {
+		diag.Subject = &resource.DeclRange
+	}
+
+	return objects, diags
+}
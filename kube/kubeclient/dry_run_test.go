@@ -0,0 +1,127 @@
+package kubeclient
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"helm.sh/helm/v4/pkg/kube"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"kubehcl.sh/kubehcl/internal/logging"
+)
+
+func Test_ParseDryRun(t *testing.T) {
+	tests := []struct {
+		dryRun     string
+		wantErrors bool
+	}{
+		{dryRun: DryRunNone},
+		{dryRun: DryRunServer},
+		{dryRun: "client", wantErrors: true},
+		{dryRun: "", wantErrors: true},
+	}
+
+	for _, test := range tests {
+		diags := ParseDryRun(test.dryRun)
+		if diags.HasErrors() && !test.wantErrors {
+			t.Errorf("Don't want errors but received: %s", diags.Errs())
+		} else if !diags.HasErrors() && test.wantErrors {
+			t.Errorf("Want errors but did not receive any for %q", test.dryRun)
+		}
+	}
+}
+
+func Test_dryRunResource(t *testing.T) {
+	logging.SetLogger(false)
+	var requests []string
+	handler := func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodGet {
+			requests = append(requests, req.Method+" "+req.URL.Path)
+			return statusResponse(req, http.StatusNotFound, metav1.StatusReasonNotFound)
+		}
+
+		requests = append(requests, req.Method+" "+req.Header.Get("Content-Type"))
+		if dryRun := req.URL.Query().Get("dryRun"); dryRun != metav1.DryRunAll {
+			t.Errorf("Expected dryRun %s on %s but received %q", metav1.DryRunAll, req.Method, dryRun)
+		}
+		return jsonResponse(req, http.StatusCreated, map[string]any{"apiVersion": "v1", "kind": "ConfigMap", "metadata": map[string]any{"name": "test"}})
+	}
+
+	namespace := testInfo(&unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata":   map[string]any{"name": "new"},
+	}}, handler)
+	namespace.Namespace = ""
+	namespace.Name = "new"
+	namespace.Mapping = &meta.RESTMapping{
+		Resource:         schema.GroupVersionResource{Version: "v1", Resource: "namespaces"},
+		GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "Namespace"},
+		Scope:            meta.RESTScopeRoot,
+	}
+
+	inNewNamespace := testInfo(testConfigMap(map[string]any{"key": "value"}), handler)
+	inNewNamespace.Namespace = "new"
+
+	tests := []struct {
+		serverSide   string
+		wanted       kube.ResourceList
+		wantRequests []string
+		wantSkipped  bool
+	}{
+		{
+			// The namespace doesn't exist and is recorded as pending
+			serverSide:   ServerSideTrue,
+			wanted:       kube.ResourceList{namespace},
+			wantRequests: []string{"GET /namespaces/new", "GET /namespaces/new", "PATCH " + string(types.ApplyPatchType)},
+		},
+		{
+			serverSide:  ServerSideTrue,
+			wanted:      kube.ResourceList{inNewNamespace},
+			wantSkipped: true,
+		},
+		{
+			serverSide:   ServerSideFalse,
+			wanted:       kube.ResourceList{testInfo(testConfigMap(map[string]any{"key": "value"}), handler)},
+			wantRequests: []string{"GET /namespaces/default/configmaps/test", "POST application/json"},
+		},
+	}
+
+	cfg := &Config{ctx: context.Background(), FieldManager: "kubehcl", DryRun: true}
+	for _, test := range tests {
+		requests = nil
+		cfg.ServerSide = test.serverSide
+		obj, diags := cfg.dryRunResource(kube.ResourceList{}, test.wanted, RetryPolicy{Attempts: 1})
+		if diags.HasErrors() {
+			t.Errorf("Don't want errors but received: %s", diags.Errs())
+		}
+
+		if test.wantSkipped {
+			if obj != nil || len(diags) != 1 || diags[0].Severity != hcl.DiagWarning {
+				t.Errorf("Expected %s to be skipped with a warning", test.wanted[0].Name)
+			}
+		} else if obj == nil {
+			t.Errorf("Expected the object returned by the server for %s", test.wanted[0].Name)
+		}
+
+		if len(requests) != len(test.wantRequests) {
+			t.Errorf("Expected requests %v but received %v", test.wantRequests, requests)
+			continue
+		}
+		for i := range requests {
+			if requests[i] != test.wantRequests[i] {
+				t.Errorf("Expected requests %v but received %v", test.wantRequests, requests)
+				break
+			}
+		}
+	}
+
+	if !cfg.isPendingNamespace("new") {
+		t.Errorf("Expected namespace new to be pending")
+	}
+}
@@ -2,57 +2,28 @@ package kubeclient
 
 import (
 	"bytes"
-	"context"
 	"fmt"
 
 	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
 	"helm.sh/helm/v4/pkg/kube"
-	v1 "k8s.io/api/core/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/resource"
 	"kubehcl.sh/kubehcl/internal/decode"
 )
 
-func (cfg *Config) VerifyInstall(createNamespace bool) hcl.Diagnostics {
-	client, err := cfg.Client.Factory.KubernetesClientSet()
-	if err != nil {
-		panic("Couldn't get client")
-	}
-	var diags hcl.Diagnostics
-
-	if createNamespace {
-		ns := &v1.Namespace{
-			TypeMeta: metav1.TypeMeta{
-				APIVersion: "v1",
-				Kind:       "Namespace",
-			},
-			ObjectMeta: metav1.ObjectMeta{
-				Name: cfg.Settings.Namespace(),
-				Labels: map[string]string{
-					"name": cfg.Settings.Namespace(),
-				},
-			},
-		}
-
-		if _, err := client.CoreV1().Namespaces().Create(context.Background(), ns, metav1.CreateOptions{}); err != nil {
-			diags = append(diags, &hcl.Diagnostic{
-				Severity: hcl.DiagError,
-				Summary:  fmt.Sprintf("Couldn't create namepsace %s", cfg.Settings.Namespace()),
-				Detail:   err.Error(),
-			})
-		}
-	} else {
-		return cfg.validateNamespace()
-	}
-	return diags
-}
-
 // Delete resources will delete all resources in the state that are not in the configuration files
-func (cfg *Config) DeleteResources() (map[string]bool, *kube.Result, hcl.Diagnostics) {
+// Resources annotated with the keep resource policy are only forgotten from the state
+// If targeted is not nil only the state keys it matches are deleted
+// The returned map contains each removed resource, true if it was deleted and false if it was kept
+func (cfg *Config) DeleteResources(targeted func(key string) bool) (map[string]bool, *kube.Result, hcl.Diagnostics) {
 	saved, diags := cfg.Storage.GetAllStateResources()
 	var toDelete kube.ResourceList
 	deleteMap := make(map[string]bool)
 	for key, value := range saved {
+		if targeted != nil && !targeted(key) {
+			continue
+		}
 		if cfg.Storage.Get(key) == nil {
 			reader := bytes.NewReader(value)
 			savedResource, builderErr := cfg.Client.Build(reader, true)
@@ -64,6 +35,10 @@ func (cfg *Config) DeleteResources() (map[string]bool, *kube.Result, hcl.Diagnos
 				})
 				return deleteMap, nil, diags
 			}
+			if isKept(savedResource) {
+				deleteMap[key] = false
+				continue
+			}
 			toDelete = append(toDelete, savedResource...)
 			deleteMap[key] = true
 		}
@@ -73,71 +48,106 @@ func (cfg *Config) DeleteResources() (map[string]bool, *kube.Result, hcl.Diagnos
 		return deleteMap, nil, diags
 	}
 
-	res, errs := cfg.Client.Delete(toDelete)
-	for i, err := range errs {
-		if err != nil && !apierrors.IsNotFound(err) {
-			diags = append(diags, &hcl.Diagnostic{
-				Severity: hcl.DiagError,
-				Summary:  "Couldn't delete resource",
-				Detail:   fmt.Sprintf("Kind: %s,\nResource:%s\nerr: %s", res.Deleted[i].Mapping.GroupVersionKind.Kind, res.Deleted[i].Name, err.Error()),
-			})
-		}
-	}
-
-	if err := cfg.Client.WaitForDelete(toDelete, cfg.Timeout); err != nil && !apierrors.IsNotFound(err) {
-		diags = append(diags, &hcl.Diagnostic{
-			Severity: hcl.DiagError,
-			Summary:  "Couldn't delete resource within the timeout",
-			Detail:   fmt.Sprintf("Kind: %s,\nResource:%s\nerr: %s", toDelete[0].Mapping.GroupVersionKind.Kind, toDelete[0].Name, err.Error()),
-		})
-	}
+	res, deleteDiags := cfg.deleteAndWait(toDelete)
+	diags = append(diags, deleteDiags...)
 	return deleteMap, res, diags
 }
 
 // Compare states get the resource from the state and applies the changes
 // If the resource does not exist it will simply be created
-func (cfg *Config) compareStates(wanted kube.ResourceList, name string) (*kube.Result, hcl.Diagnostics) {
+// Resources matched by cfg.Replace are deleted and created again
+func (cfg *Config) compareStates(wanted kube.ResourceList, name string, lifecycle decode.Lifecycle) (*kube.Result, hcl.Diagnostics) {
 	// if cfg.StorageKind == "stateless"
 	current, diags := cfg.Storage.BuildResourceFromState(wanted, name, false)
 	if diags.HasErrors() {
 		return &kube.Result{}, diags
 	}
-	res, err := cfg.Client.Update(current, wanted, kube.ClientUpdateOptionServerSideApply(true, true))
-
-	if err != nil {
-		diags = append(diags, &hcl.Diagnostic{
-			Severity: hcl.DiagError,
-			Summary:  "Couldn't update resource",
-			Detail:   fmt.Sprintf("Kind: %s,\nResource:%s\nerr: %s", wanted[0].Mapping.GroupVersionKind.Kind, wanted[0].Name, err.Error()),
-		})
+	policy, policyDiags := cfg.retryPolicy(wanted)
+	diags = append(diags, policyDiags...)
+	if diags.HasErrors() {
+		return &kube.Result{}, diags
+	}
+	var res *kube.Result
+	var applyDiags hcl.Diagnostics
+	if cfg.Replace != nil && cfg.Replace(name) {
+		res, applyDiags = cfg.replace(wanted, name, policy)
+	} else {
+		res, applyDiags = cfg.apply(current, wanted, policy, name, lifecycle)
+	}
+	diags = append(diags, applyDiags...)
+	if diags.HasErrors() {
 		return res, diags
 	}
-
-	if err := cfg.Client.Wait(wanted, cfg.Timeout); err != nil {
+	cfg.notify(name, PhaseApplied)
+
+	cfg.notify(name, PhaseWaiting)
+	if err := policy.do(cfg.ctx, "Wait", wanted[0].Name, func() error {
+		return cfg.wait(wanted, name)
+	}); err != nil {
+		detail := fmt.Sprintf("Kind: %s,\nResource:%s\nerr: %s", wanted[0].Mapping.GroupVersionKind.Kind, wanted[0].Name, err.Error())
+		if waitDiags := cfg.waitDiagnostics(wanted); waitDiags != "" {
+			detail += "\n\n" + waitDiags
+		}
 		diags = append(diags, &hcl.Diagnostic{
 			Severity: hcl.DiagError,
 			Summary:  "Resource is not ready within the timeout",
-			Detail:   fmt.Sprintf("Kind: %s,\nResource:%s\nerr: %s", wanted[0].Mapping.GroupVersionKind.Kind, wanted[0].Name, err.Error()),
+			Detail:   detail,
 		})
 	}
 
+	if !diags.HasErrors() && isCRD(wanted) {
+		cfg.refreshDiscovery()
+	}
+
 	return res, diags
 }
 
+// Checks the postconditions of an applied instance against the live object
+func (cfg *Config) checkPostconditions(r *decode.DecodedResource, key string, wanted kube.ResourceList) hcl.Diagnostics {
+	if len(r.Postconditions) == 0 {
+		return hcl.Diagnostics{}
+	}
+	info := wanted[0]
+	obj, err := resource.NewHelper(info.Client, info.Mapping).Get(info.Namespace, info.Name)
+	var live map[string]any
+	if err == nil {
+		live, err = runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	}
+	var self cty.Value
+	if err == nil {
+		self, err = liveValue(live)
+	}
+	if err != nil {
+		return hcl.Diagnostics{
+			&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Couldn't read the applied resource",
+				Detail:   fmt.Sprintf("The postconditions of %s couldn't be checked: %s", key, err),
+				Subject:  &r.DeclRange,
+			},
+		}
+	}
+	return r.CheckPostconditions(key, self)
+}
+
 // Create updates the current state to fit the new configuration and updates the current state accordingly
 func (cfg *Config) Create(resource *decode.DecodedResource) (kube.Result, hcl.Diagnostics) {
 
-	var diags hcl.Diagnostics
+	var diags, postconditionDiags hcl.Diagnostics
 	var results = kube.Result{}
 	for key, value := range resource.Config {
 
 		kubeResourceList, buildDiags := cfg.buildResource(key, value, &resource.DeclRange)
 		diags = append(diags, buildDiags...)
-		res, updateDiags := cfg.compareStates(kubeResourceList, key)
+		if buildDiags.HasErrors() {
+			continue
+		}
+		res, updateDiags := cfg.compareStates(kubeResourceList, key, resource.Lifecycle)
 		if !updateDiags.HasErrors() {
 			results.Created = append(results.Created, res.Created...)
 			results.Updated = append(results.Updated, res.Updated...)
 			results.Deleted = append(results.Deleted, res.Deleted...)
+			postconditionDiags = append(postconditionDiags, cfg.checkPostconditions(resource, key, kubeResourceList)...)
 		}
 		diags = append(diags, updateDiags...)
 	}
@@ -146,6 +156,23 @@ func (cfg *Config) Create(resource *decode.DecodedResource) (kube.Result, hcl.Di
 		diag.Subject = &resource.DeclRange
 	}
 
-	return results, diags
+	// Postconditions point at their own conditions
+	return results, append(diags, postconditionDiags...)
+
+}
 
+// Keeps the previous state of every resource which was not applied
+// Used when the walk was cancelled or targeted so resources which were not reached are not dropped from the state
+// Keys matched by pruned were removed by DeleteResources and are not kept, nil keeps every key
+func (cfg *Config) KeepUnappliedState(pruned func(key string) bool) hcl.Diagnostics {
+	saved, diags := cfg.Storage.GetAllStateResources()
+	for key, value := range saved {
+		if pruned != nil && pruned(key) {
+			continue
+		}
+		if cfg.Storage.Get(key) == nil {
+			cfg.Storage.Add(key, value)
+		}
+	}
+	return diags
 }
@@ -1,7 +1,6 @@
 package kubeclient
 
 import (
-	"context"
 	"fmt"
 
 	"github.com/hashicorp/hcl/v2"
@@ -23,7 +22,7 @@ func (cfg *Config) List() ([]string, hcl.Diagnostics) {
 		})
 	}
 
-	if secretList, getSecretErr := client.CoreV1().Secrets(cfg.Settings.Namespace()).List(context.Background(), metav1.ListOptions{FieldSelector: "type=" + storage.SecretType}); apierrors.IsNotFound(getSecretErr) {
+	if secretList, getSecretErr := client.CoreV1().Secrets(cfg.Settings.Namespace()).List(cfg.ctx, metav1.ListOptions{FieldSelector: "type=" + storage.SecretType}); apierrors.IsNotFound(getSecretErr) {
 		return nil, diags
 	} else {
 		var secretNames []string
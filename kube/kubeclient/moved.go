@@ -0,0 +1,62 @@
+package kubeclient
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hashicorp/hcl/v2"
+	"kubehcl.sh/kubehcl/internal/decode"
+	"kubehcl.sh/kubehcl/internal/logging"
+)
+
+// Renames the addresses saved in the state according to the moved blocks so they are not deleted and created again
+// An address is not moved when its new address is already saved in the state
+// Returns the new address of each moved resource mapped to its previous address
+func (cfg *Config) Move(moved decode.DecodedMovedList) (map[string]string, hcl.Diagnostics) {
+	movedFrom := make(map[string]string)
+	if len(moved) == 0 {
+		return movedFrom, hcl.Diagnostics{}
+	}
+	saved, diags := cfg.Storage.GetAllStateResources()
+	if diags.HasErrors() {
+		return movedFrom, diags
+	}
+
+	var keys []string
+	for key := range saved {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	renames := make(map[string]string)
+	for _, key := range keys {
+		to, ok := moved.Resolve(key)
+		if !ok {
+			continue
+		}
+		if _, exists := saved[to]; exists {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "Moved object already exists in the state",
+				Detail:   fmt.Sprintf("%s was not moved to %s since %s is already saved in the state", key, to, to),
+			})
+			continue
+		}
+		if previous, exists := movedFrom[to]; exists {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "Moved object already exists in the state",
+				Detail:   fmt.Sprintf("%s was not moved to %s since %s was already moved there", key, to, previous),
+			})
+			continue
+		}
+		renames[key] = to
+		movedFrom[to] = key
+	}
+
+	for from, to := range renames {
+		logging.KubeLogger.Info(fmt.Sprintf("Moving %s to %s", from, to))
+		cfg.Storage.Move(from, to)
+	}
+	return movedFrom, diags
+}
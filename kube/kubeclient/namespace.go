@@ -0,0 +1,243 @@
+package kubeclient
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"kubehcl.sh/kubehcl/internal/decode"
+)
+
+// Returns the attribute of an object or the element of a map, false if it doesn't exist or is unknown
+func getAttr(value cty.Value, name string) (cty.Value, bool) {
+	if value.IsNull() || !value.IsKnown() {
+		return cty.NilVal, false
+	}
+	ty := value.Type()
+	switch {
+	case ty.IsObjectType() && ty.HasAttribute(name):
+		return value.GetAttr(name), true
+	case ty.IsMapType():
+		if has := value.HasIndex(cty.StringVal(name)); has.IsKnown() && has.True() {
+			return value.Index(cty.StringVal(name)), true
+		}
+	}
+	return cty.NilVal, false
+}
+
+// Returns the namespaces set in the metadata of the resource instances
+func ResourceNamespaces(resource *decode.DecodedResource) []string {
+	var namespaces []string
+	for _, value := range resource.Config {
+		metadata, ok := getAttr(value, "metadata")
+		if !ok {
+			continue
+		}
+		namespace, ok := getAttr(metadata, "namespace")
+		if !ok || namespace.IsNull() || !namespace.IsKnown() || namespace.Type() != cty.String {
+			continue
+		}
+		if ns := namespace.AsString(); ns != "" && !slices.Contains(namespaces, ns) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// Returns the names of the Namespace objects declared by the resource instances
+func DeclaredNamespaces(resource *decode.DecodedResource) []string {
+	var namespaces []string
+	for _, value := range resource.Config {
+		kind, ok := getAttr(value, "kind")
+		if !ok || kind.IsNull() || !kind.IsKnown() || kind.Type() != cty.String || kind.AsString() != "Namespace" {
+			continue
+		}
+		metadata, ok := getAttr(value, "metadata")
+		if !ok {
+			continue
+		}
+		name, ok := getAttr(metadata, "name")
+		if ok && !name.IsNull() && name.IsKnown() && name.Type() == cty.String {
+			namespaces = append(namespaces, name.AsString())
+		}
+	}
+	return namespaces
+}
+
+// Verifies the namespaces of the release exist before installing
+// With createNamespace the namespace of the settings and the namespaces of the resources are created when missing
+// Namespaces created by kubehcl are recorded in the state so uninstall can delete them
+// Without createNamespace only the namespace of the settings is verified
+func (cfg *Config) VerifyInstall(createNamespace bool, namespaces []string) hcl.Diagnostics {
+	if !createNamespace {
+		return cfg.validateNamespace()
+	}
+	client, err := cfg.Client.Factory.KubernetesClientSet()
+	if err != nil {
+		panic("Couldn't get client")
+	}
+
+	wanted := []string{cfg.Settings.Namespace()}
+	for _, namespace := range namespaces {
+		if !slices.Contains(wanted, namespace) {
+			wanted = append(wanted, namespace)
+		}
+	}
+
+	owned, diags := cfg.Storage.GetNamespaces()
+	if diags.HasErrors() {
+		return diags
+	}
+	created := false
+	for _, namespace := range wanted {
+		if _, getErr := client.CoreV1().Namespaces().Get(cfg.ctx, namespace, metav1.GetOptions{}); getErr == nil {
+			continue
+		} else if !apierrors.IsNotFound(getErr) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Couldn't get namespace %s", namespace),
+				Detail:   getErr.Error(),
+			})
+			continue
+		}
+
+		ns := &v1.Namespace{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "v1",
+				Kind:       "Namespace",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: namespace,
+				Labels: map[string]string{
+					"name": namespace,
+				},
+			},
+		}
+		opts := metav1.CreateOptions{}
+		if cfg.DryRun {
+			opts.DryRun = []string{metav1.DryRunAll}
+		}
+		_, createErr := client.CoreV1().Namespaces().Create(cfg.ctx, ns, opts)
+		switch {
+		case cfg.DryRun && createErr == nil:
+			cfg.addPendingNamespace(namespace)
+		case apierrors.IsAlreadyExists(createErr):
+			// Created by someone else since it was checked, it is not owned by the release
+		case createErr != nil:
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Couldn't create namespace %s", namespace),
+				Detail:   createErr.Error(),
+			})
+		case !cfg.DryRun && !slices.Contains(owned, namespace):
+			owned = append(owned, namespace)
+			created = true
+		}
+	}
+	if created {
+		slices.Sort(owned)
+		cfg.Storage.SetNamespaces(owned)
+	}
+	return diags
+}
+
+// Returns the objects of the release annotated with the keep resource policy in the namespace as kind/name
+// Kept objects are forgotten from the state thus every namespaced kind which can be listed is searched
+func keptObjects(ctx context.Context, dc discovery.DiscoveryInterface, dyn dynamic.Interface, namespace string, release string) ([]string, error) {
+	lists, err := discovery.ServerPreferredNamespacedResources(dc)
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, err
+	}
+	var kept []string
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			return nil, err
+		}
+		for _, apiResource := range list.APIResources {
+			if strings.Contains(apiResource.Name, "/") || !slices.Contains(apiResource.Verbs, "list") {
+				continue
+			}
+			objects, err := dyn.Resource(gv.WithResource(apiResource.Name)).Namespace(namespace).List(ctx, metav1.ListOptions{})
+			if apierrors.IsNotFound(err) || apierrors.IsMethodNotSupported(err) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			for _, obj := range objects.Items {
+				annotations := obj.GetAnnotations()
+				if annotations[ReleaseAnno] == release && annotations[ResourcePolicyAnno] == KeepPolicy {
+					kept = append(kept, fmt.Sprintf("%s/%s", apiResource.Kind, obj.GetName()))
+				}
+			}
+		}
+	}
+	slices.Sort(kept)
+	return kept, nil
+}
+
+// Deletes the namespaces created by kubehcl which are recorded in the state
+// Namespaces which no longer exist are ignored
+// Namespaces which still contain kept objects of the release are not deleted since deleting them would delete the objects
+func (cfg *Config) DeleteNamespaces(namespaces []string) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	if len(namespaces) == 0 {
+		return diags
+	}
+	client, err := cfg.Client.Factory.KubernetesClientSet()
+	if err != nil {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Couldn't get client",
+			Detail:   fmt.Sprintf("%s", err),
+		})
+	}
+	dyn, err := cfg.Client.Factory.DynamicClient()
+	if err != nil {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Couldn't get dynamic client",
+			Detail:   fmt.Sprintf("%s", err),
+		})
+	}
+	opts := metav1.DeleteOptions{}
+	if cfg.DeletionPropagation != "" {
+		opts.PropagationPolicy = &cfg.DeletionPropagation
+	}
+	for _, namespace := range namespaces {
+		kept, keptErr := keptObjects(cfg.ctx, client.Discovery(), dyn, namespace, cfg.Name)
+		if keptErr != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Couldn't delete namespace %s", namespace),
+				Detail:   fmt.Sprintf("The namespace wasn't deleted since it couldn't be searched for kept objects: %s", keptErr),
+			})
+			continue
+		}
+		if len(kept) > 0 {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  fmt.Sprintf("Namespace %s was not deleted", namespace),
+				Detail:   fmt.Sprintf("The namespace contains objects with the %s resource policy which would be deleted with it: %s", KeepPolicy, strings.Join(kept, ", ")),
+			})
+			continue
+		}
+		if deleteErr := client.CoreV1().Namespaces().Delete(cfg.ctx, namespace, opts); deleteErr != nil && !apierrors.IsNotFound(deleteErr) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Couldn't delete namespace %s", namespace),
+				Detail:   deleteErr.Error(),
+			})
+		}
+	}
+	return diags
+}
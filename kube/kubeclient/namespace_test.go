@@ -0,0 +1,207 @@
+package kubeclient
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"helm.sh/helm/v4/pkg/kube"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
+	"kubehcl.sh/kubehcl/internal/logging"
+	"kubehcl.sh/kubehcl/settings"
+)
+
+var configMapsResource = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+// The namespaced resources served by the fake api server
+var testResources = &metav1.APIResourceList{
+	GroupVersion: "v1",
+	APIResources: []metav1.APIResource{
+		{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: metav1.Verbs{"get", "list", "delete"}},
+		{Name: "pods/log", Kind: "Pod", Namespaced: true, Verbs: metav1.Verbs{"get"}},
+		{Name: "namespaces", Kind: "Namespace", Verbs: metav1.Verbs{"get", "list"}},
+	},
+}
+
+// Returns a config map in the namespace annotated with the annotations
+func namespacedConfigMap(namespace string, name string, annotations map[string]any) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]any{
+			"name":        name,
+			"namespace":   namespace,
+			"annotations": annotations,
+		},
+	}}
+}
+
+// Returns a dynamic client which serves the objects
+func testDynamicClient(objects ...runtime.Object) *fakedynamic.FakeDynamicClient {
+	return fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{configMapsResource: "ConfigMapList"}, objects...)
+}
+
+func Test_keptObjects(t *testing.T) {
+	keep := map[string]any{ReleaseAnno: "web", ResourcePolicyAnno: KeepPolicy}
+	dyn := testDynamicClient(
+		namespacedConfigMap("apps", "data", keep),
+		namespacedConfigMap("apps", "config", map[string]any{ReleaseAnno: "web"}),
+		namespacedConfigMap("apps", "other", map[string]any{ReleaseAnno: "other", ResourcePolicyAnno: KeepPolicy}),
+		namespacedConfigMap("system", "data", keep),
+	)
+	dc := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{testResources}}}
+
+	tests := []struct {
+		namespace string
+		want      []string
+	}{
+		{namespace: "apps", want: []string{"ConfigMap/data"}},
+		{namespace: "system", want: []string{"ConfigMap/data"}},
+		{namespace: "empty"},
+	}
+
+	for _, test := range tests {
+		kept, err := keptObjects(context.Background(), dc, dyn, test.namespace, "web")
+		if err != nil {
+			t.Errorf("Don't want errors but received: %s", err)
+			continue
+		}
+		if !slices.Equal(kept, test.want) {
+			t.Errorf("Expected kept objects %v in %s but received %v", test.want, test.namespace, kept)
+		}
+	}
+}
+
+// Serves the discovery of the fake api server and records the namespaces which are created and deleted
+type namespaceServer struct {
+	t *testing.T
+	// existing are the namespaces which exist and racing the namespaces created by someone else once they were checked
+	existing []string
+	racing   []string
+	created  []string
+	deleted  []string
+}
+
+func (s *namespaceServer) handle(req *http.Request) (*http.Response, error) {
+	switch {
+	case req.Method == http.MethodGet && req.URL.Path == "/api":
+		return jsonResponse(req, http.StatusOK, &metav1.APIVersions{TypeMeta: metav1.TypeMeta{Kind: "APIVersions"}, Versions: []string{"v1"}})
+	case req.Method == http.MethodGet && req.URL.Path == "/apis":
+		return jsonResponse(req, http.StatusOK, &metav1.APIGroupList{TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"}})
+	case req.Method == http.MethodGet && req.URL.Path == "/api/v1":
+		return jsonResponse(req, http.StatusOK, testResources)
+	case req.Method == http.MethodGet:
+		for _, namespace := range s.existing {
+			if req.URL.Path == "/api/v1/namespaces/"+namespace {
+				return jsonResponse(req, http.StatusOK, map[string]any{"apiVersion": "v1", "kind": "Namespace", "metadata": map[string]any{"name": namespace}})
+			}
+		}
+		return statusResponse(req, http.StatusNotFound, metav1.StatusReasonNotFound)
+	case req.Method == http.MethodPost && req.URL.Path == "/api/v1/namespaces":
+		obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(mustReadBody(s.t, req), nil, nil)
+		if err != nil {
+			s.t.Fatalf("Couldn't read the created namespace: %s", err)
+		}
+		namespace := obj.(*corev1.Namespace)
+		if slices.Contains(s.racing, namespace.GetName()) {
+			return statusResponse(req, http.StatusConflict, metav1.StatusReasonAlreadyExists)
+		}
+		if req.URL.Query().Get("dryRun") == "" {
+			s.created = append(s.created, namespace.GetName())
+		}
+		return jsonResponse(req, http.StatusCreated, namespace)
+	case req.Method == http.MethodDelete:
+		s.deleted = append(s.deleted, req.URL.Path)
+		return jsonResponse(req, http.StatusOK, &metav1.Status{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Status"}, Status: metav1.StatusSuccess})
+	}
+	s.t.Errorf("Unexpected request %s %s", req.Method, req.URL.Path)
+	return statusResponse(req, http.StatusMethodNotAllowed, metav1.StatusReasonMethodNotAllowed)
+}
+
+func Test_VerifyInstall(t *testing.T) {
+	logging.SetLogger(false)
+	tests := []struct {
+		dryRun         bool
+		owned          []string
+		namespaces     []string
+		wantCreated    []string
+		wantNamespaces []string
+		wantPending    []string
+	}{
+		{
+			// The namespace of the settings exists and isn't recorded
+			namespaces:     []string{"new", "racing"},
+			wantCreated:    []string{"new"},
+			wantNamespaces: []string{"new"},
+		},
+		{
+			// Namespaces which were already recorded are created again without being recorded twice
+			owned:          []string{"new", "old"},
+			namespaces:     []string{"new"},
+			wantCreated:    []string{"new"},
+			wantNamespaces: []string{"new", "old"},
+		},
+		{
+			// Namespaces created in dry run are pending and not recorded
+			dryRun:      true,
+			namespaces:  []string{"new"},
+			wantPending: []string{"new"},
+		},
+	}
+
+	for _, test := range tests {
+		server := &namespaceServer{t: t, existing: []string{"apps"}, racing: []string{"racing"}}
+		conf := settings.NewSettings()
+		conf.SetNamespace("apps")
+		state := &memStorage{namespaces: test.owned}
+		cfg := &Config{ctx: context.Background(), Client: testClient(t, server.handle), Settings: conf, Storage: state, DryRun: test.dryRun}
+		diags := cfg.VerifyInstall(true, test.namespaces)
+		if diags.HasErrors() {
+			t.Errorf("Don't want errors but received: %s", diags.Errs())
+			continue
+		}
+		if !slices.Equal(server.created, test.wantCreated) {
+			t.Errorf("Expected the created namespaces %v but received %v", test.wantCreated, server.created)
+		}
+		if !slices.Equal(state.namespaces, test.wantNamespaces) {
+			t.Errorf("Expected the recorded namespaces %v but received %v", test.wantNamespaces, state.namespaces)
+		}
+		for _, namespace := range test.wantPending {
+			if !cfg.isPendingNamespace(namespace) {
+				t.Errorf("Expected namespace %s to be pending", namespace)
+			}
+		}
+	}
+}
+
+func Test_DeleteNamespaces(t *testing.T) {
+	logging.SetLogger(false)
+	server := &namespaceServer{t: t}
+	tf := testFactory(t, server.handle)
+	tf.FakeDynamicClient = testDynamicClient(
+		namespacedConfigMap("kept", "data", map[string]any{ReleaseAnno: "web", ResourcePolicyAnno: KeepPolicy}),
+		namespacedConfigMap("free", "data", map[string]any{ReleaseAnno: "other", ResourcePolicyAnno: KeepPolicy}),
+	)
+	cfg := &Config{ctx: context.Background(), Name: "web", Client: &kube.Client{Factory: tf, Waiter: fakeWaiter{}}}
+
+	diags := cfg.DeleteNamespaces([]string{"free", "kept"})
+	if diags.HasErrors() {
+		t.Fatalf("Don't want errors but received: %s", diags.Errs())
+	}
+	if !slices.Equal(server.deleted, []string{"/api/v1/namespaces/free"}) {
+		t.Errorf("Expected only namespace free to be deleted but received %v", server.deleted)
+	}
+	if len(diags) != 1 || diags[0].Severity != hcl.DiagWarning {
+		t.Errorf("Expected a warning for the namespace which wasn't deleted but received %v", diags)
+	}
+}
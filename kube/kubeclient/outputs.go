@@ -0,0 +1,60 @@
+package kubeclient
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"kubehcl.sh/kubehcl/internal/decode"
+	"kubehcl.sh/kubehcl/kube/kubeclient/storage"
+)
+
+// Saves the root outputs in the state on the next update
+// Outputs which were removed from the configuration are removed from the state
+func (cfg *Config) SaveOutputs(outputs decode.DecodedOutputMap) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	saved := make(map[string]storage.Output)
+	for name, output := range outputs {
+		// Sensitive outputs are saved with the sensitive flag instead of marks
+		outputValue, _ := output.Value.UnmarkDeep()
+		if !outputValue.IsWhollyKnown() {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Output value is unknown",
+				Detail:   fmt.Sprintf("The value of output %s couldn't be computed", name),
+				Subject:  &output.DeclRange,
+			})
+			continue
+		}
+		value, err := ctyjson.Marshal(outputValue, outputValue.Type())
+		if err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Couldn't convert output to json",
+				Detail:   fmt.Sprintf("Output %s: %s", name, err),
+				Subject:  &output.DeclRange,
+			})
+			continue
+		}
+		ty, err := ctyjson.MarshalType(outputValue.Type())
+		if err != nil {
+			panic("Should not get here: " + err.Error())
+		}
+		saved[name] = storage.Output{
+			Value:       value,
+			Type:        ty,
+			Description: output.Description,
+			Sensitive:   output.Sensitive,
+		}
+	}
+	if diags.HasErrors() {
+		return diags
+	}
+	cfg.Storage.SetOutputs(saved)
+	return diags
+}
+
+// Returns the root outputs saved in the state
+func (cfg *Config) Outputs() (map[string]storage.Output, hcl.Diagnostics) {
+	return cfg.Storage.GetOutputs()
+}
@@ -84,7 +84,9 @@ func (cfg *Config) BuildResource(resource *decode.DecodedResource) (map[string]k
 	return resourceMap, diags
 }
 
-func (cfg *Config) buildObject(to, from kube.ResourceList) (*view.CompareResources, hcl.Diagnostics) {
+// Builds the live and the merged object of the resource
+// Resources matched by cfg.Replace are marked as replaced, so are resources which change an immutable field when recreate is true
+func (cfg *Config) buildObject(to, from kube.ResourceList, key string, recreate bool) (*view.CompareResources, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
 	f := cmdutil.NewFactory(cfg.Settings.RESTClientGetter())
 	o, err := f.OpenAPIV3Client()
@@ -108,15 +110,24 @@ func (cfg *Config) buildObject(to, from kube.ResourceList) (*view.CompareResourc
 		OpenAPIGetter:   f,
 		OpenAPIV3Root:   oRoot,
 		Force:           false,
-		ServerSideApply: false,
-		FieldManager:    "kubectl-client-side-apply",
-		ForceConflicts:  false,
+		ServerSideApply: cfg.ServerSide != ServerSideFalse,
+		FieldManager:    cfg.FieldManager,
+		ForceConflicts:  cfg.ForceConflicts,
 		IOStreams:       ioStreams,
 	}
 	cmp := &view.CompareResources{
 		Current: obj.Live(),
+		Replace: cfg.Replace != nil && cfg.Replace(key),
 	}
-	if wanted, err := obj.Merged(); err != nil {
+	if wanted, err := obj.Merged(); err != nil && isImmutable(err) {
+		// The merged object can't be built, the resource is compared to the local object instead
+		cmp.Wanted = to[0].Object
+		if recreate {
+			cmp.Replace = true
+		} else if !cmp.Replace {
+			diags = append(diags, immutableDiagnostic(err, to, key))
+		}
+	} else if err != nil {
 		diags = append(diags, &hcl.Diagnostic{
 			Severity: hcl.DiagError,
 			Summary:  "Couldn't get current resource state",
@@ -129,7 +140,9 @@ func (cfg *Config) buildObject(to, from kube.ResourceList) (*view.CompareResourc
 	return cmp, diags
 }
 
-func (cfg *Config) CompareResources(to, from map[string]kube.ResourceList) (map[string]*view.CompareResources, hcl.Diagnostics) {
+// Compares the wanted resources to the resources in the state
+// recreate reports if the resource is recreated when it changes an immutable field
+func (cfg *Config) CompareResources(to, from map[string]kube.ResourceList, recreate func(key string) bool) (map[string]*view.CompareResources, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
 	cmpMap := make(map[string]*view.CompareResources)
 	for key, toVal := range to {
@@ -142,7 +155,7 @@ func (cfg *Config) CompareResources(to, from map[string]kube.ResourceList) (map[
 					Wanted: toVal[0].Object,
 				}
 			} else {
-				cmp, buildDiags := cfg.buildObject(toVal, fromVal)
+				cmp, buildDiags := cfg.buildObject(toVal, fromVal, key, recreate != nil && recreate(key))
 				diags = append(diags, buildDiags...)
 				cmpMap[key] = cmp
 			}
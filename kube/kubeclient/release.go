@@ -0,0 +1,165 @@
+package kubeclient
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/hashicorp/hcl/v2"
+	"helm.sh/helm/v4/pkg/chart/common"
+	"helm.sh/helm/v4/pkg/kube"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
+	"kubehcl.sh/kubehcl/internal/decode"
+	"kubehcl.sh/kubehcl/kube/kubeclient/storage"
+	"kubehcl.sh/kubehcl/settings"
+)
+
+// Reads the release and the capabilities of the cluster of the settings before the folder is decoded
+// The release is installed when it has no state secret and upgraded otherwise
+func ReadRelease(ctx context.Context, name string, conf *settings.EnvSettings) (*decode.Release, hcl.Diagnostics) {
+	client, diags := clientSet(conf)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	capabilities, diags := readCapabilities(client)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	release := &decode.Release{
+		Name:         name,
+		Namespace:    conf.Namespace(),
+		Capabilities: *capabilities,
+	}
+	secret, err := client.CoreV1().Secrets(conf.Namespace()).Get(ctx, storage.SecretName(name), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		release.IsInstall = true
+		release.Revision = storage.Revision(nil)
+	} else if err != nil {
+		return nil, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Couldn't get state secret",
+			Detail:   fmt.Sprintf("Unable to retrieve secret %s err: %s", storage.SecretName(name), err),
+		})
+	} else {
+		release.IsUpgrade = true
+		release.Revision = storage.Revision(secret.Data)
+	}
+	return release, diags
+}
+
+// Returns the kubernetes client set of the cluster of the settings
+func clientSet(conf *settings.EnvSettings) (kubernetes.Interface, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	client, err := kube.New(conf.RESTClientGetter()).Factory.KubernetesClientSet()
+	if err != nil {
+		return nil, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Couldn't create kubernetes client",
+			Detail:   fmt.Sprintf("Client couldn't be created, error: %s", err),
+		})
+	}
+	return client, diags
+}
+
+// Reads the kubernetes version and the api versions of the cluster
+func readCapabilities(client kubernetes.Interface) (*decode.Capabilities, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	version, err := client.Discovery().ServerVersion()
+	if err != nil {
+		return nil, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Version could not be obtained",
+			Detail:   fmt.Sprintf("Failed to retrieve kubernetes version, error: %s", err),
+		})
+	}
+	apiVersions, err := serverAPIVersions(client.Discovery())
+	if err != nil {
+		return nil, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "API versions could not be obtained",
+			Detail:   fmt.Sprintf("Failed to retrieve the api versions of the cluster, error: %s", err),
+		})
+	}
+	return &decode.Capabilities{KubeVersion: version.GitVersion, APIVersions: apiVersions}, diags
+}
+
+// ClusterReader reads the capabilities of the cluster aliases of the release
+// Aliases connect to their cluster the same way the release is installed to them
+type ClusterReader struct {
+	settings *settings.EnvSettings
+}
+
+func NewClusterReader(conf *settings.EnvSettings) *ClusterReader {
+	return &ClusterReader{settings: conf}
+}
+
+// Reads the kubernetes version and the api versions of the cluster alias
+func (r *ClusterReader) Capabilities(cluster *decode.DecodedCluster) (*decode.Capabilities, hcl.Diagnostics) {
+	conf := r.settings.ForCluster(cluster.Context, cluster.Kubeconfig, cluster.Namespace)
+	client, diags := clientSet(conf)
+	var capabilities *decode.Capabilities
+	if !diags.HasErrors() {
+		capabilities, diags = readCapabilities(client)
+	}
+	for _, diag := range diags {
+		diag.Summary = fmt.Sprintf("%s: %s", clusterName(cluster.Name), diag.Summary)
+		diag.Subject = &cluster.DeclRange
+	}
+	return capabilities, diags
+}
+
+// Returns the group/versions and the group/version/kinds served by the cluster as helm does for .Capabilities.APIVersions
+// Groups which failed discovery are skipped
+func serverAPIVersions(client discovery.ServerResourcesInterface) ([]string, error) {
+	groups, resources, err := client.ServerGroupsAndResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, err
+	}
+
+	var apiVersions []string
+	for _, group := range groups {
+		for _, gv := range group.Versions {
+			apiVersions = append(apiVersions, gv.GroupVersion)
+		}
+	}
+	for _, resourceList := range resources {
+		for _, resource := range resourceList.APIResources {
+			apiVersions = append(apiVersions, resourceList.GroupVersion+"/"+resource.Kind)
+		}
+	}
+	slices.Sort(apiVersions)
+	return slices.Compact(apiVersions), nil
+}
+
+// Returns the release rendered by template which doesn't connect to the cluster
+// The name is empty when template wasn't given a release name
+// The clusters default to the kubernetes version and api versions helm template uses, the api versions are added to the defaults
+func TemplateRelease(name string, namespace string, kubeVersion string, apiVersions []string) (*decode.Release, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	release := &decode.Release{
+		Name:      name,
+		Namespace: namespace,
+		Revision:  storage.Revision(nil),
+		IsInstall: true,
+		Capabilities: decode.Capabilities{
+			KubeVersion: common.DefaultCapabilities.KubeVersion.Version,
+			APIVersions: slices.Concat(common.DefaultVersionSet, apiVersions),
+		},
+	}
+	if kubeVersion != "" {
+		version, err := common.ParseKubeVersion(kubeVersion)
+		if err != nil {
+			return nil, append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid kube version",
+				Detail:   fmt.Sprintf("--kube-version %s is not a valid kubernetes version: %s", kubeVersion, err),
+			})
+		}
+		release.KubeVersion = version.Version
+	}
+	return release, diags
+}
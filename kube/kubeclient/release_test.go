@@ -0,0 +1,33 @@
+package kubeclient
+
+import (
+	"slices"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_readCapabilities(t *testing.T) {
+	client := fake.NewClientset()
+	discovery := client.Discovery().(*fakediscovery.FakeDiscovery)
+	discovery.FakedServerVersion = &version.Info{GitVersion: "v1.20.0"}
+	discovery.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap"}}},
+		{GroupVersion: "policy/v1beta1", APIResources: []metav1.APIResource{{Name: "poddisruptionbudgets", Kind: "PodDisruptionBudget"}}},
+	}
+
+	capabilities, diags := readCapabilities(client)
+	if diags.HasErrors() {
+		t.Fatalf("Don't want errors but received: %s", diags.Errs())
+	}
+	if capabilities.KubeVersion != "v1.20.0" {
+		t.Errorf("Expected version v1.20.0 but received %s", capabilities.KubeVersion)
+	}
+	want := []string{"policy/v1beta1", "policy/v1beta1/PodDisruptionBudget", "v1", "v1/ConfigMap"}
+	if !slices.Equal(capabilities.APIVersions, want) {
+		t.Errorf("Expected api versions %v but received %v", want, capabilities.APIVersions)
+	}
+}
@@ -0,0 +1,52 @@
+package kubeclient
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"helm.sh/helm/v4/pkg/kube"
+)
+
+// Message of the api server when an update changes a field which can't be updated
+const immutableErr = "field is immutable"
+
+// Checks if the update failed because an immutable field was changed
+func isImmutable(err error) bool {
+	return err != nil && strings.Contains(err.Error(), immutableErr)
+}
+
+// Converts an immutable field error into a diagnostic which explains how to recreate the resource
+func immutableDiagnostic(err error, wanted kube.ResourceList, name string) *hcl.Diagnostic {
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Resource changes an immutable field",
+		Detail: fmt.Sprintf("Kind: %s,\nResource:%s\nerr: %s\nThe resource must be deleted and created again, use --replace=%s or set lifecycle { recreate_on_immutable = true } in the resource",
+			wanted[0].Mapping.GroupVersionKind.Kind, wanted[0].Name, err.Error(), name),
+	}
+}
+
+// Deletes the resource, waits for the deletion and creates it again
+// The resource is deleted even if it is annotated with the keep resource policy since it is created again
+func (cfg *Config) replace(wanted kube.ResourceList, name string, policy RetryPolicy) (*kube.Result, hcl.Diagnostics) {
+	cfg.notify(name, PhaseReplacing)
+	res, diags := cfg.deleteAndWait(wanted)
+	if diags.HasErrors() {
+		return res, diags
+	}
+
+	created, err := cfg.update(kube.ResourceList{}, wanted, policy)
+	if err != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Couldn't create replaced resource",
+			Detail:   fmt.Sprintf("Kind: %s,\nResource:%s\nThe resource was deleted but couldn't be created again\nerr: %s", wanted[0].Mapping.GroupVersionKind.Kind, wanted[0].Name, err.Error()),
+		})
+		return res, diags
+	}
+	if created != nil {
+		res.Created = append(res.Created, created.Created...)
+		res.Updated = append(res.Updated, created.Updated...)
+	}
+	return res, diags
+}
@@ -0,0 +1,120 @@
+package kubeclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"helm.sh/helm/v4/pkg/kube"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ResourcePolicyAnno is the annotation name for a resource policy
+	ResourcePolicyAnno = "kubehcl.sh/resource-policy"
+	// KeepPolicy makes uninstall and pruning forget the resource from the state instead of deleting it
+	KeepPolicy = "keep"
+	// ReleaseAnno is the annotation name for the release which manages a resource
+	ReleaseAnno = "kubehcl.sh/release"
+)
+
+var cascadeOptions = map[string]metav1.DeletionPropagation{
+	"background": metav1.DeletePropagationBackground,
+	"foreground": metav1.DeletePropagationForeground,
+	"orphan":     metav1.DeletePropagationOrphan,
+}
+
+// Parses the cascade flag into the propagation policy used when deleting resources
+func ParseCascade(cascade string) (metav1.DeletionPropagation, hcl.Diagnostics) {
+	if policy, exists := cascadeOptions[cascade]; exists {
+		return policy, hcl.Diagnostics{}
+	}
+	return "", hcl.Diagnostics{
+		&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid cascade option",
+			Detail:   fmt.Sprintf("Cascade must be one of [background, foreground, orphan] got: %s", cascade),
+		},
+	}
+}
+
+// Checks if the resource is annotated with the keep resource policy
+func isKept(resource kube.ResourceList) bool {
+	for _, info := range resource {
+		accessor, err := meta.Accessor(info.Object)
+		if err != nil {
+			continue
+		}
+		if accessor.GetAnnotations()[ResourcePolicyAnno] == KeepPolicy {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the names of the resources separated by commas
+func resourceNames(resources kube.ResourceList) string {
+	names := make([]string, 0, len(resources))
+	for _, info := range resources {
+		names = append(names, info.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Returns the configured propagation policy, background when it isn't set
+func (cfg *Config) propagation() metav1.DeletionPropagation {
+	if cfg.DeletionPropagation == "" {
+		return metav1.DeletePropagationBackground
+	}
+	return cfg.DeletionPropagation
+}
+
+// Deletes the resources with the configured propagation policy and waits for the deletion to complete
+// Resources with the same retry policy are deleted and waited for together, each group is retried with its own policy
+func (cfg *Config) deleteAndWait(toDelete kube.ResourceList) (*kube.Result, hcl.Diagnostics) {
+	propagation := cfg.propagation()
+
+	res := &kube.Result{}
+	policies, groups, diags := cfg.groupByRetryPolicy(toDelete)
+	for i, group := range groups {
+		if cfg.ctx.Err() != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Operation cancelled",
+				Detail:   fmt.Sprintf("Deletion was stopped before deleting Resources: %s: %s", resourceNames(group), context.Cause(cfg.ctx)),
+			})
+			return res, diags
+		}
+		err := policies[i].do(cfg.ctx, "Delete", resourceNames(group), func() error {
+			deleted, errs := cfg.Client.DeleteWithPropagationPolicy(group, propagation)
+			if deleted != nil {
+				res.Deleted = append(res.Deleted, deleted.Deleted...)
+			}
+			return errors.Join(errs...)
+		})
+		if err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Couldn't delete resource",
+				Detail:   fmt.Sprintf("err: %s", err.Error()),
+			})
+		}
+	}
+
+	for i, group := range groups {
+		if err := policies[i].do(cfg.ctx, "Wait for delete", resourceNames(group), func() error {
+			return cfg.Client.WaitForDelete(group, cfg.waitTimeout())
+		}); err != nil && !apierrors.IsNotFound(err) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Couldn't delete resource within the timeout",
+				Detail:   fmt.Sprintf("Resources:%s\nerr: %s", resourceNames(group), err.Error()),
+			})
+		}
+	}
+	return res, diags
+}
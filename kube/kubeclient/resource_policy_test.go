@@ -0,0 +1,185 @@
+package kubeclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"helm.sh/helm/v4/pkg/kube"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	"kubehcl.sh/kubehcl/internal/logging"
+	"kubehcl.sh/kubehcl/kube/kubeclient/storage"
+)
+
+// memStorage keeps the state in memory, methods which are not overridden panic
+type memStorage struct {
+	storage.Storage
+	// saved is the state of the previous release and current the state being built
+	saved      storage.ResourceMap
+	current    map[string][]byte
+	namespaces []string
+}
+
+func (s *memStorage) GetNamespaces() ([]string, hcl.Diagnostics) {
+	return s.namespaces, hcl.Diagnostics{}
+}
+
+func (s *memStorage) SetNamespaces(namespaces []string) {
+	s.namespaces = namespaces
+}
+
+func (s *memStorage) GetAllStateResources() (storage.ResourceMap, hcl.Diagnostics) {
+	return s.saved, hcl.Diagnostics{}
+}
+
+func (s *memStorage) Get(name string) []byte {
+	return s.current[name]
+}
+
+func (s *memStorage) Add(name string, data []byte) {
+	s.current[name] = data
+}
+
+// fakeWaiter doesn't wait for anything
+type fakeWaiter struct {
+	kube.Waiter
+}
+
+func (fakeWaiter) WaitForDelete(resources kube.ResourceList, timeout time.Duration) error {
+	return nil
+}
+
+// Returns a test factory whose requests are sent to handler
+func testFactory(t *testing.T, handler func(req *http.Request) (*http.Response, error)) *cmdtesting.TestFactory {
+	t.Helper()
+	tf := cmdtesting.NewTestFactory().WithNamespace("default")
+	t.Cleanup(tf.Cleanup)
+	client := &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client:               fake.CreateHTTPClient(handler),
+	}
+	tf.Client = client
+	tf.UnstructuredClient = client
+	return tf
+}
+
+// Returns a kube client whose requests are sent to handler
+func testClient(t *testing.T, handler func(req *http.Request) (*http.Response, error)) *kube.Client {
+	t.Helper()
+	return &kube.Client{Factory: testFactory(t, handler), Waiter: fakeWaiter{}}
+}
+
+// Returns the json of a config map with the annotations
+func configMapJSON(t *testing.T, name string, annotations map[string]any) []byte {
+	t.Helper()
+	obj := testConfigMap(map[string]any{"key": "value"})
+	obj.SetName(name)
+	metadata := obj.Object["metadata"].(map[string]any)
+	metadata["annotations"] = annotations
+	data, err := json.Marshal(obj.Object)
+	if err != nil {
+		t.Fatalf("Couldn't marshal config map: %s", err)
+	}
+	return data
+}
+
+func Test_ParseCascade(t *testing.T) {
+	tests := []struct {
+		cascade    string
+		want       metav1.DeletionPropagation
+		wantErrors bool
+	}{
+		{cascade: "background", want: metav1.DeletePropagationBackground},
+		{cascade: "foreground", want: metav1.DeletePropagationForeground},
+		{cascade: "orphan", want: metav1.DeletePropagationOrphan},
+		{cascade: "Background", wantErrors: true},
+		{cascade: "", wantErrors: true},
+		{cascade: "cascade", wantErrors: true},
+	}
+
+	for _, test := range tests {
+		policy, diags := ParseCascade(test.cascade)
+		if diags.HasErrors() && !test.wantErrors {
+			t.Errorf("Don't want errors but received: %s", diags.Errs())
+		} else if !diags.HasErrors() && test.wantErrors {
+			t.Errorf("Want errors but did not receive any for %q", test.cascade)
+		}
+		if policy != test.want {
+			t.Errorf("Expected propagation %q for %q but received %q", test.want, test.cascade, policy)
+		}
+	}
+}
+
+func Test_isKept(t *testing.T) {
+	tests := []struct {
+		resources kube.ResourceList
+		want      bool
+	}{
+		{resources: kube.ResourceList{annotatedInfo(nil)}},
+		{resources: kube.ResourceList{annotatedInfo(map[string]any{ResourcePolicyAnno: KeepPolicy})}, want: true},
+		{resources: kube.ResourceList{annotatedInfo(map[string]any{ResourcePolicyAnno: "delete"})}},
+		{resources: kube.ResourceList{annotatedInfo(map[string]any{"kubehcl.sh/other": KeepPolicy})}},
+		{resources: kube.ResourceList{annotatedInfo(nil), annotatedInfo(map[string]any{ResourcePolicyAnno: KeepPolicy})}, want: true},
+		{resources: kube.ResourceList{}},
+	}
+
+	for i, test := range tests {
+		if kept := isKept(test.resources); kept != test.want {
+			t.Errorf("Test %d: expected kept to be %t but received %t", i, test.want, kept)
+		}
+	}
+}
+
+func Test_DeleteResources(t *testing.T) {
+	logging.SetLogger(false)
+	var deleted []string
+	handler := func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodDelete {
+			t.Errorf("Unexpected request %s %s", req.Method, req.URL.Path)
+			return statusResponse(req, http.StatusMethodNotAllowed, metav1.StatusReasonMethodNotAllowed)
+		}
+		deleted = append(deleted, req.URL.Path)
+		var options metav1.DeleteOptions
+		if err := json.NewDecoder(req.Body).Decode(&options); err != nil || options.PropagationPolicy == nil || *options.PropagationPolicy != metav1.DeletePropagationForeground {
+			t.Errorf("Expected the deletion to use the foreground propagation policy")
+		}
+		return jsonResponse(req, http.StatusOK, &metav1.Status{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Status"}, Status: metav1.StatusSuccess})
+	}
+
+	state := &memStorage{
+		saved: storage.ResourceMap{
+			"kube_resource.kept":    configMapJSON(t, "kept", map[string]any{ResourcePolicyAnno: KeepPolicy}),
+			"kube_resource.removed": configMapJSON(t, "removed", nil),
+			"kube_resource.wanted":  configMapJSON(t, "wanted", nil),
+		},
+		current: make(map[string][]byte),
+	}
+	// Resources which are still in the configuration are added to the current state before pruning
+	state.current["kube_resource.wanted"] = state.saved["kube_resource.wanted"]
+
+	cfg := &Config{ctx: t.Context(), Client: testClient(t, handler), Storage: state, Retry: RetryPolicy{Attempts: 1}, DeletionPropagation: metav1.DeletePropagationForeground}
+	deleteMap, _, diags := cfg.DeleteResources(nil)
+	if diags.HasErrors() {
+		t.Fatalf("Don't want errors but received: %s", diags.Errs())
+	}
+
+	want := map[string]bool{"kube_resource.kept": false, "kube_resource.removed": true}
+	if len(deleteMap) != len(want) {
+		t.Errorf("Expected removed resources %v but received %v", want, deleteMap)
+	}
+	for key, wantDeleted := range want {
+		if isDeleted, exists := deleteMap[key]; !exists || isDeleted != wantDeleted {
+			t.Errorf("Expected %s to be removed with deleted %t but received %v", key, wantDeleted, deleteMap)
+		}
+	}
+	if len(deleted) != 1 || deleted[0] != "/namespaces/default/configmaps/removed" {
+		t.Errorf("Expected only the removed config map to be deleted but received %v", deleted)
+	}
+	if state.Get("kube_resource.kept") != nil {
+		t.Errorf("Expected the kept resource to be removed from the state")
+	}
+}
@@ -0,0 +1,254 @@
+package kubeclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"helm.sh/helm/v4/pkg/kube"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"kubehcl.sh/kubehcl/internal/logging"
+	"kubehcl.sh/kubehcl/settings"
+)
+
+const (
+	// RetryAttemptsAnno overrides the number of attempts for a single resource
+	RetryAttemptsAnno = "kubehcl.sh/retry-attempts"
+	// RetryBackoffAnno overrides the initial backoff for a single resource
+	RetryBackoffAnno = "kubehcl.sh/retry-backoff"
+	// RetryOnAnno overrides the comma separated retryable errors for a single resource
+	RetryOnAnno = "kubehcl.sh/retry-on"
+)
+
+const (
+	RetryConflict    = "conflict"
+	RetryThrottled   = "throttled"
+	RetryServerError = "server-error"
+	RetryTimeout     = "timeout"
+)
+
+// Message of the api server when an admission webhook could not be called
+const webhookErr = "failed calling webhook"
+
+// RetryPolicy decides how many times a failed api request is attempted and how long to wait between attempts
+type RetryPolicy struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	RetryOn    map[string]bool
+}
+
+// Parses the retryable errors into a set
+func parseRetryOn(retryOn []string) (map[string]bool, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	set := make(map[string]bool)
+	for _, reason := range retryOn {
+		reason = strings.TrimSpace(reason)
+		switch reason {
+		case RetryConflict, RetryThrottled, RetryServerError, RetryTimeout:
+			set[reason] = true
+		case "":
+		default:
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid retry-on option",
+				Detail:   fmt.Sprintf("Retry-on must be any of [conflict, throttled, server-error, timeout] got: %s", reason),
+			})
+		}
+	}
+	return set, diags
+}
+
+// Validates the retry settings and sets the global retry policy of the config
+func (cfg *Config) ConfigureRetry(retry *settings.RetrySettings) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	if retry.Attempts < 1 {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid retry attempts",
+			Detail:   fmt.Sprintf("Retry attempts must be at least 1 got: %d", retry.Attempts),
+		})
+	}
+
+	if retry.Backoff < 0 || retry.MaxBackoff < 0 {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid retry backoff",
+			Detail:   "Retry backoff and max backoff can't be negative",
+		})
+	}
+
+	retryOn, retryOnDiags := parseRetryOn(retry.RetryOn)
+	diags = append(diags, retryOnDiags...)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	cfg.Retry = RetryPolicy{
+		Attempts:   retry.Attempts,
+		Backoff:    retry.Backoff,
+		MaxBackoff: retry.MaxBackoff,
+		RetryOn:    retryOn,
+	}
+	return diags
+}
+
+// Returns the retry policy of the resource
+// The global policy is overridden by the retry annotations of the resource
+func (cfg *Config) retryPolicy(resource kube.ResourceList) (RetryPolicy, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	policy := cfg.Retry
+	if policy.Attempts < 1 {
+		policy.Attempts = 1
+	}
+
+	for _, info := range resource {
+		accessor, err := meta.Accessor(info.Object)
+		if err != nil {
+			continue
+		}
+		annotations := accessor.GetAnnotations()
+		if value, exists := annotations[RetryAttemptsAnno]; exists {
+			attempts, err := strconv.Atoi(value)
+			if err != nil || attempts < 1 {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid retry attempts annotation",
+					Detail:   fmt.Sprintf("Annotation %s must be a number greater than 0 got: %s", RetryAttemptsAnno, value),
+				})
+			} else {
+				policy.Attempts = attempts
+			}
+		}
+
+		if value, exists := annotations[RetryBackoffAnno]; exists {
+			backoff, err := time.ParseDuration(value)
+			if err != nil || backoff < 0 {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid retry backoff annotation",
+					Detail:   fmt.Sprintf("Annotation %s must be a duration such as 5s got: %s", RetryBackoffAnno, value),
+				})
+			} else {
+				policy.Backoff = backoff
+			}
+		}
+
+		if value, exists := annotations[RetryOnAnno]; exists {
+			retryOn, retryOnDiags := parseRetryOn(strings.Split(value, ","))
+			diags = append(diags, retryOnDiags...)
+			if !retryOnDiags.HasErrors() {
+				policy.RetryOn = retryOn
+			}
+		}
+	}
+	return policy, diags
+}
+
+// Classifies the error into one of the retryable errors
+// Returns an empty string if the error should never be retried
+func retryReason(err error) string {
+	switch {
+	case len(fieldManagerConflicts(err)) > 0:
+		// Field manager conflicts will fail the same way on every attempt
+		return ""
+	case apierrors.IsConflict(err):
+		return RetryConflict
+	case apierrors.IsTooManyRequests(err):
+		return RetryThrottled
+	case apierrors.IsServerTimeout(err), apierrors.IsTimeout(err), strings.Contains(err.Error(), webhookErr):
+		return RetryTimeout
+	case apierrors.IsInternalError(err), apierrors.IsServiceUnavailable(err), apierrors.IsUnexpectedServerError(err):
+		return RetryServerError
+	}
+
+	var statusErr apierrors.APIStatus
+	if errors.As(err, &statusErr) && statusErr.Status().Code >= 500 {
+		return RetryServerError
+	}
+	return ""
+}
+
+// Calls fn until it succeeds, fails with an error which is not retryable or the attempts are exhausted
+// The backoff is doubled after each attempt up to the max backoff
+// No more attempts are made once the context is done
+func (p RetryPolicy) do(ctx context.Context, operation, name string, fn func() error) error {
+	attempts := max(p.Attempts, 1)
+	backoff := p.Backoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			if attempt > 1 {
+				logging.KubeLogger.Info(fmt.Sprintf("%s of %s succeeded on attempt %d/%d", operation, name, attempt, attempts))
+			}
+			return nil
+		}
+
+		reason := retryReason(err)
+		if attempt >= attempts || !p.RetryOn[reason] {
+			if attempt > 1 {
+				logging.KubeLogger.Warn(fmt.Sprintf("%s of %s failed on attempt %d/%d: %s", operation, name, attempt, attempts, err.Error()))
+			}
+			return err
+		}
+
+		logging.KubeLogger.Warn(fmt.Sprintf("%s of %s failed on attempt %d/%d with a %s error, retrying in %s: %s", operation, name, attempt, attempts, reason, backoff, err.Error()))
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff = p.nextBackoff(backoff)
+	}
+}
+
+// Returns the backoff of the next attempt, the backoff is doubled up to the max backoff
+func (p RetryPolicy) nextBackoff(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return backoff
+}
+
+// Returns a key which is equal for policies that retry the same way
+func (p RetryPolicy) key() string {
+	retryOn := make([]string, 0, len(p.RetryOn))
+	for reason, enabled := range p.RetryOn {
+		if enabled {
+			retryOn = append(retryOn, reason)
+		}
+	}
+	slices.Sort(retryOn)
+	return fmt.Sprintf("%d/%s/%s/%s", p.Attempts, p.Backoff, p.MaxBackoff, strings.Join(retryOn, ","))
+}
+
+// Groups the resources by their retry policy, the groups keep the order of the resources
+func (cfg *Config) groupByRetryPolicy(resources kube.ResourceList) ([]RetryPolicy, []kube.ResourceList, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	var policies []RetryPolicy
+	var groups []kube.ResourceList
+	index := make(map[string]int)
+	for _, info := range resources {
+		policy, policyDiags := cfg.retryPolicy(kube.ResourceList{info})
+		diags = append(diags, policyDiags...)
+		key := policy.key()
+		i, exists := index[key]
+		if !exists {
+			i = len(groups)
+			index[key] = i
+			policies = append(policies, policy)
+			groups = append(groups, kube.ResourceList{})
+		}
+		groups[i] = append(groups[i], info)
+	}
+	return policies, groups, diags
+}
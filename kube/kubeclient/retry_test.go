@@ -0,0 +1,248 @@
+package kubeclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v4/pkg/kube"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+	"kubehcl.sh/kubehcl/internal/logging"
+)
+
+func Test_retryReason(t *testing.T) {
+	gr := schema.GroupResource{Resource: "configmaps"}
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: apierrors.NewConflict(gr, "test", errors.New("the object has been modified")), want: RetryConflict},
+		{err: apierrors.NewApplyConflict([]metav1.StatusCause{{Type: metav1.CauseTypeFieldManagerConflict, Field: ".data.key"}}, "conflict"), want: ""},
+		{err: apierrors.NewTooManyRequests("slow down", 1), want: RetryThrottled},
+		{err: apierrors.NewServerTimeout(gr, "patch", 1), want: RetryTimeout},
+		{err: apierrors.NewTimeoutError("timeout", 1), want: RetryTimeout},
+		{err: errors.New(`Internal error occurred: failed calling webhook "validate.example.com": context deadline exceeded`), want: RetryTimeout},
+		{err: apierrors.NewInternalError(errors.New("etcd")), want: RetryServerError},
+		{err: apierrors.NewServiceUnavailable("unavailable"), want: RetryServerError},
+		{err: apierrors.NewGenericServerResponse(http.StatusBadGateway, "patch", gr, "test", "", 0, false), want: RetryServerError},
+		{err: apierrors.NewNotFound(gr, "test"), want: ""},
+		{err: apierrors.NewBadRequest("invalid"), want: ""},
+		{err: fmt.Errorf("wrapped: %w", apierrors.NewTooManyRequests("slow down", 1)), want: RetryThrottled},
+		{err: errors.New("connection refused"), want: ""},
+	}
+
+	for _, test := range tests {
+		if reason := retryReason(test.err); reason != test.want {
+			t.Errorf("Expected reason %q for %s but received %q", test.want, test.err, reason)
+		}
+	}
+}
+
+// Returns a config map annotated with the annotations
+func annotatedInfo(annotations map[string]any) *resource.Info {
+	return &resource.Info{
+		Name: "test",
+		Object: &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]any{
+				"name":        "test",
+				"annotations": annotations,
+			},
+		}},
+	}
+}
+
+func Test_retryPolicy(t *testing.T) {
+	global := RetryPolicy{
+		Attempts:   3,
+		Backoff:    2 * time.Second,
+		MaxBackoff: 30 * time.Second,
+		RetryOn:    map[string]bool{RetryConflict: true},
+	}
+	tests := []struct {
+		annotations map[string]any
+		want        RetryPolicy
+		wantErrors  bool
+	}{
+		{
+			want: global,
+		},
+		{
+			annotations: map[string]any{
+				RetryAttemptsAnno: "5",
+				RetryBackoffAnno:  "5s",
+				RetryOnAnno:       "server-error, timeout",
+			},
+			want: RetryPolicy{
+				Attempts:   5,
+				Backoff:    5 * time.Second,
+				MaxBackoff: 30 * time.Second,
+				RetryOn:    map[string]bool{RetryServerError: true, RetryTimeout: true},
+			},
+		},
+		{
+			annotations: map[string]any{RetryAttemptsAnno: "0"},
+			want:        global,
+			wantErrors:  true,
+		},
+		{
+			annotations: map[string]any{RetryAttemptsAnno: "many"},
+			want:        global,
+			wantErrors:  true,
+		},
+		{
+			annotations: map[string]any{RetryBackoffAnno: "-1s"},
+			want:        global,
+			wantErrors:  true,
+		},
+		{
+			annotations: map[string]any{RetryBackoffAnno: "5"},
+			want:        global,
+			wantErrors:  true,
+		},
+		{
+			annotations: map[string]any{RetryOnAnno: "conflict,forbidden"},
+			want:        global,
+			wantErrors:  true,
+		},
+	}
+
+	cfg := &Config{Retry: global}
+	for _, test := range tests {
+		policy, diags := cfg.retryPolicy(kube.ResourceList{annotatedInfo(test.annotations)})
+		if diags.HasErrors() && !test.wantErrors {
+			t.Errorf("Don't want errors but received: %s", diags.Errs())
+		} else if !diags.HasErrors() && test.wantErrors {
+			t.Errorf("Want errors but did not receive any for %v", test.annotations)
+		}
+		if policy.key() != test.want.key() {
+			t.Errorf("Expected policy %s but received %s", test.want.key(), policy.key())
+		}
+	}
+}
+
+func Test_groupByRetryPolicy(t *testing.T) {
+	cfg := &Config{Retry: RetryPolicy{Attempts: 3, RetryOn: map[string]bool{RetryConflict: true}}}
+	resources := kube.ResourceList{
+		annotatedInfo(nil),
+		annotatedInfo(map[string]any{RetryAttemptsAnno: "5"}),
+		annotatedInfo(nil),
+		annotatedInfo(map[string]any{RetryAttemptsAnno: "5"}),
+	}
+	policies, groups, diags := cfg.groupByRetryPolicy(resources)
+	if diags.HasErrors() {
+		t.Fatalf("Don't want errors but received: %s", diags.Errs())
+	}
+	if len(groups) != 2 || len(groups[0]) != 2 || len(groups[1]) != 2 {
+		t.Fatalf("Expected 2 groups of 2 resources but received %d groups", len(groups))
+	}
+	if groups[0][0] != resources[0] || groups[0][1] != resources[2] || groups[1][0] != resources[1] || groups[1][1] != resources[3] {
+		t.Errorf("Expected the groups to keep the order of the resources")
+	}
+	if policies[0].Attempts != 3 || policies[1].Attempts != 5 {
+		t.Errorf("Expected the attempts of the groups to be 3 and 5 but received %d and %d", policies[0].Attempts, policies[1].Attempts)
+	}
+}
+
+func Test_nextBackoff(t *testing.T) {
+	tests := []struct {
+		policy  RetryPolicy
+		backoff time.Duration
+		want    time.Duration
+	}{
+		{policy: RetryPolicy{MaxBackoff: 30 * time.Second}, backoff: 2 * time.Second, want: 4 * time.Second},
+		{policy: RetryPolicy{MaxBackoff: 30 * time.Second}, backoff: 20 * time.Second, want: 30 * time.Second},
+		{policy: RetryPolicy{}, backoff: 20 * time.Second, want: 40 * time.Second},
+		{policy: RetryPolicy{MaxBackoff: 30 * time.Second}, backoff: 0, want: 0},
+	}
+
+	for _, test := range tests {
+		if backoff := test.policy.nextBackoff(test.backoff); backoff != test.want {
+			t.Errorf("Expected backoff %s after %s but received %s", test.want, test.backoff, backoff)
+		}
+	}
+}
+
+func Test_do(t *testing.T) {
+	logging.SetLogger(false)
+	throttled := apierrors.NewTooManyRequests("slow down", 1)
+	notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, "test")
+	tests := []struct {
+		policy RetryPolicy
+		// errs are returned by the attempts in order, attempts after the last error succeed
+		errs         []error
+		cancelled    bool
+		wantAttempts int
+		wantErrors   bool
+	}{
+		{
+			policy:       RetryPolicy{Attempts: 3, RetryOn: map[string]bool{RetryThrottled: true}},
+			errs:         []error{throttled, throttled},
+			wantAttempts: 3,
+		},
+		{
+			policy:       RetryPolicy{Attempts: 3, RetryOn: map[string]bool{RetryThrottled: true}},
+			errs:         []error{throttled, throttled, throttled},
+			wantAttempts: 3,
+			wantErrors:   true,
+		},
+		{
+			policy:       RetryPolicy{Attempts: 3, RetryOn: map[string]bool{RetryThrottled: true}},
+			errs:         []error{notFound},
+			wantAttempts: 1,
+			wantErrors:   true,
+		},
+		{
+			policy:       RetryPolicy{Attempts: 3, RetryOn: map[string]bool{RetryConflict: true}},
+			errs:         []error{throttled},
+			wantAttempts: 1,
+			wantErrors:   true,
+		},
+		{
+			policy:       RetryPolicy{Attempts: 0, RetryOn: map[string]bool{RetryThrottled: true}},
+			errs:         []error{throttled},
+			wantAttempts: 1,
+			wantErrors:   true,
+		},
+		{
+			policy:       RetryPolicy{Attempts: 3, Backoff: time.Hour, RetryOn: map[string]bool{RetryThrottled: true}},
+			errs:         []error{throttled},
+			cancelled:    true,
+			wantAttempts: 1,
+			wantErrors:   true,
+		},
+	}
+
+	for _, test := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		if test.cancelled {
+			cancel()
+		}
+		attempts := 0
+		err := test.policy.do(ctx, "Update", "test", func() error {
+			attempts++
+			if attempts <= len(test.errs) {
+				return test.errs[attempts-1]
+			}
+			return nil
+		})
+		cancel()
+
+		if err != nil && !test.wantErrors {
+			t.Errorf("Don't want errors but received: %s", err)
+		} else if err == nil && test.wantErrors {
+			t.Errorf("Want errors but did not receive any")
+		}
+		if attempts != test.wantAttempts {
+			t.Errorf("Expected %d attempts but received %d", test.wantAttempts, attempts)
+		}
+	}
+}
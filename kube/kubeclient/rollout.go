@@ -0,0 +1,209 @@
+/*
+This file was inspired from https://github.com/helm/helm
+This file has been modified from the original version
+Changes made to fit kubehcl purposes
+This file retains its' original license
+// SPDX-License-Identifier: Apache-2.0
+Licesne: https://www.apache.org/licenses/LICENSE-2.0
+*/
+package kubeclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/aggregator"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/collector"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/event"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/status"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/watcher"
+	"github.com/fluxcd/cli-utils/pkg/object"
+	"helm.sh/helm/v4/pkg/kube"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Annotation of a deployment which contains the revision being rolled out
+const deploymentRevisionAnno = "deployment.kubernetes.io/revision"
+
+// RolloutProgress is the progress of a workload rollout as observed by the status watcher
+// Revision is the revision being rolled out, it is empty when the kind has no revisions
+type RolloutProgress struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Revision  string `json:"revision,omitempty"`
+	Desired   int64  `json:"desired"`
+	Updated   int64  `json:"updated"`
+	Ready     int64  `json:"ready"`
+	Available int64  `json:"available"`
+}
+
+func (p RolloutProgress) String() string {
+	msg := fmt.Sprintf("%d/%d updated, %d/%d ready, %d/%d available", p.Updated, p.Desired, p.Ready, p.Desired, p.Available, p.Desired)
+	if p.Revision != "" {
+		msg = fmt.Sprintf("revision %s: %s", p.Revision, msg)
+	}
+	return msg
+}
+
+// RolloutFunc is called each time the rollout progress of a workload changes while it is being waited on
+type RolloutFunc func(key string, progress RolloutProgress)
+
+// Returns the value of an integer field of the object or the default if it is not set
+func nestedInt(obj map[string]any, def int64, fields ...string) int64 {
+	value, found, err := unstructured.NestedInt64(obj, fields...)
+	if err != nil || !found {
+		return def
+	}
+	return value
+}
+
+// Extracts the rollout progress from a live Deployment, StatefulSet or DaemonSet
+func rolloutProgress(obj *unstructured.Unstructured) (RolloutProgress, bool) {
+	if obj == nil {
+		return RolloutProgress{}, false
+	}
+	progress := RolloutProgress{Kind: obj.GetKind(), Name: obj.GetName()}
+	content := obj.Object
+	switch obj.GetKind() {
+	case "Deployment":
+		progress.Revision = obj.GetAnnotations()[deploymentRevisionAnno]
+		progress.Desired = nestedInt(content, 1, "spec", "replicas")
+		progress.Updated = nestedInt(content, 0, "status", "updatedReplicas")
+		progress.Ready = nestedInt(content, 0, "status", "readyReplicas")
+		progress.Available = nestedInt(content, 0, "status", "availableReplicas")
+	case "StatefulSet":
+		progress.Revision, _, _ = unstructured.NestedString(content, "status", "updateRevision")
+		progress.Desired = nestedInt(content, 1, "spec", "replicas")
+		progress.Updated = nestedInt(content, 0, "status", "updatedReplicas")
+		progress.Ready = nestedInt(content, 0, "status", "readyReplicas")
+		progress.Available = nestedInt(content, 0, "status", "availableReplicas")
+	case "DaemonSet":
+		progress.Desired = nestedInt(content, 0, "status", "desiredNumberScheduled")
+		progress.Updated = nestedInt(content, 0, "status", "updatedNumberScheduled")
+		progress.Ready = nestedInt(content, 0, "status", "numberReady")
+		progress.Available = nestedInt(content, 0, "status", "numberAvailable")
+	default:
+		return RolloutProgress{}, false
+	}
+	return progress, true
+}
+
+// Waits for the workloads to be current using the same status watcher as the kube client
+// The rollout progress of the workloads is reported to cfg.OnRollout whenever it changes
+// Paused deployments are not waited on
+func (cfg *Config) waitRollout(resources kube.ResourceList, key string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	dynamicClient, err := cfg.Client.Factory.DynamicClient()
+	if err != nil {
+		return err
+	}
+	restMapper, err := cfg.Settings.RESTClientGetter().ToRESTMapper()
+	if err != nil {
+		return err
+	}
+
+	var ids []object.ObjMetadata
+	for _, resource := range resources {
+		if obj, ok := resource.Object.(*unstructured.Unstructured); ok && obj.GetKind() == "Deployment" {
+			if paused, _, _ := unstructured.NestedBool(obj.Object, "spec", "paused"); paused {
+				continue
+			}
+		}
+		id, err := object.RuntimeToObjMeta(resource.Object)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	cancelCtx, cancelWatch := context.WithCancel(ctx)
+	defer cancelWatch()
+	sw := watcher.NewDefaultStatusWatcher(dynamicClient, restMapper)
+	eventCh := sw.Watch(cancelCtx, ids, watcher.Options{})
+	statusCollector := collector.NewResourceStatusCollector(ids)
+
+	last := make(map[object.ObjMetadata]RolloutProgress)
+	observer := collector.ObserverFunc(func(statusCollector *collector.ResourceStatusCollector, e event.Event) {
+		var rss []*event.ResourceStatus
+		for _, rs := range statusCollector.ResourceStatuses {
+			if rs != nil {
+				rss = append(rss, rs)
+			}
+		}
+		if e.Type == event.ResourceUpdateEvent && e.Resource != nil && cfg.OnRollout != nil {
+			if progress, ok := rolloutProgress(e.Resource.Resource); ok && progress != last[e.Resource.Identifier] {
+				last[e.Resource.Identifier] = progress
+				cfg.OnRollout(key, progress)
+			}
+		}
+		if aggregator.AggregateStatus(rss, status.CurrentStatus) == status.CurrentStatus {
+			cancelWatch()
+		}
+	})
+	<-statusCollector.ListenWithObserver(eventCh, observer)
+
+	if statusCollector.Error != nil {
+		return statusCollector.Error
+	}
+
+	// Only the parent context error is checked, the watch is cancelled once every resource is current
+	if ctx.Err() != nil {
+		var errs []error
+		for _, id := range ids {
+			rs := statusCollector.ResourceStatuses[id]
+			if rs == nil || rs.Status == status.CurrentStatus {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("resource not ready, name: %s, kind: %s, status: %s", rs.Identifier.Name, rs.Identifier.GroupKind.Kind, rs.Status))
+		}
+		errs = append(errs, ctx.Err())
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// Returns whether the rollout progress of the kind is reported while waiting
+func isWorkload(kind string) bool {
+	switch kind {
+	case "Deployment", "StatefulSet", "DaemonSet":
+		return true
+	}
+	return false
+}
+
+// Splits the resources into workloads whose rollout is observed and the rest
+func splitWorkloads(resources kube.ResourceList) (kube.ResourceList, kube.ResourceList) {
+	var workloads, others kube.ResourceList
+	for _, resource := range resources {
+		if isWorkload(resource.Object.GetObjectKind().GroupVersionKind().Kind) {
+			workloads = append(workloads, resource)
+		} else {
+			others = append(others, resource)
+		}
+	}
+	return workloads, others
+}
+
+// Waits for the resources to be ready
+// Without a rollout callback the wait of the kube client is used for every resource,
+// otherwise only workloads are waited on by waitRollout to report their progress
+func (cfg *Config) wait(resources kube.ResourceList, key string) error {
+	if cfg.OnRollout == nil {
+		return cfg.Client.Wait(resources, cfg.waitTimeout())
+	}
+	workloads, others := splitWorkloads(resources)
+	deadline := time.Now().Add(cfg.waitTimeout())
+	if len(others) > 0 {
+		if err := cfg.Client.Wait(others, cfg.waitTimeout()); err != nil {
+			return err
+		}
+	}
+	if len(workloads) == 0 {
+		return nil
+	}
+	return cfg.waitRollout(workloads, key, time.Until(deadline))
+}
@@ -0,0 +1,38 @@
+package kubeclient
+
+import (
+	"testing"
+
+	"helm.sh/helm/v4/pkg/kube"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// Returns the info of an object of the kind
+func kindInfo(kind string) *resource.Info {
+	return &resource.Info{
+		Name: "test",
+		Object: &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "apps/v1",
+			"kind":       kind,
+			"metadata":   map[string]any{"name": "test"},
+		}},
+	}
+}
+
+func Test_splitWorkloads(t *testing.T) {
+	resources := kube.ResourceList{
+		kindInfo("Deployment"),
+		kindInfo("ConfigMap"),
+		kindInfo("StatefulSet"),
+		kindInfo("Job"),
+		kindInfo("DaemonSet"),
+	}
+	workloads, others := splitWorkloads(resources)
+	if len(workloads) != 3 || workloads[0] != resources[0] || workloads[1] != resources[2] || workloads[2] != resources[4] {
+		t.Errorf("Expected the Deployment, StatefulSet and DaemonSet to be workloads but received %d workloads", len(workloads))
+	}
+	if len(others) != 2 || others[0] != resources[1] || others[1] != resources[3] {
+		t.Errorf("Expected the ConfigMap and Job to be waited on by the kube client but received %d resources", len(others))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"slices"
 	"sync"
 
 	"encoding/json"
@@ -29,6 +30,11 @@ type KubeSecretStorage struct {
 	storageKind             string
 	stateData               map[string][]byte
 	currentStateResourceMap ResourceMap
+	clusterState            *ClusterState
+	// namespaces contains the namespaces created by kubehcl, nil keeps the namespaces of the previous release
+	namespaces []string
+	// outputs contains the root outputs, nil keeps the outputs of the previous release
+	outputs map[string]Output
 }
 
 func New(client *kube.Client, name string, namespace string, storageKind string) (Storage, hcl.Diagnostics) {
@@ -79,10 +85,38 @@ func (s *KubeSecretStorage) genSecret(key string, lbs labels) (*v1.Secret, hcl.D
 	releaseMap["release"] = data
 	releaseMap["previous-releases"] = prevData
 	releaseMap["storage-kind"] = []byte(s.storageKind)
+	// The cluster state of the previous release is kept unless it was replaced
+	if s.clusterState != nil {
+		clusters, err := json.Marshal(s.clusterState)
+		if err != nil {
+			panic("Should not get here: " + err.Error())
+		}
+		releaseMap["clusters"] = clusters
+	} else if clusters, exists := s.stateData["clusters"]; exists {
+		releaseMap["clusters"] = clusters
+	}
+	if s.namespaces != nil {
+		namespaces, err := json.Marshal(s.namespaces)
+		if err != nil {
+			panic("Should not get here: " + err.Error())
+		}
+		releaseMap["namespaces"] = namespaces
+	} else if namespaces, exists := s.stateData["namespaces"]; exists {
+		releaseMap["namespaces"] = namespaces
+	}
+	if s.outputs != nil {
+		outputs, err := json.Marshal(s.outputs)
+		if err != nil {
+			panic("Should not get here: " + err.Error())
+		}
+		releaseMap["outputs"] = outputs
+	} else if outputs, exists := s.stateData["outputs"]; exists {
+		releaseMap["outputs"] = outputs
+	}
 
 	return &v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:   "kubehcl." + key,
+			Name:   SecretName(key),
 			Labels: lbs.toMap(),
 		},
 		Type: v1.SecretType(SecretType),
@@ -90,6 +124,23 @@ func (s *KubeSecretStorage) genSecret(key string, lbs labels) (*v1.Secret, hcl.D
 	}, diags
 }
 
+// Returns the name of the state secret of the release
+func SecretName(name string) string {
+	return "kubehcl." + name
+}
+
+// Returns the revision the next install of the release deploys from the data of its state secret
+// Each install saves the release it replaces in the previous releases, nil data means the release was never installed
+func Revision(data map[string][]byte) int {
+	previousReleases := make(map[string]json.RawMessage)
+	if len(data) > 0 {
+		if err := json.Unmarshal(data["previous-releases"], &previousReleases); err != nil {
+			panic("should not get here: " + err.Error())
+		}
+	}
+	return len(previousReleases) + 1
+}
+
 func (s *KubeSecretStorage) AddPreviousData(data map[string][]byte) {
 	str := fmt.Sprintf("release-%d", len(s.previousData))
 	s.previousData[str] = data
@@ -121,6 +172,101 @@ func (s *KubeSecretStorage) Get(name string) []byte {
 	return nil
 }
 
+// Returns the sorted names of the resources in the storage
+func (s *KubeSecretStorage) Keys() []string {
+	mutex.Lock()
+	defer mutex.Unlock()
+	keys := make([]string, 0, len(s.resourceMap))
+	for key := range s.resourceMap {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Replaces the cluster state which is saved on the next update
+func (s *KubeSecretStorage) SetClusterState(state *ClusterState) {
+	s.clusterState = state
+}
+
+// Get the cluster state saved in the current state
+// An empty cluster state is returned when the release has no state
+func (s *KubeSecretStorage) GetClusterState() (*ClusterState, hcl.Diagnostics) {
+	state := &ClusterState{
+		Clusters: make(map[string]ClusterConnection),
+		Owners:   make(map[string]string),
+	}
+	if s.storageKind == "stateless" {
+		return state, hcl.Diagnostics{}
+	}
+	data, diags := s.getState()
+	if clusters, exists := data["clusters"]; exists {
+		if err := json.Unmarshal(clusters, state); err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Couldn't read the clusters of the state",
+				Detail:   err.Error(),
+			})
+		}
+	}
+	if state.Clusters == nil {
+		state.Clusters = make(map[string]ClusterConnection)
+	}
+	if state.Owners == nil {
+		state.Owners = make(map[string]string)
+	}
+	return state, diags
+}
+
+// Replaces the namespaces created by kubehcl which are saved on the next update
+func (s *KubeSecretStorage) SetNamespaces(namespaces []string) {
+	s.namespaces = namespaces
+}
+
+// Get the sorted namespaces created by kubehcl which are saved in the current state
+func (s *KubeSecretStorage) GetNamespaces() ([]string, hcl.Diagnostics) {
+	namespaces := []string{}
+	if s.storageKind == "stateless" {
+		return namespaces, hcl.Diagnostics{}
+	}
+	data, diags := s.getState()
+	if saved, exists := data["namespaces"]; exists {
+		if err := json.Unmarshal(saved, &namespaces); err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Couldn't read the namespaces of the state",
+				Detail:   err.Error(),
+			})
+		}
+	}
+	slices.Sort(namespaces)
+	return namespaces, diags
+}
+
+// Replaces the root outputs which are saved on the next update
+func (s *KubeSecretStorage) SetOutputs(outputs map[string]Output) {
+	s.outputs = outputs
+}
+
+// Get the root outputs saved in the current state
+func (s *KubeSecretStorage) GetOutputs() (map[string]Output, hcl.Diagnostics) {
+	outputs := make(map[string]Output)
+	if s.storageKind == "stateless" {
+		return outputs, hcl.Diagnostics{}
+	}
+	data, diags := s.getState()
+	if saved, exists := data["outputs"]; exists {
+		if err := json.Unmarshal(saved, &outputs); err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Couldn't read the outputs of the state",
+				Detail:   err.Error(),
+			})
+		}
+	}
+	return outputs, diags
+}
+
 // Get the current state of applied resources
 // State is saved as a secret inside kubernetes in the given namespace
 // The secret type is kubehcl.sh/module.v1
@@ -196,6 +342,17 @@ func (s *KubeSecretStorage) GetAllStateResources() (ResourceMap, hcl.Diagnostics
 
 }
 
+// Moves a resource of the current state to a new address
+// The state is saved under the new address on the next update, the state must be read before moving
+func (s *KubeSecretStorage) Move(from string, to string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if data, exists := s.currentStateResourceMap[from]; exists {
+		s.currentStateResourceMap[to] = data
+		delete(s.currentStateResourceMap, from)
+	}
+}
+
 func (s *KubeSecretStorage) getStorageKind() (string, hcl.Diagnostics) {
 	data, diags := s.getState()
 	storageKind := ""
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var LockSecretType = "kubehcl.sh/lock.v1"
+
+// Returns the name of the secret which locks the state of the release
+func (s *KubeSecretStorage) lockName() string {
+	return "kubehcl." + s.name + ".lock"
+}
+
+// Returns who is taking the lock
+func lockHolder() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s (pid %d)", hostname, os.Getpid())
+}
+
+// Lock prevents other operations from changing the state of the release until it is unlocked
+// The lock is a secret which is created next to the state, creating it fails if another operation holds the lock
+// Stateless releases are never locked
+func (s *KubeSecretStorage) Lock() hcl.Diagnostics {
+	if s.storageKind == "stateless" {
+		return hcl.Diagnostics{}
+	}
+	var diags hcl.Diagnostics
+	client, err := s.client.Factory.KubernetesClientSet()
+	if err != nil {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Couldn't get client",
+			Detail:   fmt.Sprintf("%s", err),
+		})
+	}
+
+	lbs := labels{}
+	lbs.init()
+	lbs.set("owner", "kubehcl")
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   s.lockName(),
+			Labels: lbs.toMap(),
+		},
+		Type: v1.SecretType(LockSecretType),
+		Data: map[string][]byte{
+			"holder":  []byte(lockHolder()),
+			"created": []byte(time.Now().UTC().Format(time.RFC3339)),
+		},
+	}
+
+	_, createErr := client.CoreV1().Secrets(s.namespace).Create(context.Background(), secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(createErr) {
+		detail := fmt.Sprintf("Another operation is changing release %s in namespace %s", s.name, s.namespace)
+		if current, getErr := client.CoreV1().Secrets(s.namespace).Get(context.Background(), s.lockName(), metav1.GetOptions{}); getErr == nil {
+			detail = fmt.Sprintf("Release %s in namespace %s is locked by %s since %s", s.name, s.namespace, current.Data["holder"], current.Data["created"])
+		}
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "State is locked",
+			Detail:   fmt.Sprintf("%s\nIf no other operation is running delete the secret %s to unlock it", detail, s.lockName()),
+		})
+	} else if createErr != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Couldn't lock state",
+			Detail:   fmt.Sprintf("%s", createErr),
+		})
+	} else {
+		// The state read before the lock was taken may have been changed by the previous holder
+		s.stateData = nil
+		s.currentStateResourceMap = nil
+	}
+	return diags
+}
+
+// Unlock releases the lock taken by Lock
+func (s *KubeSecretStorage) Unlock() hcl.Diagnostics {
+	if s.storageKind == "stateless" {
+		return hcl.Diagnostics{}
+	}
+	var diags hcl.Diagnostics
+	client, err := s.client.Factory.KubernetesClientSet()
+	if err != nil {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Couldn't get client",
+			Detail:   fmt.Sprintf("%s", err),
+		})
+	}
+
+	if deleteErr := client.CoreV1().Secrets(s.namespace).Delete(context.Background(), s.lockName(), metav1.DeleteOptions{}); deleteErr != nil && !apierrors.IsNotFound(deleteErr) {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Couldn't unlock state",
+			Detail:   fmt.Sprintf("%s\nDelete the secret %s to unlock it", deleteErr, s.lockName()),
+		})
+	}
+	return diags
+}
@@ -9,18 +9,54 @@ Licesne: https://www.apache.org/licenses/LICENSE-2.0
 package storage
 
 import (
+	"encoding/json"
+
 	"github.com/hashicorp/hcl/v2"
 	"helm.sh/helm/v4/pkg/kube"
 )
 
 type ResourceMap map[string][]byte
+
+// ClusterConnection is how a cluster alias is connected to
+type ClusterConnection struct {
+	Context    string `json:"context,omitempty"`
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+}
+
+// ClusterState records the clusters of a release and the cluster alias which owns each address
+// It is saved in the state of the default cluster, addresses of the default cluster are not recorded
+type ClusterState struct {
+	Clusters map[string]ClusterConnection `json:"clusters"`
+	Owners   map[string]string            `json:"owners"`
+}
+
+// Output is a root output of the release saved in the state
+// Value and Type are the json encoding of the cty value and its type
+type Output struct {
+	Value       json.RawMessage `json:"value"`
+	Type        json.RawMessage `json:"type"`
+	Description string          `json:"description,omitempty"`
+	Sensitive   bool            `json:"sensitive,omitempty"`
+}
+
 type Storage interface {
 	Add(name string, data []byte)
 	Delete(name string)
 	Get(name string) []byte
+	Keys() []string
 	GetAllStateResources() (ResourceMap, hcl.Diagnostics)
+	Move(from string, to string)
 	GetResourceCurrentState(resources kube.ResourceList) (kube.ResourceList, hcl.Diagnostics)
 	BuildResourceFromState(wanted kube.ResourceList, name string, currentOnly bool) (kube.ResourceList, hcl.Diagnostics)
 	DeleteState() hcl.Diagnostics
 	UpdateState() hcl.Diagnostics
+	GetClusterState() (*ClusterState, hcl.Diagnostics)
+	SetClusterState(state *ClusterState)
+	GetNamespaces() ([]string, hcl.Diagnostics)
+	SetNamespaces(namespaces []string)
+	GetOutputs() (map[string]Output, hcl.Diagnostics)
+	SetOutputs(outputs map[string]Output)
+	Lock() hcl.Diagnostics
+	Unlock() hcl.Diagnostics
 }
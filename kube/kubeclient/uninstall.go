@@ -6,20 +6,23 @@ import (
 
 	"github.com/hashicorp/hcl/v2"
 	"helm.sh/helm/v4/pkg/kube"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
 // Delete all resources from a given state
-func (cfg *Config) DeleteAllResources() (*kube.Result, hcl.Diagnostics) {
+// Resources annotated with the keep resource policy are left in the cluster and only removed from the state
+// If targeted is not nil only the state keys it matches are deleted and the rest of the state is kept
+func (cfg *Config) DeleteAllResources(targeted func(key string) bool) (*kube.Result, hcl.Diagnostics) {
 
 	// var wanted kube.ResourceList = kube.ResourceList{}
 	// get saved secret which contains the state
 	saved, diags := cfg.Storage.GetAllStateResources()
 
 	var toDelete kube.ResourceList
-	for _, value := range saved {
-		// if the key doesn't exist in the storage meaning it doesn't exist in configuration so delete it
-		// if cfg.Storage.Get(key) == nil {
+	for key, value := range saved {
+		if targeted != nil && !targeted(key) {
+			cfg.Storage.Add(key, value)
+			continue
+		}
 		reader := bytes.NewReader(value)
 		savedResource, builderErr := cfg.Client.Build(reader, true)
 		if builderErr != nil {
@@ -30,33 +33,27 @@ func (cfg *Config) DeleteAllResources() (*kube.Result, hcl.Diagnostics) {
 			})
 			return nil, diags
 		}
+		if isKept(savedResource) {
+			continue
+		}
 		toDelete = append(toDelete, savedResource...)
-		// }
-	}
-	// delete all managed resources that don't appear in configuration
-	if len(toDelete) < 1 {
-		return nil, diags
 	}
 
-	res, errs := cfg.Client.Delete(toDelete)
-	for i, err := range errs {
-		if err != nil && !apierrors.IsNotFound(err) {
-			diags = append(diags, &hcl.Diagnostic{
-				Severity: hcl.DiagError,
-				Summary:  "Couldn't delete resource",
-				Detail:   fmt.Sprintf("Kind: %s,\nResource:%s\nerr: %s", res.Deleted[i].Mapping.GroupVersionKind.Kind, res.Deleted[i].Name, err.Error()),
-			})
-		}
+	var res *kube.Result
+	if len(toDelete) > 0 {
+		var deleteDiags hcl.Diagnostics
+		res, deleteDiags = cfg.deleteAndWait(toDelete)
+		diags = append(diags, deleteDiags...)
 	}
 
-	if err := cfg.Client.WaitForDelete(toDelete, cfg.Timeout); err != nil && !apierrors.IsNotFound(err) {
-		diags = append(diags, &hcl.Diagnostic{
-			Severity: hcl.DiagError,
-			Summary:  "Couldn't delete resource within the timeout",
-			Detail:   fmt.Sprintf("Kind: %s,\nResource:%s\nerr: %s", toDelete[0].Mapping.GroupVersionKind.Kind, toDelete[0].Name, err.Error()),
-		})
+	if diags.HasErrors() {
+		return res, diags
 	}
 
+	if targeted != nil {
+		diags = append(diags, cfg.Storage.UpdateState()...)
+		return res, diags
+	}
 	diags = append(diags, cfg.Storage.DeleteState()...)
 	return res, diags
 }
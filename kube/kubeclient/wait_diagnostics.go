@@ -0,0 +1,276 @@
+package kubeclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v4/pkg/kube"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
+	"kubehcl.sh/kubehcl/internal/logging"
+)
+
+const (
+	// How long collecting the diagnostics of a resource which is not ready may take
+	waitDiagnosticsTimeout = 10 * time.Second
+	// Number of most recent events shown for each object
+	maxEvents = 10
+	// Number of pods which are inspected for each resource
+	maxPods = 5
+	// Number of log lines shown for each failing container
+	logTailLines int64 = 20
+)
+
+// Container waiting reasons which mean the container can't start
+var failingWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"RunContainerError":          true,
+}
+
+// Collects the events, replica sets, pods, container statuses and logs of resources which are not ready
+// Returns an empty string if nothing could be collected
+// The collection is not stopped when the command is cancelled since the diagnostics explain why the wait failed
+func (cfg *Config) waitDiagnostics(resources kube.ResourceList) string {
+	client, err := cfg.Client.Factory.KubernetesClientSet()
+	if err != nil {
+		logging.KubeLogger.Warn(fmt.Sprintf("Couldn't get client to collect wait diagnostics: %s", err.Error()))
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(cfg.ctx), waitDiagnosticsTimeout)
+	defer cancel()
+
+	var sections []string
+	for _, info := range resources {
+		if section := collectResourceDiagnostics(ctx, client, info); section != "" {
+			sections = append(sections, section)
+		}
+	}
+	return strings.Join(sections, "\n")
+}
+
+// Collects the diagnostics of a single resource and the pods it selects
+func collectResourceDiagnostics(ctx context.Context, client kubernetes.Interface, info *resource.Info) string {
+	kind := info.Mapping.GroupVersionKind.Kind
+	var b strings.Builder
+	writeEvents(ctx, client, &b, info.Namespace, kind, info.Name, "")
+
+	if kind == "Pod" {
+		pod, err := client.CoreV1().Pods(info.Namespace).Get(ctx, info.Name, metav1.GetOptions{})
+		if err != nil {
+			logging.KubeLogger.Info(fmt.Sprintf("Couldn't get pod %s for wait diagnostics: %s", info.Name, err.Error()))
+		} else {
+			writePod(ctx, client, &b, pod, false)
+		}
+		return b.String()
+	}
+
+	selector, ok := podSelector(info)
+	if !ok {
+		return b.String()
+	}
+
+	if kind == "Deployment" {
+		writeReplicaSets(ctx, client, &b, info.Namespace, info.Name, selector)
+	}
+
+	pods, err := client.CoreV1().Pods(info.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		logging.KubeLogger.Info(fmt.Sprintf("Couldn't list pods of %s for wait diagnostics: %s", info.Name, err.Error()))
+		return b.String()
+	}
+
+	// Pods which are failing are shown first
+	items := pods.Items
+	sort.SliceStable(items, func(i, j int) bool {
+		return len(failingContainers(&items[i])) > len(failingContainers(&items[j]))
+	})
+	for i := range items {
+		if i >= maxPods {
+			fmt.Fprintf(&b, "... %d more pods\n", len(items)-maxPods)
+			break
+		}
+		writePod(ctx, client, &b, &items[i], true)
+	}
+	return b.String()
+}
+
+// Returns the label selector of the pods which are created by the resource
+func podSelector(info *resource.Info) (labels.Selector, bool) {
+	obj, ok := info.Object.(*unstructured.Unstructured)
+	if !ok {
+		return nil, false
+	}
+
+	switch info.Mapping.GroupVersionKind.Kind {
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet":
+		raw, found, err := unstructured.NestedMap(obj.Object, "spec", "selector")
+		if err != nil || !found {
+			return nil, false
+		}
+		var labelSelector metav1.LabelSelector
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &labelSelector); err != nil {
+			return nil, false
+		}
+		selector, err := metav1.LabelSelectorAsSelector(&labelSelector)
+		return selector, err == nil && !selector.Empty()
+	case "Job":
+		return labels.SelectorFromSet(labels.Set{"job-name": info.Name}), true
+	case "Service":
+		raw, found, err := unstructured.NestedStringMap(obj.Object, "spec", "selector")
+		if err != nil || !found || len(raw) == 0 {
+			return nil, false
+		}
+		return labels.SelectorFromSet(raw), true
+	}
+	return nil, false
+}
+
+// Writes the most recent events of the object
+// Nothing is written when the object has no events
+func writeEvents(ctx context.Context, client kubernetes.Interface, b *strings.Builder, namespace, kind, name, indent string) {
+	selector := fmt.Sprintf("involvedObject.kind=%s,involvedObject.name=%s", kind, name)
+	events, err := client.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		logging.KubeLogger.Info(fmt.Sprintf("Couldn't list events of %s %s for wait diagnostics: %s", kind, name, err.Error()))
+		return
+	}
+	if len(events.Items) == 0 {
+		return
+	}
+
+	items := events.Items
+	sort.Slice(items, func(i, j int) bool {
+		return eventTime(items[i]).Before(eventTime(items[j]))
+	})
+	if len(items) > maxEvents {
+		items = items[len(items)-maxEvents:]
+	}
+
+	fmt.Fprintf(b, "%sEvents of %s %s:\n", indent, kind, name)
+	for _, event := range items {
+		fmt.Fprintf(b, "%s  %s %s: %s\n", indent, event.Type, event.Reason, strings.TrimSpace(event.Message))
+	}
+}
+
+// Returns the last time the event occurred
+func eventTime(event v1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+// Writes the replica sets of the deployment with their ready and desired replicas
+func writeReplicaSets(ctx context.Context, client kubernetes.Interface, b *strings.Builder, namespace, deployment string, selector labels.Selector) {
+	replicaSets, err := client.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		logging.KubeLogger.Info(fmt.Sprintf("Couldn't list replica sets of %s for wait diagnostics: %s", deployment, err.Error()))
+		return
+	}
+
+	for _, rs := range replicaSets.Items {
+		owned := false
+		for _, owner := range rs.OwnerReferences {
+			if owner.Kind == "Deployment" && owner.Name == deployment {
+				owned = true
+			}
+		}
+		if !owned {
+			continue
+		}
+		desired := int32(0)
+		if rs.Spec.Replicas != nil {
+			desired = *rs.Spec.Replicas
+		}
+		fmt.Fprintf(b, "ReplicaSet %s: %d/%d ready\n", rs.Name, rs.Status.ReadyReplicas, desired)
+	}
+}
+
+// Returns the statuses of the containers which are failing to start or run
+func failingContainers(pod *v1.Pod) []v1.ContainerStatus {
+	var failing []v1.ContainerStatus
+	statuses := append(append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		switch {
+		case status.State.Waiting != nil && failingWaitingReasons[status.State.Waiting.Reason]:
+			failing = append(failing, status)
+		case status.State.Terminated != nil && status.State.Terminated.ExitCode != 0:
+			failing = append(failing, status)
+		case status.LastTerminationState.Terminated != nil && status.LastTerminationState.Terminated.ExitCode != 0:
+			failing = append(failing, status)
+		}
+	}
+	return failing
+}
+
+// Describes the state of the container such as CrashLoopBackOff or OOMKilled
+func containerState(status v1.ContainerStatus) string {
+	var state string
+	switch {
+	case status.State.Waiting != nil:
+		state = fmt.Sprintf("waiting: %s", status.State.Waiting.Reason)
+		if status.State.Waiting.Message != "" {
+			state += fmt.Sprintf(" (%s)", status.State.Waiting.Message)
+		}
+	case status.State.Terminated != nil:
+		state = fmt.Sprintf("terminated: %s, exit code %d", status.State.Terminated.Reason, status.State.Terminated.ExitCode)
+	case status.State.Running != nil:
+		state = "running"
+	}
+	if last := status.LastTerminationState.Terminated; last != nil {
+		state += fmt.Sprintf(", last terminated: %s, exit code %d", last.Reason, last.ExitCode)
+	}
+	return fmt.Sprintf("%s, restarts %d", state, status.RestartCount)
+}
+
+// Writes the phase, unschedulable condition, container statuses and the logs of the failing containers of the pod
+func writePod(ctx context.Context, client kubernetes.Interface, b *strings.Builder, pod *v1.Pod, withEvents bool) {
+	fmt.Fprintf(b, "Pod %s: %s\n", pod.Name, pod.Status.Phase)
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodScheduled && condition.Status == v1.ConditionFalse {
+			fmt.Fprintf(b, "  %s: %s\n", condition.Reason, condition.Message)
+		}
+	}
+	for _, status := range append(append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+		fmt.Fprintf(b, "  Container %s: %s\n", status.Name, containerState(status))
+	}
+
+	if withEvents {
+		writeEvents(ctx, client, b, pod.Namespace, "Pod", pod.Name, "  ")
+	}
+
+	for _, status := range failingContainers(pod) {
+		// The logs of the previous container explain the crash when the container was restarted
+		previous := status.State.Terminated == nil && status.LastTerminationState.Terminated != nil
+		tail := logTailLines
+		logs, err := client.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &v1.PodLogOptions{
+			Container: status.Name,
+			TailLines: &tail,
+			Previous:  previous,
+		}).DoRaw(ctx)
+		if err != nil || len(strings.TrimSpace(string(logs))) == 0 {
+			continue
+		}
+		fmt.Fprintf(b, "  Last logs of container %s:\n", status.Name)
+		for _, line := range strings.Split(strings.TrimRight(string(logs), "\n"), "\n") {
+			fmt.Fprintf(b, "    %s\n", line)
+		}
+	}
+}
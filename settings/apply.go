@@ -0,0 +1,31 @@
+package settings
+
+import (
+	"github.com/spf13/pflag"
+)
+
+const defaultFieldManager = "kubehcl"
+
+// ApplySettings contains the flags which control how resources are applied to the cluster
+type ApplySettings struct {
+	FieldManager   string
+	ForceConflicts bool
+	ServerSide     string
+	// Replace contains the addresses of the resources which are deleted and created again
+	Replace []string
+}
+
+func NewApplySettings() *ApplySettings {
+	return &ApplySettings{
+		FieldManager:   envOr("KUBEHCL_FIELD_MANAGER", defaultFieldManager),
+		ForceConflicts: envBoolOr("KUBEHCL_FORCE_CONFLICTS", true),
+		ServerSide:     envOr("KUBEHCL_SERVER_SIDE", "true"),
+	}
+}
+
+func AddApplySettings(a *ApplySettings, fs *pflag.FlagSet) {
+	fs.StringVar(&a.FieldManager, "field-manager", a.FieldManager, "Name of the manager used to track field ownership")
+	fs.BoolVar(&a.ForceConflicts, "force-conflicts", a.ForceConflicts, "If true, server-side apply will force the changes against conflicts of other field managers and take ownership of their fields. Set to false to fail on conflicts instead")
+	fs.StringArrayVar(&a.Replace, "replace", a.Replace, "Delete and create again the resource at the given address, for example kube_resource.foo or module.test.kube_resource.bar[\"x\"]. Can be repeated")
+	fs.StringVar(&a.ServerSide, "server-side", a.ServerSide, "Must be \"true\", \"false\" or \"auto\". Apply resources using server-side apply, client-side apply or server-side apply with a client-side fallback")
+}
@@ -5,15 +5,18 @@ import (
 )
 
 type CmdSettings struct {
-	VarsFile string
-	Vars     []string
+	VarsFile            string
+	Vars                []string
+	DisableKindOrdering bool
+	Targets             []string
 }
 
 // Apply view settings to the diagprinter
 
 func NewCmdSettings() *CmdSettings {
 	cmdSettings := &CmdSettings{
-		VarsFile: envOr("KUBEHCL_VARS", "kubehcl.tfvars"),
+		VarsFile:            envOr("KUBEHCL_VARS", "kubehcl.tfvars"),
+		DisableKindOrdering: envBoolOr("KUBEHCL_DISABLE_KIND_ORDERING", false),
 	}
 
 	return cmdSettings
@@ -22,4 +25,6 @@ func NewCmdSettings() *CmdSettings {
 func AddCmdSettings(c *CmdSettings, fs *pflag.FlagSet) {
 	fs.StringVar(&c.VarsFile, "var-file", c.VarsFile, "Vars filename to load values into variables")
 	fs.StringSliceVar(&c.Vars, "var", make([]string, 0), "Set a specific variable's value must have an equal within it")
+	fs.BoolVar(&c.DisableKindOrdering, "disable-kind-ordering", c.DisableKindOrdering, "Disable the implicit ordering of resources by kind, only depends_on will order the resources")
+	fs.StringArrayVar(&c.Targets, "target", nil, "Limit the operation to the given resource or module address and its dependencies such as kube_resource.foo, module.test or module.test.kube_resource.bar[\"x\"], can be repeated")
 }
@@ -37,6 +37,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
@@ -91,6 +92,8 @@ type EnvSettings struct {
 
 	// Timeout for the operation
 	Timeout int
+	// Deadline for the whole operation, zero means no deadline
+	Deadline time.Duration
 	// QPS is queries per second which may be used to avoid throttling.
 	QPS float32
 
@@ -118,6 +121,7 @@ func NewSettings() *EnvSettings {
 		KubeInsecureSkipTLSVerify: envBoolOr("KUBEHCL_KUBEINSECURE_SKIP_TLS_VERIFY", false),
 		BurstLimit:                envIntOr("KUBEHCL_BURST_LIMIT", defaultBurstLimit),
 		Timeout:                   envIntOr("KUBEHCL_TIMEOUT", defaultTimeout),
+		Deadline:                  envDurationOr("KUBEHCL_DEADLINE", 0),
 		QPS:                       envFloat32Or("KUBEHCL_QPS", defaultQPS),
 		RegistryConfig:            envOr("KUBEHCL_REGISTRY_CONFIG", kubehclpath.ConfigPath("registry/config.json")),
 		RepositoryConfig:          envOr("KUBEHCL_REPOSITORY_CONFIG", kubehclpath.ConfigPath("repositories.hcl")),
@@ -126,21 +130,28 @@ func NewSettings() *EnvSettings {
 	}
 	env.Debug, _ = strconv.ParseBool(os.Getenv("KUBEHCL_DEBUG"))
 
+	env.bindConfigFlags()
+
+	return env
+}
+
+// Binds the kubernetes config flags to the settings
+func (s *EnvSettings) bindConfigFlags() {
 	// bind to kubernetes config flags
 	config := &genericclioptions.ConfigFlags{
-		Namespace:        &env.namespace,
-		Context:          &env.KubeContext,
-		BearerToken:      &env.KubeToken,
-		APIServer:        &env.KubeAPIServer,
-		CAFile:           &env.KubeCaFile,
-		KubeConfig:       &env.KubeConfig,
-		Impersonate:      &env.KubeAsUser,
-		Insecure:         &env.KubeInsecureSkipTLSVerify,
-		TLSServerName:    &env.KubeTLSServerName,
-		ImpersonateGroup: &env.KubeAsGroups,
+		Namespace:        &s.namespace,
+		Context:          &s.KubeContext,
+		BearerToken:      &s.KubeToken,
+		APIServer:        &s.KubeAPIServer,
+		CAFile:           &s.KubeCaFile,
+		KubeConfig:       &s.KubeConfig,
+		Impersonate:      &s.KubeAsUser,
+		Insecure:         &s.KubeInsecureSkipTLSVerify,
+		TLSServerName:    &s.KubeTLSServerName,
+		ImpersonateGroup: &s.KubeAsGroups,
 		WrapConfigFn: func(config *rest.Config) *rest.Config {
-			config.Burst = env.BurstLimit
-			config.QPS = env.QPS
+			config.Burst = s.BurstLimit
+			config.QPS = s.QPS
 			config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
 				return &kube.RetryingRoundTripper{Wrapped: rt}
 			})
@@ -148,12 +159,34 @@ func NewSettings() *EnvSettings {
 			return config
 		},
 	}
-	if env.BurstLimit != defaultBurstLimit {
-		config = config.WithDiscoveryBurst(env.BurstLimit)
+	if s.BurstLimit != defaultBurstLimit {
+		config = config.WithDiscoveryBurst(s.BurstLimit)
 	}
-	env.config = config
+	s.config = config
+}
 
-	return env
+// ForCluster returns a copy of the settings which connects to another cluster
+// Empty arguments keep the current value, a different context or kubeconfig drops the connection flags of the current cluster
+func (s *EnvSettings) ForCluster(kubeContext, kubeConfig, namespace string) *EnvSettings {
+	env := *s
+	env.KubeAsGroups = append([]string{}, s.KubeAsGroups...)
+	if kubeContext != "" || kubeConfig != "" {
+		env.KubeToken = ""
+		env.KubeAPIServer = ""
+		env.KubeCaFile = ""
+		env.KubeTLSServerName = ""
+	}
+	if kubeContext != "" {
+		env.KubeContext = kubeContext
+	}
+	if kubeConfig != "" {
+		env.KubeConfig = kubeConfig
+	}
+	if namespace != "" {
+		env.namespace = namespace
+	}
+	env.bindConfigFlags()
+	return &env
 }
 
 // AddFlags binds flags to the given flagset.
@@ -172,6 +205,7 @@ func (s *EnvSettings) AddFlags(fs *pflag.FlagSet) {
 	fs.IntVar(&s.BurstLimit, "burst-limit", s.BurstLimit, "client-side default throttling limit")
 	fs.Float32Var(&s.QPS, "qps", s.QPS, "queries per second used when communicating with the Kubernetes API, not including bursting")
 	fs.IntVar(&s.Timeout, "timeout", s.Timeout, "Timeout for each resource creation")
+	fs.DurationVar(&s.Deadline, "deadline", s.Deadline, "Deadline for the whole operation such as 10m, resources which were not started before the deadline are skipped, 0 means no deadline")
 	fs.StringVar(&s.RegistryConfig, "registry-config", s.RegistryConfig, "path to the registry config file")
 	fs.StringVar(&s.RepositoryConfig, "repository-config", s.RepositoryConfig, "path to the file containing repository names and URLs")
 	fs.StringVar(&s.RepositoryCache, "repository-cache", s.RepositoryCache, "path to the directory containing cached repository indexes")
@@ -209,6 +243,18 @@ func envIntOr(name string, def int) int {
 	return ret
 }
 
+func envDurationOr(name string, def time.Duration) time.Duration {
+	if name == "" {
+		return def
+	}
+	envVal := envOr(name, def.String())
+	ret, err := time.ParseDuration(envVal)
+	if err != nil {
+		return def
+	}
+	return ret
+}
+
 func envFloat32Or(name string, def float32) float32 {
 	if name == "" {
 		return def
@@ -273,6 +319,15 @@ func (s *EnvSettings) SetNamespace(namespace string) {
 	s.namespace = namespace
 }
 
+// StateLocation returns the api server and namespace which hold the state and lock of a release
+func (s *EnvSettings) StateLocation() (string, error) {
+	config, err := s.config.ToRESTConfig()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s", config.Host, s.Namespace()), nil
+}
+
 // RESTClientGetter gets the kubeconfig from EnvSettings
 func (s *EnvSettings) RESTClientGetter() genericclioptions.RESTClientGetter {
 	return s.config
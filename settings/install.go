@@ -0,0 +1,66 @@
+package settings
+
+import (
+	"github.com/spf13/pflag"
+)
+
+const defaultCascade = "background"
+
+// InstallSettings contains the flags of the install command
+type InstallSettings struct {
+	*ApplySettings
+	*RetrySettings
+	CreateNamespace bool
+	Cascade         string
+	DryRun          string
+	Output          string
+	// ContinueOnError keeps applying the resources which don't depend on a failed resource, it is set by default
+	ContinueOnError bool
+	// Contexts contains the kube contexts or context globs the release is installed to concurrently
+	Contexts []string
+}
+
+// UninstallSettings contains the flags of the uninstall command
+type UninstallSettings struct {
+	*RetrySettings
+	Cascade string
+	// KeepNamespace keeps the namespaces created by --create-namespace
+	KeepNamespace bool
+}
+
+func NewInstallSettings() *InstallSettings {
+	return &InstallSettings{
+		ApplySettings:   NewApplySettings(),
+		RetrySettings:   NewRetrySettings(),
+		Cascade:         envOr("KUBEHCL_CASCADE", defaultCascade),
+		DryRun:          "none",
+		Output:          envOr("KUBEHCL_OUTPUT", "human"),
+		ContinueOnError: true,
+		Contexts:        envCSV("KUBEHCL_CONTEXTS"),
+	}
+}
+
+func AddInstallSettings(i *InstallSettings, fs *pflag.FlagSet) {
+	AddApplySettings(i.ApplySettings, fs)
+	AddRetrySettings(i.RetrySettings, fs)
+	fs.BoolVar(&i.CreateNamespace, "create-namespace", false, "Create the release namespace and the namespaces of the resources when missing, created namespaces are saved in the state and deleted on uninstall")
+	fs.StringVar(&i.Cascade, "cascade", i.Cascade, "Must be \"background\", \"orphan\", or \"foreground\". Selects the deletion cascading strategy for the dependents of resources removed from the configuration")
+	fs.StringVar(&i.DryRun, "dry-run", i.DryRun, "Must be \"none\" or \"server\". If server, every resource is sent to the api server without persisting it and the state is not updated")
+	fs.StringVarP(&i.Output, "output", "o", i.Output, "Must be \"human\" or \"json\". Format of the progress output, json prints one event per line")
+	fs.BoolVar(&i.ContinueOnError, "continue-on-error", i.ContinueOnError, "Keep applying the resources which don't depend on a failed resource, resources blocked by a failure are skipped and keep their previous state. Set to false to stop starting new resources at the first failure")
+	fs.StringSliceVar(&i.Contexts, "contexts", i.Contexts, "Comma separated kube contexts or context globs such as edge-*, the release is installed to each context concurrently with its own state. Values in <context>.tfvars override the vars file")
+}
+
+func NewUninstallSettings() *UninstallSettings {
+	return &UninstallSettings{
+		RetrySettings: NewRetrySettings(),
+		Cascade:       envOr("KUBEHCL_CASCADE", defaultCascade),
+		KeepNamespace: envBoolOr("KUBEHCL_KEEP_NAMESPACE", false),
+	}
+}
+
+func AddUninstallSettings(u *UninstallSettings, fs *pflag.FlagSet) {
+	AddRetrySettings(u.RetrySettings, fs)
+	fs.StringVar(&u.Cascade, "cascade", u.Cascade, "Must be \"background\", \"orphan\", or \"foreground\". Selects the deletion cascading strategy for the dependents of the release resources")
+	fs.BoolVar(&u.KeepNamespace, "keep-namespace", u.KeepNamespace, "Keep the namespaces which were created by --create-namespace, by default they are deleted with the release")
+}
@@ -0,0 +1,20 @@
+package settings
+
+import (
+	"github.com/spf13/pflag"
+)
+
+// OutputSettings contains the flags of the output command
+type OutputSettings struct {
+	Output string
+}
+
+func NewOutputSettings() *OutputSettings {
+	return &OutputSettings{
+		Output: envOr("KUBEHCL_OUTPUT", "human"),
+	}
+}
+
+func AddOutputSettings(o *OutputSettings, fs *pflag.FlagSet) {
+	fs.StringVarP(&o.Output, "output", "o", o.Output, "Must be \"human\", \"json\" or \"raw\". Format of the outputs, raw prints the value of a single string, number or bool output without quotes")
+}
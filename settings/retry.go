@@ -0,0 +1,43 @@
+package settings
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+const (
+	defaultRetryAttempts   = 3
+	defaultRetryBackoff    = 2 * time.Second
+	defaultRetryMaxBackoff = 30 * time.Second
+)
+
+var defaultRetryOn = []string{"conflict", "throttled", "server-error", "timeout"}
+
+// RetrySettings contains the flags of the retry policy used for transient api errors
+type RetrySettings struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	RetryOn    []string
+}
+
+func NewRetrySettings() *RetrySettings {
+	retryOn := envCSV("KUBEHCL_RETRY_ON")
+	if len(retryOn) == 0 {
+		retryOn = defaultRetryOn
+	}
+	return &RetrySettings{
+		Attempts:   envIntOr("KUBEHCL_RETRY_ATTEMPTS", defaultRetryAttempts),
+		Backoff:    envDurationOr("KUBEHCL_RETRY_BACKOFF", defaultRetryBackoff),
+		MaxBackoff: envDurationOr("KUBEHCL_RETRY_MAX_BACKOFF", defaultRetryMaxBackoff),
+		RetryOn:    retryOn,
+	}
+}
+
+func AddRetrySettings(r *RetrySettings, fs *pflag.FlagSet) {
+	fs.IntVar(&r.Attempts, "retry-attempts", r.Attempts, "Number of attempts for each api request which fails with a retryable error, 1 disables retries")
+	fs.DurationVar(&r.Backoff, "retry-backoff", r.Backoff, "Time to wait before the first retry, doubled after each attempt")
+	fs.DurationVar(&r.MaxBackoff, "retry-max-backoff", r.MaxBackoff, "Maximum time to wait between attempts")
+	fs.StringSliceVar(&r.RetryOn, "retry-on", r.RetryOn, "Errors which are retried, any of \"conflict\", \"throttled\", \"server-error\" and \"timeout\"")
+}
@@ -0,0 +1,29 @@
+package settings
+
+import (
+	"github.com/spf13/pflag"
+)
+
+// TemplateSettings contains the flags of the template command
+type TemplateSettings struct {
+	// DataFile supplies the values of kube_data blocks since template doesn't connect to the cluster
+	DataFile string
+	// KubeVersion is the kubernetes version exposed as cluster.version
+	KubeVersion string
+	// APIVersions are added to the default api versions exposed as cluster.api_versions
+	APIVersions []string
+}
+
+func NewTemplateSettings() *TemplateSettings {
+	return &TemplateSettings{
+		DataFile:    envOr("KUBEHCL_DATA_FILE", ""),
+		KubeVersion: envOr("KUBEHCL_KUBE_VERSION", ""),
+		APIVersions: envCSV("KUBEHCL_API_VERSIONS"),
+	}
+}
+
+func AddTemplateSettings(t *TemplateSettings, fs *pflag.FlagSet) {
+	fs.StringVar(&t.DataFile, "data-file", t.DataFile, "Json file which maps the address of each kube_data block such as kube_data.foo or module.bar.kube_data.foo to its object or list of objects")
+	fs.StringVar(&t.KubeVersion, "kube-version", t.KubeVersion, "Kubernetes version exposed as cluster.version since template doesn't connect to the cluster, for example 1.30 or v1.30.2")
+	fs.StringSliceVar(&t.APIVersions, "api-versions", t.APIVersions, "Comma separated api versions or api versions with kinds such as policy/v1 or monitoring.coreos.com/v1/ServiceMonitor added to cluster.api_versions, can be repeated")
+}
@@ -9,7 +9,8 @@ import (
 
 func NewView() *view.ViewArgs {
 	view := &view.ViewArgs{
-		NoColor: envBoolOr("KUBEHCL_NOCOLOR", false),
+		NoColor:       envBoolOr("KUBEHCL_NOCOLOR", false),
+		ShowSensitive: envBoolOr("KUBEHCL_SHOW_SENSITIVE", false),
 	}
 
 	return view
@@ -17,4 +18,5 @@ func NewView() *view.ViewArgs {
 
 func AddViewFlags(v *view.ViewArgs, fs *pflag.FlagSet) {
 	fs.BoolVar(&v.NoColor, "no-color", v.NoColor, "No color will not print the colors of the output in any command")
+	fs.BoolVar(&v.ShowSensitive, "show-sensitive", v.ShowSensitive, "Show sensitive values in the plan, template, outputs and diagnostics instead of redacting them")
 }